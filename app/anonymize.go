@@ -0,0 +1,280 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/viper"
+)
+
+// Alias prefixes for each kind of anonymized name, numbered in order of appearance.
+const (
+	identifierAlias = "name"
+	moduleAlias     = "example.com/module"
+	packageAlias    = "pkg"
+	directoryAlias  = "dir"
+	fileAlias       = "file"
+	repoAlias       = "repo"
+)
+
+// genericPathNames are directory, file and package names that reveal nothing about a
+// project, so they are kept to give the model some structure to describe.
+var genericPathNames = map[string]bool{
+	"api": true, "app": true, "assets": true, "bin": true, "build": true, "cmd": true,
+	"config": true, "dist": true, "doc": true, "docs": true, "examples": true, "go": true,
+	"index": true, "internal": true, "lib": true, "main": true, "pkg": true, "public": true,
+	"scripts": true, "src": true, "test": true, "testdata": true, "tests": true, "util": true,
+	"utils": true, "vendor": true, "README": true, "LICENSE": true, "CHANGELOG": true,
+	"CONTRIBUTING": true, "Makefile": true, "Dockerfile": true,
+}
+
+var (
+	packageClausePattern = regexp.MustCompile(`(?m)^[ +-]package\s+([A-Za-z_][\w.]*)`)
+	goModulePattern      = regexp.MustCompile(`(?m)^module\s+"?([^\s"]+)"?`)
+	wordPattern          = regexp.MustCompile(`^\w(?:.*\w)?$`)
+)
+
+// sensitiveName is a name to anonymize, with the prefix of its alias.
+type sensitiveName struct {
+	value string
+	alias string
+}
+
+// Anonymizer consistently renames file paths, package names and configured identifiers,
+// so proprietary code can be described without revealing them, and maps the aliases back
+// in the model's output.
+type Anonymizer struct {
+	// aliases maps each original name to its alias, and originals the lowercased alias back
+	aliases   map[string]string
+	originals map[string]string
+
+	anonymizePattern *regexp.Regexp
+	restorePattern   *regexp.Regexp
+}
+
+// newAnonymizer assigns aliases to names, first come first served. Aliases that already
+// occur in corpus, the text being anonymized, are skipped so they restore unambiguously.
+func newAnonymizer(names []sensitiveName, corpus string) *Anonymizer {
+	a := &Anonymizer{
+		aliases:   make(map[string]string),
+		originals: make(map[string]string),
+	}
+
+	corpus = strings.ToLower(corpus)
+	counts := make(map[string]int)
+	for _, name := range names {
+		if _, ok := a.aliases[name.value]; ok {
+			continue
+		}
+
+		var alias string
+		for {
+			counts[name.alias]++
+			alias = fmt.Sprintf("%s%d", name.alias, counts[name.alias])
+			if !strings.Contains(corpus, alias) {
+				break
+			}
+		}
+		a.aliases[name.value] = alias
+		a.originals[alias] = name.value
+	}
+
+	a.anonymizePattern = alternationPattern("", a.aliases)
+	a.restorePattern = alternationPattern("(?i)", a.originals)
+	return a
+}
+
+// alternationPattern matches any key of words as a whole word, preferring longer keys.
+func alternationPattern(flags string, words map[string]string) *regexp.Regexp {
+	if len(words) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(words))
+	for key := range words {
+		keys = append(keys, regexp.QuoteMeta(key))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	return regexp.MustCompile(flags + `\b(?:` + strings.Join(keys, "|") + `)\b`)
+}
+
+// AnonymizerFromConfig builds an Anonymizer for a prompt when anonymize is enabled, or
+// returns nil. It renames the names in anonymize-identifiers, the Go module path, the
+// repository, the packages declared in the diff, and the directories and files that
+// changed, except for generic names such as "cmd" or "main".
+func (g *GitRepo) AnonymizerFromConfig(fileDiffs []FileDiff, diff string, pctx PromptContext) (*Anonymizer, error) {
+	if !viper.GetBool("anonymize") {
+		return nil, nil
+	}
+
+	var names []sensitiveName
+	for _, identifier := range viper.GetStringSlice("anonymize-identifiers") {
+		if !wordPattern.MatchString(identifier) {
+			return nil, fmt.Errorf("anonymize-identifiers entry %q must start and end with a letter, digit or underscore", identifier)
+		}
+		names = append(names, sensitiveName{identifier, identifierAlias})
+	}
+
+	module, err := g.modulePath()
+	if err != nil {
+		return nil, err
+	}
+	if module != "" {
+		names = append(names, sensitiveName{module, moduleAlias})
+	}
+	names = appendPathName(names, pctx.Repo, repoAlias)
+
+	for _, match := range packageClausePattern.FindAllStringSubmatch(diff, -1) {
+		names = appendPathName(names, match[1], packageAlias)
+	}
+
+	for _, fileDiff := range fileDiffs {
+		for _, filePath := range []string{fileDiff.Change.NewPath, fileDiff.Change.OldPath, fileDiff.Filename} {
+			names = appendPathNames(names, filePath)
+		}
+	}
+
+	corpus := []string{diff, strings.Join(pctx.OmittedFiles, "\n"), pctx.Branch, pctx.TicketTitle, pctx.TicketDescription, pctx.Repo}
+	for _, commit := range pctx.Commits {
+		corpus = append(corpus, commit.Subject, commit.Body)
+	}
+	corpus = append(corpus, pctx.Examples.Commits...)
+	corpus = append(corpus, pctx.Examples.PRs...)
+
+	return newAnonymizer(names, strings.Join(corpus, "\n")), nil
+}
+
+// modulePath returns the module path declared in the go.mod at the root of the working
+// tree, or "" if there is none.
+func (g *GitRepo) modulePath() (string, error) {
+	worktree, err := g.repo.Worktree()
+	if errors.Is(err, git.ErrIsBareRepository) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	content, err := readOptionalFile(worktree.Filesystem, "go.mod")
+	if err != nil {
+		return "", err
+	}
+	if match := goModulePattern.FindStringSubmatch(content); match != nil {
+		return match[1], nil
+	}
+	return "", nil
+}
+
+// appendPathNames adds the directories and the file name, without extension, of a path.
+func appendPathNames(names []sensitiveName, filePath string) []sensitiveName {
+	if filePath == "" {
+		return names
+	}
+
+	dir, file := path.Split(filePath)
+	for _, segment := range strings.Split(strings.Trim(dir, "/"), "/") {
+		names = appendPathName(names, segment, directoryAlias)
+	}
+	return appendPathName(names, strings.TrimSuffix(file, path.Ext(file)), fileAlias)
+}
+
+// appendPathName adds a name unless it is generic, too short to be meaningful, or does
+// not start and end with a word character, such as dotfiles.
+func appendPathName(names []sensitiveName, value, alias string) []sensitiveName {
+	if len(value) < 3 || genericPathNames[value] || !wordPattern.MatchString(value) {
+		return names
+	}
+	return append(names, sensitiveName{value, alias})
+}
+
+// Len returns the number of anonymized names.
+func (a *Anonymizer) Len() int {
+	return len(a.aliases)
+}
+
+// Anonymize replaces every anonymized name in text with its alias.
+func (a *Anonymizer) Anonymize(text string) string {
+	if a.anonymizePattern == nil {
+		return text
+	}
+	return a.anonymizePattern.ReplaceAllStringFunc(text, func(name string) string {
+		return a.aliases[name]
+	})
+}
+
+// AnonymizePrompt anonymizes the diff and the context sent along with it.
+func (a *Anonymizer) AnonymizePrompt(diff string, pctx PromptContext) (string, PromptContext) {
+	commits := make([]CommitInfo, len(pctx.Commits))
+	for i, commit := range pctx.Commits {
+		commit.Subject = a.Anonymize(commit.Subject)
+		commit.Body = a.Anonymize(commit.Body)
+		commit.Message = a.Anonymize(commit.Message)
+		commits[i] = commit
+	}
+	pctx.Commits = commits
+
+	omitted := make([]string, len(pctx.OmittedFiles))
+	for i, filePath := range pctx.OmittedFiles {
+		omitted[i] = a.Anonymize(filePath)
+	}
+	pctx.OmittedFiles = omitted
+
+	scopes := make([]string, len(pctx.Scopes))
+	for i, scope := range pctx.Scopes {
+		scopes[i] = a.Anonymize(scope)
+	}
+	pctx.Scopes = scopes
+	pctx.Branch = a.Anonymize(pctx.Branch)
+	pctx.Repo = a.Anonymize(pctx.Repo)
+	pctx.Examples = Examples{Commits: a.anonymizeAll(pctx.Examples.Commits), PRs: a.anonymizeAll(pctx.Examples.PRs), Style: pctx.Examples.Style}
+	pctx.TicketTitle = a.Anonymize(pctx.TicketTitle)
+	pctx.TicketDescription = a.Anonymize(pctx.TicketDescription)
+
+	return a.Anonymize(diff), pctx
+}
+
+// anonymizeAll anonymizes each text.
+func (a *Anonymizer) anonymizeAll(texts []string) []string {
+	anonymized := make([]string, len(texts))
+	for i, text := range texts {
+		anonymized[i] = a.Anonymize(text)
+	}
+	return anonymized
+}
+
+// Restore replaces aliases in text with the original names. Aliases are matched ignoring
+// case, since the model may capitalize them at the start of a sentence.
+func (a *Anonymizer) Restore(text string) string {
+	if a.restorePattern == nil {
+		return text
+	}
+	return a.restorePattern.ReplaceAllStringFunc(text, func(alias string) string {
+		return a.originals[strings.ToLower(alias)]
+	})
+}
+
+// RestoreResult maps the aliases in a generated commit message and PR description back,
+// keeping the other fields of the result.
+func (a *Anonymizer) RestoreResult(result *LLMResult) *LLMResult {
+	restored := *result
+	restored.CommitMessage = a.Restore(result.CommitMessage)
+	restored.Body = a.Restore(result.Body)
+	restored.PRDescription = a.Restore(result.PRDescription)
+	restored.Trailers = slices.Clone(result.Trailers)
+	for i := range restored.Trailers {
+		restored.Trailers[i].Value = a.Restore(restored.Trailers[i].Value)
+	}
+	return &restored
+}
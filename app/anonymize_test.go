@@ -0,0 +1,136 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestAnonymizerRoundTrip(t *testing.T) {
+	names := []sensitiveName{
+		{"Skyhook", identifierAlias},
+		{"billing", packageAlias},
+		{"billing", directoryAlias},
+		{"invoice", fileAlias},
+	}
+	a := newAnonymizer(names, "uses pkg1 already")
+
+	diff := "diff --git a/billing/invoice.go b/billing/invoice.go\n+package billing // Skyhook invoices\n"
+	anonymized := a.Anonymize(diff)
+	expected := "diff --git a/pkg2/file1.go b/pkg2/file1.go\n+package pkg2 // name1 invoices\n"
+	if anonymized != expected {
+		t.Errorf("Anonymize() = %q, expected %q", anonymized, expected)
+	}
+
+	restored := a.Restore("feat(pkg2): Pkg2 sends name1 emails from FILE1.go")
+	if restored != "feat(billing): billing sends Skyhook emails from invoice.go" {
+		t.Errorf("Restore() = %q", restored)
+	}
+
+	if a.Len() != 3 {
+		t.Errorf("Len() = %d, expected 3", a.Len())
+	}
+}
+
+func TestAppendPathNames(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{path: "internal/billing/invoice_test.go", expected: []string{"billing", "invoice_test"}},
+		{path: "cmd/main.go", expected: nil},
+		{path: ".github/workflows/ci.yml", expected: []string{"workflows"}},
+		{path: "", expected: nil},
+	}
+
+	for _, test := range tests {
+		var values []string
+		for _, name := range appendPathNames(nil, test.path) {
+			values = append(values, name.value)
+		}
+		if strings.Join(values, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("appendPathNames(%q) = %v, expected %v", test.path, values, test.expected)
+		}
+	}
+}
+
+func TestPreparePromptAnonymizes(t *testing.T) {
+	defer viper.Reset()
+
+	tr := newTestRepo(t)
+	tr.writeFile("go.mod", "module github.com/acme/ledger\n")
+	tr.writeFile("billing/invoice.go", "package billing\n")
+	tr.stage("go.mod")
+	tr.stage("billing/invoice.go")
+	base := tr.commit("chore: initial commit", "Alice")
+
+	tr.writeFile("billing/invoice.go", "package billing\n\nimport \"github.com/acme/ledger/billing/tax\"\n\n// Total is computed by Skyhook\nvar Total = tax.Rate\n")
+	tr.stage("billing/invoice.go")
+	head := tr.commit("feat(billing): add Skyhook totals", "Alice")
+
+	viper.Set("anonymize", true)
+	viper.Set("anonymize-identifiers", []string{"Skyhook"})
+
+	repo := tr.gitRepo()
+	fileDiffs, err := repo.GetFileDiffs(base.String(), head.String(), DefaultDiffOptions())
+	if err != nil {
+		t.Fatalf("GetFileDiffs failed: %v", err)
+	}
+	commits, err := repo.GetCommitRange(base.String(), head.String(), 10)
+	if err != nil {
+		t.Fatalf("GetCommitRange failed: %v", err)
+	}
+
+	prompt, err := repo.PreparePrompt(fileDiffs, commits, "", "")
+	if err != nil {
+		t.Fatalf("PreparePrompt failed: %v", err)
+	}
+
+	for _, original := range []string{"billing", "invoice", "Skyhook", "acme", "ledger"} {
+		if strings.Contains(prompt.Diff, original) || strings.Contains(prompt.Context.Commits[0].Subject, original) {
+			t.Errorf("Expected %q to be anonymized, got:\n%s\n%s", original, prompt.Diff, prompt.Context.Commits[0].Subject)
+		}
+	}
+	if !strings.Contains(prompt.Diff, `"example.com/module1/pkg1/tax"`) {
+		t.Errorf("Expected the import path to be anonymized, got:\n%s", prompt.Diff)
+	}
+
+	generated := &LLMResult{
+		CommitMessage:  "feat(pkg1): compute totals with name1",
+		PRDescription:  "Changes `file1.go` in example.com/module1.",
+		Trailers:       []Trailer{{Key: "Refs", Value: "name1"}},
+		OfflineReason:  "rate limited",
+		RepairAttempts: 1,
+	}
+	result := prompt.Anonymizer.RestoreResult(generated)
+	if result.CommitMessage != "feat(billing): compute totals with Skyhook" {
+		t.Errorf("CommitMessage = %q", result.CommitMessage)
+	}
+	if result.Trailers[0].Value != "Skyhook" || generated.Trailers[0].Value != "name1" {
+		t.Errorf("Expected the trailers to be restored on a copy, got %v and %v", result.Trailers, generated.Trailers)
+	}
+	if result.OfflineReason != "rate limited" || result.RepairAttempts != 1 {
+		t.Errorf("Expected the other fields to be kept, got %+v", result)
+	}
+	if result.PRDescription != "Changes `invoice.go` in github.com/acme/ledger." {
+		t.Errorf("PRDescription = %q", result.PRDescription)
+	}
+}
+
+func TestAnonymizerFromConfigErrors(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("anonymize", true)
+	viper.Set("anonymize-identifiers", []string{"-flag"})
+
+	tr := newTestRepo(t)
+	if _, err := tr.gitRepo().AnonymizerFromConfig(nil, "", PromptContext{}); err == nil {
+		t.Error("Expected an error for an identifier starting with a dash")
+	}
+
+	viper.Set("anonymize", false)
+	if a, err := tr.gitRepo().AnonymizerFromConfig(nil, "", PromptContext{}); a != nil || err != nil {
+		t.Errorf("Expected no anonymizer when anonymize is off, got %v, %v", a, err)
+	}
+}
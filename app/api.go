@@ -2,8 +2,8 @@ package app
 
 import (
 	"bytes"
-	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,8 +15,8 @@ import (
 	"github.com/spf13/viper"
 )
 
-//go:embed templates/system_prompt.md
-var systemPrompt string
+// openRouterURL is the chat completions endpoint, a variable so tests can use a local server.
+var openRouterURL = "https://openrouter.ai/api/v1/chat/completions"
 
 type llModel int
 
@@ -93,10 +93,31 @@ type Choice struct {
 
 // LLMResult holds the generated commit message and PR description.
 type LLMResult struct {
+	// CommitMessage is the header of the commit message, its first line.
 	CommitMessage string
+	// Body explains what changed and why, unwrapped, or is empty.
+	Body string
+	// Trailers end the commit message, e.g. "Refs: #123", see [Trailer].
+	Trailers      []Trailer
 	PRDescription string
+	// OfflineReason explains why the result was generated by [GenerateOffline] instead
+	// of the model, or is empty.
+	OfflineReason string
+	// Violations are the lint rules the commit message still breaks, see [LintRules].
+	Violations []LintViolation
+	// RepairAttempts counts how often the model was asked to fix the commit message.
+	RepairAttempts int
+	// Ticket is the ticket of the branch referenced in the result, see [ApplyTicket].
+	Ticket string
 }
 
+// ErrNoAPIKey is returned when no OpenRouter API key is configured.
+var ErrNoAPIKey = errors.New("OpenRouter API key not configured. Set via --api-key flag, OPENROUTER_API_KEY env var, or config file")
+
+// ErrModelUnreachable is returned when the OpenRouter API cannot be reached, e.g. because
+// the network is down.
+var ErrModelUnreachable = errors.New("failed to reach the OpenRouter API")
+
 type OpenRouterError struct {
 	Error struct {
 		Message  string `json:"message"`
@@ -107,22 +128,66 @@ type OpenRouterError struct {
 	} `json:"error"`
 }
 
+// PromptContext carries structured context that is sent to the model alongside the diff.
+type PromptContext struct {
+	// Commits are the commits in the compared range, newest first.
+	Commits []CommitInfo
+	// OmittedFiles changed but were left out of the diff by the [PromptIgnore] rules.
+	OmittedFiles []string
+	// Scopes are the conventional commit scopes the change touches, most files first.
+	Scopes []string
+	// Types are the allowed conventional commit types, or empty to leave them to the model.
+	Types []string
+	// Rejected is a previous commit message that broke the lint rules, to be repaired.
+	Rejected *RejectedMessage
+	// Branch is the branch the changes were made on, and Ticket the ticket it names.
+	Branch string
+	Ticket string
+	// TicketTitle and TicketDescription describe the requirement of the ticket, when a
+	// [TicketProvider] found it.
+	TicketTitle       string
+	TicketDescription string
+	// PRTemplate is the content of the PR template to follow, see [PreparedPrompt.UsePRTemplate].
+	PRTemplate string
+	// Repo is the name of the repository, see [GitRepo.RepoName].
+	Repo string
+	// Languages are the languages of the changed files, see [DiffLanguages].
+	Languages []string
+	// Stats count the changed files and lines, including those left out of the diff.
+	Stats DiffStats
+	// Style is the commit message style, [DefaultStyle] unless the style setting says otherwise.
+	Style string
+	// Examples show the conventions of the repository, see [GitRepo.ExamplesFromConfig].
+	Examples Examples
+	// Redacted is set when secrets and personal data were replaced with placeholders,
+	// see [Redactor].
+	Redacted bool
+	// Templates render the prompt, or are nil for the built-in ones.
+	Templates *PromptTemplates
+}
+
+// RejectedMessage is a generated commit message that broke the lint rules.
+type RejectedMessage struct {
+	CommitMessage string
+	Violations    []string
+}
+
 // GenerateCommitAndPR sends a git diff to the OpenRouter API and returns a generated
 // commit message and PR description as an [LLMResult]
-func GenerateCommitAndPR(diff string) (*LLMResult, error) {
+func GenerateCommitAndPR(diff string, pctx PromptContext) (*LLMResult, error) {
 	modelName := viper.GetString("model")
 	if modelName == "" {
 		modelName = DeepseekR1.String()
 	}
-	return GenerateCommitAndPRWithModel(diff, ParseModel(modelName))
+	return GenerateCommitAndPRWithModel(diff, pctx, ParseModel(modelName))
 }
 
 // GenerateCommitAndPRWithModel sends a git diff to the OpenRouter API using a specific model
 // and returns a generated commit message and PR description as an [LLMResult]
-func GenerateCommitAndPRWithModel(diff string, model llModel) (*LLMResult, error) {
+func GenerateCommitAndPRWithModel(diff string, pctx PromptContext, model llModel) (*LLMResult, error) {
 	apiKey := getAPIKey()
 	if apiKey == "" {
-		return nil, fmt.Errorf("OpenRouter API key not configured. Set via --api-key flag, OPENROUTER_API_KEY env var, or config file")
+		return nil, ErrNoAPIKey
 	}
 
 	logger, err := NewAPILogger()
@@ -137,17 +202,11 @@ func GenerateCommitAndPRWithModel(diff string, model llModel) (*LLMResult, error
 
 	requestUUID := uuid.New().String()
 
-	prTemplateFile := viper.GetString("pr-template")
-	if prTemplateFile != "" {
-		templateContent, err := os.ReadFile(prTemplateFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read PR template file: %w", err)
-		}
-		systemPrompt += fmt.Sprintf("\n\nUse this PR template as a guide for the structure and format of the PR description:\n\n%s", string(templateContent))
+	systemPrompt, userPrompt, err := pctx.Templates.Render(diff, pctx)
+	if err != nil {
+		return nil, err
 	}
 
-	userPrompt := fmt.Sprintf("Here is the Git diff to analyze:\n\n```diff\n%s\n```", diff)
-
 	req := APIRequest{
 		Model: model.String(),
 		Messages: []Message{
@@ -161,7 +220,7 @@ func GenerateCommitAndPRWithModel(diff string, model llModel) (*LLMResult, error
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", "https://openrouter.ai/api/v1/chat/completions", bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequest("POST", openRouterURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -189,7 +248,7 @@ func GenerateCommitAndPRWithModel(diff string, model llModel) (*LLMResult, error
 		if logger != nil {
 			logger.LogAPICall(requestUUID, req, nil, err, statusCode, duration)
 		}
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrModelUnreachable, err)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -246,22 +305,38 @@ func GenerateCommitAndPRWithModel(diff string, model llModel) (*LLMResult, error
 }
 
 // parseResponse parses the raw string response from the LLM into an LLMResult struct.
-// It expects the response to be in a specific format with "COMMIT:" and "PR:" prefixes.
+// It expects the response to be in a specific format with "COMMIT:" and "PR:" prefixes, and
+// optional "BODY:" and "TRAILERS:" sections between them.
 func parseResponse(content string) (*LLMResult, error) {
 	lines := strings.Split(content, "\n")
 
 	var commitMessage string
-	var prLines []string
-	inPR := false
+	var bodyLines, trailerLines, prLines []string
+	section := ""
 
 	for _, line := range lines {
-		if strings.HasPrefix(line, "COMMIT:") {
-			commitMessage = strings.TrimSpace(strings.TrimPrefix(line, "COMMIT:"))
-		} else if strings.HasPrefix(line, "PR:") {
-			inPR = true
+		marker, rest, _ := strings.Cut(line, ":")
+		if section != "PR" && (marker == "COMMIT" || marker == "BODY" || marker == "TRAILERS" || marker == "PR") {
+			section = marker
+		}
+
+		switch {
+		case section == marker && marker == "COMMIT":
+			commitMessage = strings.TrimSpace(rest)
+		case section == marker && marker == "PR":
 			continue
-		} else if inPR {
-			prLines = append(prLines, line)
+		case section == marker:
+			line = rest
+			fallthrough
+		default:
+			switch section {
+			case "BODY":
+				bodyLines = append(bodyLines, line)
+			case "TRAILERS":
+				trailerLines = append(trailerLines, line)
+			case "PR":
+				prLines = append(prLines, line)
+			}
 		}
 	}
 
@@ -276,7 +351,15 @@ func parseResponse(content string) (*LLMResult, error) {
 
 	return &LLMResult{
 			CommitMessage: commitMessage,
+			Body:          strings.TrimSpace(strings.Join(bodyLines, "\n")),
+			Trailers:      modelTrailers(ParseTrailers(strings.Join(trailerLines, "\n"))),
 			PRDescription: prDescription,
 		},
 		nil
 }
+
+// FullCommitMessage returns the header, the body wrapped at 72 columns and the trailers,
+// see [FormatCommitMessage].
+func (r *LLMResult) FullCommitMessage() string {
+	return FormatCommitMessage(r.CommitMessage, r.Body, r.Trailers)
+}
@@ -346,7 +346,7 @@ index 0000000..5e1c309
 @@ -0,0 +1 @@
 +Hello World`
 
-	result, err := GenerateCommitAndPR(testDiff)
+	result, err := GenerateCommitAndPR(testDiff, PromptContext{})
 	if err != nil {
 		t.Fatalf("GenerateCommitAndPR failed: %v", err)
 	}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// builtinAttributeMacros defines the macros git knows without a .gitattributes entry.
+const builtinAttributeMacros = "[attr]binary -diff -merge -text\n"
+
+// FileAttributes are the .gitattributes settings that affect how a file is diffed.
+type FileAttributes struct {
+	// Generated marks generated code (linguist-generated). Generated files are collapsed
+	// in the diff viewer and left out of the prompt.
+	Generated bool
+	// NoDiff marks files that are never diffed (-diff or binary). Like binary files,
+	// only their size is shown and their content is never sent to the model.
+	NoDiff bool
+	// Text is "set", "unset", "auto" or "" when the text attribute is unspecified.
+	Text string
+	// EOL is the eol attribute, "lf" or "crlf", or "" when unspecified.
+	EOL string
+}
+
+// normalizesEOL reports whether git would store the file with LF line endings, so that
+// line ending differences are not changes.
+func (a FileAttributes) normalizesEOL(content []byte) bool {
+	switch a.Text {
+	case "unset":
+		return false
+	case "set":
+		return true
+	case "auto":
+		return !isBinary(content)
+	default:
+		return a.EOL != ""
+	}
+}
+
+// normalizeEOL converts CRLF line endings to LF when the attributes ask for normalization.
+func (a FileAttributes) normalizeEOL(content []byte) []byte {
+	if !a.normalizesEOL(content) || !bytes.Contains(content, []byte("\r\n")) {
+		return content
+	}
+	return bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+}
+
+// Attributes holds the .gitattributes rules of a repository, lowest priority first.
+type Attributes struct {
+	rules  []gitattributes.MatchAttribute
+	macros map[string]gitattributes.MatchAttribute
+}
+
+// ParseAttributes parses the contents of .gitattributes files, given in increasing order
+// of priority. Like git, it skips lines it cannot parse.
+func ParseAttributes(contents ...string) *Attributes {
+	a := &Attributes{macros: make(map[string]gitattributes.MatchAttribute)}
+	for _, content := range append([]string{builtinAttributeMacros}, contents...) {
+		for _, line := range strings.Split(content, "\n") {
+			rule, err := gitattributes.ParseAttributesLine(strings.TrimRight(line, "\r"), nil, true)
+			if err != nil || rule.Name == "" {
+				continue
+			}
+			if rule.Pattern == nil {
+				a.macros[rule.Name] = rule
+				continue
+			}
+			a.rules = append(a.rules, rule)
+		}
+	}
+	return a
+}
+
+// LoadAttributes reads the .gitattributes file at the root of the working tree and the
+// repository's info/attributes file, which takes precedence. Nested .gitattributes files
+// are not read.
+func (g *GitRepo) LoadAttributes() (*Attributes, error) {
+	var contents []string
+
+	worktree, err := g.repo.Worktree()
+	switch {
+	case errors.Is(err, git.ErrIsBareRepository):
+	case err != nil:
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	default:
+		content, err := readOptionalFile(worktree.Filesystem, ".gitattributes")
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, content)
+	}
+
+	if storage, ok := g.repo.Storer.(*filesystem.Storage); ok {
+		content, err := readOptionalFile(storage.Filesystem(), "info/attributes")
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, content)
+	}
+
+	return ParseAttributes(contents...), nil
+}
+
+// readOptionalFile reads a file, returning "" if it does not exist.
+func readOptionalFile(fs billy.Filesystem, name string) (string, error) {
+	content, err := util.ReadFile(fs, name)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return string(content), nil
+}
+
+// Lookup returns the attributes of a path. A nil Attributes has no rules.
+func (a *Attributes) Lookup(path string) FileAttributes {
+	if a == nil {
+		return FileAttributes{}
+	}
+
+	// Later rules override earlier ones, and setting a macro applies its attributes
+	values := make(map[string]gitattributes.Attribute)
+	parts := strings.Split(path, "/")
+	for _, rule := range a.rules {
+		if !rule.Pattern.Match(parts) {
+			continue
+		}
+		for _, attr := range rule.Attributes {
+			if macro, ok := a.macros[attr.Name()]; ok && attr.IsSet() {
+				for _, expanded := range macro.Attributes {
+					values[expanded.Name()] = expanded
+				}
+			}
+			values[attr.Name()] = attr
+		}
+	}
+
+	var result FileAttributes
+	if attr, ok := values["linguist-generated"]; ok {
+		result.Generated = attr.IsSet() || (attr.IsValueSet() && attr.Value() == "true")
+	}
+	if attr, ok := values["diff"]; ok {
+		result.NoDiff = attr.IsUnset()
+	}
+	if attr, ok := values["text"]; ok {
+		switch {
+		case attr.IsSet():
+			result.Text = "set"
+		case attr.IsUnset():
+			result.Text = "unset"
+		case attr.IsValueSet() && attr.Value() == "auto":
+			result.Text = "auto"
+		}
+	}
+	if attr, ok := values["eol"]; ok && attr.IsValueSet() {
+		result.EOL = attr.Value()
+	}
+
+	return result
+}
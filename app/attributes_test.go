@@ -0,0 +1,134 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestAttributesLookup(t *testing.T) {
+	attributes := ParseAttributes(
+		"* text=auto\n"+
+			"*.pb.go linguist-generated\n"+
+			"gen/** linguist-generated=true\n"+
+			"gen/keep.go linguist-generated=false\n"+
+			"*.svg -diff\n"+
+			"*.dat binary\n"+
+			"*.bat eol=crlf\n"+
+			"not a valid line with = = =\n",
+		"*.dat diff\n",
+	)
+
+	tests := []struct {
+		path     string
+		expected FileAttributes
+	}{
+		{"main.go", FileAttributes{Text: "auto"}},
+		{"api/v1/service.pb.go", FileAttributes{Generated: true, Text: "auto"}},
+		{"gen/models/user.go", FileAttributes{Generated: true, Text: "auto"}},
+		{"gen/keep.go", FileAttributes{Text: "auto"}},
+		{"assets/logo.svg", FileAttributes{NoDiff: true, Text: "auto"}},
+		{"build.bat", FileAttributes{Text: "auto", EOL: "crlf"}},
+		// The binary macro unsets text, and the later file sets diff again
+		{"blob.dat", FileAttributes{Text: "unset"}},
+	}
+
+	for _, test := range tests {
+		if result := attributes.Lookup(test.path); result != test.expected {
+			t.Errorf("Lookup(%q) = %+v, expected %+v", test.path, result, test.expected)
+		}
+	}
+
+	var none *Attributes
+	if result := none.Lookup("main.go"); result != (FileAttributes{}) {
+		t.Errorf("Expected no attributes from nil rules, got %+v", result)
+	}
+}
+
+func TestFileDiffsHonorAttributes(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.writeFile(".gitattributes", "*.txt text\n*.svg -diff\ngen/** linguist-generated\n")
+	tr.writeFile("notes.txt", "one\ntwo\n")
+	tr.writeFile("raw.md", "one\ntwo\n")
+	tr.writeFile("logo.svg", "<svg></svg>\n")
+	tr.writeFile("gen/api.go", "package gen\n")
+	for _, name := range []string{".gitattributes", "notes.txt", "raw.md", "logo.svg", "gen/api.go"} {
+		tr.stage(name)
+	}
+	base := tr.commit("chore: initial commit", "Alice")
+
+	// Only line endings change in the text files; the others change content
+	tr.writeFile("notes.txt", "one\r\ntwo\r\n")
+	tr.writeFile("raw.md", "one\r\ntwo\r\n")
+	tr.writeFile("logo.svg", "<svg><path/></svg>\n")
+	tr.writeFile("gen/api.go", "package gen\n\nconst Version = 2\n")
+	for _, name := range []string{"notes.txt", "raw.md", "logo.svg", "gen/api.go"} {
+		tr.stage(name)
+	}
+	head := tr.commit("chore: update files", "Alice")
+
+	repo := tr.gitRepo()
+	fileDiffs, err := repo.GetFileDiffs(base.String(), head.String(), DefaultDiffOptions())
+	if err != nil {
+		t.Fatalf("GetFileDiffs failed: %v", err)
+	}
+
+	changes := make(map[string]FileDiff)
+	for _, fileDiff := range fileDiffs {
+		changes[fileDiff.Filename] = fileDiff
+	}
+
+	if _, ok := changes["notes.txt"]; ok {
+		t.Error("Expected a line ending change in a normalized text file to be dropped")
+	}
+	if _, ok := changes["raw.md"]; !ok {
+		t.Error("Expected a line ending change without attributes to be shown")
+	}
+
+	svg := changes["logo.svg"]
+	if !svg.Change.Binary || strings.Contains(svg.Content, "<path/>") {
+		t.Errorf("Expected a -diff file to be summarized without content, got:\n%s", svg.Content)
+	}
+
+	generated := changes["gen/api.go"]
+	if !generated.Change.Generated || !strings.Contains(generated.Content, "+const Version = 2") {
+		t.Errorf("Expected a full diff for a generated file, got %+v", generated.Change)
+	}
+
+	collapsed := CombineFileDiffs(CollapseGenerated(fileDiffs))
+	if !strings.Contains(collapsed, "generated file collapsed (+2 -0 lines)\n") || strings.Contains(collapsed, "Version") {
+		t.Errorf("Expected the generated file to be collapsed, got:\n%s", collapsed)
+	}
+
+	ignore, err := repo.LoadPromptIgnore()
+	if err != nil {
+		t.Fatalf("LoadPromptIgnore failed: %v", err)
+	}
+	promptDiff, omitted := ignore.PromptDiff(fileDiffs)
+	if strings.Contains(promptDiff, "Version") || strings.Contains(promptDiff, "<path/>") {
+		t.Errorf("Expected generated and -diff content to stay out of the prompt, got:\n%s", promptDiff)
+	}
+	if len(omitted) != 1 || omitted[0] != "gen/api.go" {
+		t.Errorf("Expected the generated file to be omitted, got %v", omitted)
+	}
+}
+
+func TestDiffViewerTogglesGeneratedFiles(t *testing.T) {
+	fileDiffs := []FileDiff{{
+		Filename: "gen/api.go",
+		Content:  "diff --git a/gen/api.go b/gen/api.go\n--- a/gen/api.go\n+++ b/gen/api.go\n@@ -1 +1 @@\n-const A = 1\n+const A = 2\n",
+		Change:   FileChange{Kind: ChangeModify, OldPath: "gen/api.go", NewPath: "gen/api.go", Generated: true},
+	}}
+
+	dv := NewDiffViewerFromFileDiffs(fileDiffs, false, false, false)
+	dv.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	if view := dv.unifiedViewport.View(); strings.Contains(view, "const A") || !strings.Contains(view, "generated file collapsed") {
+		t.Errorf("Expected the generated file to start collapsed, got:\n%s", view)
+	}
+
+	dv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+	if view := dv.unifiedViewport.View(); !strings.Contains(view, "const A = 2") {
+		t.Errorf("Expected G to expand the generated file, got:\n%s", view)
+	}
+}
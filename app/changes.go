@@ -0,0 +1,696 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aymanbagabas/go-udiff"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ChangeKind classifies how a file changed between two versions.
+type ChangeKind string
+
+const (
+	ChangeAdd    ChangeKind = "add"
+	ChangeDelete ChangeKind = "delete"
+	ChangeModify ChangeKind = "modify"
+	ChangeRename ChangeKind = "rename"
+	ChangeCopy   ChangeKind = "copy"
+)
+
+// renameThreshold is the minimum similarity, in percent, for a deleted and an added
+// file to be paired as a rename. It matches git's default.
+const renameThreshold = 50
+
+// renameLimit caps the number of deleted/added file pairs compared for inexact
+// rename detection, like git's diff.renameLimit.
+const renameLimit = 1000
+
+// binarySniffLen is how many leading bytes are checked for a NUL byte to decide
+// whether content is binary, the same heuristic git uses.
+const binarySniffLen = 8000
+
+// FileChange is a typed record of how a single file changed between two versions.
+type FileChange struct {
+	Kind ChangeKind
+	// OldPath is empty for additions and NewPath is empty for deletions.
+	OldPath string
+	NewPath string
+	// OldMode and NewMode are git file modes, zero for a side that does not exist.
+	OldMode filemode.FileMode
+	NewMode filemode.FileMode
+	// Binary reports that either side is binary, or that .gitattributes disables diffing
+	// the file; binary changes have no line edits.
+	Binary bool
+	// Generated reports that .gitattributes marks the file as generated code.
+	Generated bool
+	OldSize   int64
+	NewSize   int64
+	// Similarity is the percentage of content kept by a rename or copy.
+	Similarity int
+}
+
+// Path returns the path the change is known by: the new path, or the old path for deletions.
+func (c FileChange) Path() string {
+	if c.NewPath != "" {
+		return c.NewPath
+	}
+	return c.OldPath
+}
+
+// ModeChanged reports whether the mode of a file present on both sides changed.
+func (c FileChange) ModeChanged() bool {
+	return c.OldMode != 0 && c.NewMode != 0 && c.OldMode != c.NewMode
+}
+
+// BinarySummary describes a binary change by its size, e.g. "binary file changed (12KB → 14KB)".
+func (c FileChange) BinarySummary() string {
+	switch c.Kind {
+	case ChangeAdd:
+		return fmt.Sprintf("binary file added (%s)", formatSize(c.NewSize))
+	case ChangeDelete:
+		return fmt.Sprintf("binary file deleted (%s)", formatSize(c.OldSize))
+	default:
+		return fmt.Sprintf("binary file changed (%s → %s)", formatSize(c.OldSize), formatSize(c.NewSize))
+	}
+}
+
+// Header returns the git-style "diff --git" line followed by the extended header lines
+// describing additions, deletions, mode changes, renames and copies.
+func (c FileChange) Header() string {
+	oldPath, newPath := c.OldPath, c.NewPath
+	if oldPath == "" {
+		oldPath = newPath
+	}
+	if newPath == "" {
+		newPath = oldPath
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git a/%s b/%s\n", oldPath, newPath)
+
+	switch c.Kind {
+	case ChangeAdd:
+		fmt.Fprintf(&b, "new file mode %s\n", formatMode(c.NewMode))
+	case ChangeDelete:
+		fmt.Fprintf(&b, "deleted file mode %s\n", formatMode(c.OldMode))
+	}
+
+	if c.ModeChanged() {
+		fmt.Fprintf(&b, "old mode %s\nnew mode %s\n", formatMode(c.OldMode), formatMode(c.NewMode))
+	}
+
+	switch c.Kind {
+	case ChangeRename:
+		fmt.Fprintf(&b, "similarity index %d%%\nrename from %s\nrename to %s\n", c.Similarity, c.OldPath, c.NewPath)
+	case ChangeCopy:
+		fmt.Fprintf(&b, "similarity index %d%%\ncopy from %s\ncopy to %s\n", c.Similarity, c.OldPath, c.NewPath)
+	}
+
+	return b.String()
+}
+
+// formatMode formats a file mode the way git prints it, e.g. "100644".
+func formatMode(mode filemode.FileMode) string {
+	return fmt.Sprintf("%o", uint32(mode))
+}
+
+// formatSize formats a byte count for humans, e.g. "512B", "12KB" or "1.5MB".
+func formatSize(size int64) string {
+	switch {
+	case size < 1024:
+		return fmt.Sprintf("%dB", size)
+	case size < 1024*1024:
+		return fmt.Sprintf("%dKB", (size+512)/1024)
+	default:
+		return fmt.Sprintf("%.1fMB", float64(size)/(1024*1024))
+	}
+}
+
+// isBinary reports whether content looks binary, i.e. contains a NUL byte near the start.
+func isBinary(content []byte) bool {
+	if len(content) > binarySniffLen {
+		content = content[:binarySniffLen]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// similarity returns the percentage of the larger of two contents made up of lines
+// found in both, counting each line as often as it occurs like git's rename scoring.
+func similarity(from, to string) int {
+	if from == to {
+		return 100
+	}
+
+	lines := make(map[string]int)
+	for _, line := range strings.SplitAfter(from, "\n") {
+		lines[line]++
+	}
+
+	shared := 0
+	for _, line := range strings.SplitAfter(to, "\n") {
+		if lines[line] > 0 {
+			lines[line]--
+			shared += len(line)
+		}
+	}
+
+	return shared * 100 / max(len(from), len(to))
+}
+
+// fileVersion is one version of a file in a snapshot.
+type fileVersion struct {
+	hash plumbing.Hash
+	mode filemode.FileMode
+	// read loads the file's content.
+	read func() ([]byte, error)
+}
+
+// snapshot is a set of file versions keyed by path, e.g. a commit tree or the index.
+type snapshot map[string]fileVersion
+
+// blobReader returns a function that reads a blob's content.
+func (g *GitRepo) blobReader(hash plumbing.Hash) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		blob, err := g.repo.BlobObject(hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get blob: %w", err)
+		}
+
+		reader, err := blob.Reader()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get blob reader: %w", err)
+		}
+		defer reader.Close()
+
+		return io.ReadAll(reader)
+	}
+}
+
+// commitSnapshot returns the files of a commit's tree.
+func (g *GitRepo) commitSnapshot(commit *object.Commit) (snapshot, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit tree: %w", err)
+	}
+	return g.treeSnapshot(tree)
+}
+
+// treeSnapshot returns the files of a tree.
+func (g *GitRepo) treeSnapshot(tree *object.Tree) (snapshot, error) {
+	snap := snapshot{}
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	for {
+		name, entry, err := walker.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk tree: %w", err)
+		}
+
+		// Directories are walked into and submodules have no content to diff
+		if entry.Mode == filemode.Dir || entry.Mode == filemode.Submodule {
+			continue
+		}
+
+		snap[name] = fileVersion{hash: entry.Hash, mode: entry.Mode, read: g.blobReader(entry.Hash)}
+	}
+
+	return snap, nil
+}
+
+// changedSnapshots returns the versions of the files that differ between two trees.
+// Identical subtrees are skipped, so the cost grows with the change rather than the
+// repository.
+func (g *GitRepo) changedSnapshots(from, to *object.Tree) (snapshot, snapshot, error) {
+	changes, err := object.DiffTree(from, to)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to diff trees: %w", err)
+	}
+
+	fromSnap, toSnap := snapshot{}, snapshot{}
+	add := func(snap snapshot, entry object.ChangeEntry) {
+		if entry.Name == "" || entry.TreeEntry.Mode == filemode.Submodule {
+			return
+		}
+		snap[entry.Name] = fileVersion{hash: entry.TreeEntry.Hash, mode: entry.TreeEntry.Mode, read: g.blobReader(entry.TreeEntry.Hash)}
+	}
+	for _, change := range changes {
+		add(fromSnap, change.From)
+		add(toSnap, change.To)
+	}
+
+	return fromSnap, toSnap, nil
+}
+
+// headSnapshot returns the files at HEAD, or an empty snapshot before the first commit.
+func (g *GitRepo) headSnapshot() (snapshot, error) {
+	head, err := g.repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return snapshot{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	commit, err := g.repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+
+	return g.commitSnapshot(commit)
+}
+
+// indexSnapshot returns the files staged in the index. Conflicted entries are skipped.
+func (g *GitRepo) indexSnapshot() (snapshot, error) {
+	idx, err := g.repo.Storer.Index()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index: %w", err)
+	}
+
+	snap := snapshot{}
+	for _, entry := range idx.Entries {
+		if entry.Stage != 0 || entry.Mode == filemode.Submodule {
+			continue
+		}
+		snap[entry.Name] = fileVersion{hash: entry.Hash, mode: entry.Mode, read: g.blobReader(entry.Hash)}
+	}
+
+	return snap, nil
+}
+
+// worktreeSnapshot returns the files in the working tree. Files that status reports as
+// unchanged are taken from the index snapshot, so only modified files are read from disk.
+func (g *GitRepo) worktreeSnapshot(index snapshot) (snapshot, error) {
+	status, err := g.GetStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := g.repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	fs := worktree.Filesystem
+
+	snap := make(snapshot, len(index))
+	for path, version := range index {
+		snap[path] = version
+	}
+
+	for _, file := range status {
+		if !file.InDiff(DiffUnstaged, g.includeUntracked) {
+			continue
+		}
+
+		info, err := fs.Lstat(file.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			delete(snap, file.Path)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", file.Path, err)
+		}
+
+		var content []byte
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := fs.Readlink(file.Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read link %s: %w", file.Path, err)
+			}
+			content = []byte(target)
+		} else {
+			content, err = util.ReadFile(fs, file.Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", file.Path, err)
+			}
+		}
+
+		mode, err := filemode.NewFromOSFileMode(info.Mode())
+		if err != nil {
+			mode = filemode.Regular
+		}
+
+		snap[file.Path] = fileVersion{
+			hash: plumbing.ComputeHash(plumbing.BlobObject, content),
+			mode: mode,
+			read: func() ([]byte, error) { return content, nil },
+		}
+	}
+
+	return snap, nil
+}
+
+// changePair links a change record to the two file versions it was computed from.
+type changePair struct {
+	change   FileChange
+	from, to *fileVersion
+}
+
+// diffSnapshots computes the changes between two snapshots, pairing deletions and
+// additions into renames and detecting additions that are exact copies of files in
+// sources, or in from when sources is nil.
+func diffSnapshots(from, to, sources snapshot) []changePair {
+	paths := make(map[string]bool, len(to))
+	for path := range from {
+		paths[path] = true
+	}
+	for path := range to {
+		paths[path] = true
+	}
+
+	var pairs, added, deleted []changePair
+	for path := range paths {
+		oldVersion, inOld := from[path]
+		newVersion, inNew := to[path]
+
+		switch {
+		case inOld && inNew:
+			if oldVersion.hash == newVersion.hash && oldVersion.mode == newVersion.mode {
+				continue
+			}
+			pairs = append(pairs, changePair{
+				change: FileChange{Kind: ChangeModify, OldPath: path, NewPath: path, OldMode: oldVersion.mode, NewMode: newVersion.mode},
+				from:   &oldVersion,
+				to:     &newVersion,
+			})
+		case inOld:
+			deleted = append(deleted, changePair{
+				change: FileChange{Kind: ChangeDelete, OldPath: path, OldMode: oldVersion.mode},
+				from:   &oldVersion,
+			})
+		default:
+			added = append(added, changePair{
+				change: FileChange{Kind: ChangeAdd, NewPath: path, NewMode: newVersion.mode},
+				to:     &newVersion,
+			})
+		}
+	}
+
+	sortPairs(added)
+	sortPairs(deleted)
+
+	added, deleted, renames := detectRenames(added, deleted)
+	pairs = append(pairs, renames...)
+	pairs = append(pairs, deleted...)
+
+	if sources == nil {
+		sources = from
+	}
+	for _, add := range added {
+		pairs = append(pairs, detectCopy(add, sources))
+	}
+
+	sortPairs(pairs)
+	return pairs
+}
+
+// sortPairs orders changes by path.
+func sortPairs(pairs []changePair) {
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].change.Path() < pairs[j].change.Path()
+	})
+}
+
+// detectRenames pairs deleted and added files into renames, first by identical content
+// and then by similarity. It returns the additions and deletions that remain unpaired.
+func detectRenames(added, deleted []changePair) ([]changePair, []changePair, []changePair) {
+	var renames []changePair
+	rename := func(add, del changePair, score int) changePair {
+		return changePair{
+			change: FileChange{
+				Kind:       ChangeRename,
+				OldPath:    del.change.OldPath,
+				NewPath:    add.change.NewPath,
+				OldMode:    del.change.OldMode,
+				NewMode:    add.change.NewMode,
+				Similarity: score,
+			},
+			from: del.from,
+			to:   add.to,
+		}
+	}
+
+	// Exact renames: identical content under a new path
+	usedDeleted := make([]bool, len(deleted))
+	var remaining []changePair
+	for _, add := range added {
+		matched := false
+		for i, del := range deleted {
+			if !usedDeleted[i] && del.from.hash == add.to.hash {
+				usedDeleted[i] = true
+				renames = append(renames, rename(add, del, 100))
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			remaining = append(remaining, add)
+		}
+	}
+	added = remaining
+
+	var unmatchedDeleted []changePair
+	for i, del := range deleted {
+		if !usedDeleted[i] {
+			unmatchedDeleted = append(unmatchedDeleted, del)
+		}
+	}
+	deleted = unmatchedDeleted
+
+	if len(added) == 0 || len(deleted) == 0 || len(added)*len(deleted) > renameLimit {
+		return added, deleted, renames
+	}
+
+	// Inexact renames: score every pair of text files and take the best matches first
+	type candidate struct {
+		add, del int
+		score    int
+	}
+
+	readText := func(version *fileVersion) (string, bool) {
+		content, err := version.read()
+		if err != nil || isBinary(content) {
+			return "", false
+		}
+		return string(content), true
+	}
+
+	addContents := make([]string, len(added))
+	addText := make([]bool, len(added))
+	for i, add := range added {
+		addContents[i], addText[i] = readText(add.to)
+	}
+
+	var candidates []candidate
+	for d, del := range deleted {
+		delContent, ok := readText(del.from)
+		if !ok {
+			continue
+		}
+		for a := range added {
+			if !addText[a] {
+				continue
+			}
+			if score := similarity(delContent, addContents[a]); score >= renameThreshold {
+				candidates = append(candidates, candidate{add: a, del: d, score: score})
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	usedAdded := make([]bool, len(added))
+	usedDeleted = make([]bool, len(deleted))
+	for _, c := range candidates {
+		if usedAdded[c.add] || usedDeleted[c.del] {
+			continue
+		}
+		usedAdded[c.add] = true
+		usedDeleted[c.del] = true
+		renames = append(renames, rename(added[c.add], deleted[c.del], c.score))
+	}
+
+	var unmatchedAdded []changePair
+	for i, add := range added {
+		if !usedAdded[i] {
+			unmatchedAdded = append(unmatchedAdded, add)
+		}
+	}
+	unmatchedDeleted = nil
+	for i, del := range deleted {
+		if !usedDeleted[i] {
+			unmatchedDeleted = append(unmatchedDeleted, del)
+		}
+	}
+
+	return unmatchedAdded, unmatchedDeleted, renames
+}
+
+// detectCopy turns an addition into a copy when its content is identical to a file
+// that exists in the source snapshot. Empty files are never treated as copies.
+func detectCopy(add changePair, from snapshot) changePair {
+	emptyBlob := plumbing.ComputeHash(plumbing.BlobObject, nil)
+	if add.to.hash == emptyBlob {
+		return add
+	}
+
+	var sources []string
+	for path, version := range from {
+		if version.hash == add.to.hash {
+			sources = append(sources, path)
+		}
+	}
+	if len(sources) == 0 {
+		return add
+	}
+	sort.Strings(sources)
+
+	source := from[sources[0]]
+	return changePair{
+		change: FileChange{
+			Kind:       ChangeCopy,
+			OldPath:    sources[0],
+			NewPath:    add.change.NewPath,
+			OldMode:    source.mode,
+			NewMode:    add.change.NewMode,
+			Similarity: 100,
+		},
+		from: &source,
+		to:   add.to,
+	}
+}
+
+// fileDiffs renders each change as a FileDiff with git-style headers, honoring the
+// attributes of its path. Text changes get a unified diff of their line edits and binary
+// changes a one-line size summary. Content changes hidden by the whitespace options or by
+// line ending normalization are dropped unless the file was also moved or its mode changed.
+func (g *GitRepo) fileDiffs(pairs []changePair, attributes *Attributes, opts DiffOptions) ([]FileDiff, error) {
+	var diffs []FileDiff
+	for _, pair := range pairs {
+		change := pair.change
+		if !opts.Pathspec.MatchChange(change) {
+			continue
+		}
+
+		var original, modified []byte
+		var err error
+		if pair.from != nil {
+			if original, err = pair.from.read(); err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", change.OldPath, err)
+			}
+		}
+		if pair.to != nil {
+			if modified, err = pair.to.read(); err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", change.NewPath, err)
+			}
+		}
+
+		attrs := attributes.Lookup(change.Path())
+		change.OldSize = int64(len(original))
+		change.NewSize = int64(len(modified))
+		change.Binary = attrs.NoDiff || isBinary(original) || isBinary(modified)
+		change.Generated = attrs.Generated
+		original = attrs.normalizeEOL(original)
+		modified = attrs.normalizeEOL(modified)
+
+		var content strings.Builder
+		content.WriteString(change.Header())
+
+		var edits []udiff.Edit
+		if change.Binary {
+			if !bytes.Equal(original, modified) {
+				content.WriteString(change.BinarySummary() + "\n")
+			}
+		} else {
+			edits = ComputeEdits(string(original), string(modified), opts)
+			if len(edits) == 0 && change.Kind == ChangeModify && !change.ModeChanged() {
+				continue
+			}
+			content.WriteString(formatEditsAsUnifiedDiff(string(original), edits, change, opts.Context))
+		}
+
+		diffs = append(diffs, FileDiff{
+			Filename: change.Path(),
+			Content:  content.String(),
+			Edits:    edits,
+			Change:   change,
+		})
+	}
+
+	return diffs, nil
+}
+
+// CollapseGenerated returns the file diffs with the content of generated files replaced by
+// a one-line summary of how many lines they add and remove.
+func CollapseGenerated(fileDiffs []FileDiff) []FileDiff {
+	collapsed := make([]FileDiff, len(fileDiffs))
+	for i, fileDiff := range fileDiffs {
+		collapsed[i] = fileDiff
+		if !fileDiff.Change.Generated || fileDiff.Change.Binary {
+			continue
+		}
+
+		added, deleted := fileDiff.LineStats()
+		collapsed[i].Content = fileDiff.Change.Header() +
+			fmt.Sprintf("generated file collapsed (+%d -%d lines)\n", added, deleted)
+	}
+	return collapsed
+}
+
+// LineStats counts the lines the diff adds and removes. The "---" and "+++" file headers
+// are only skipped before the first hunk, as removed and added lines can start alike.
+func (d FileDiff) LineStats() (added, deleted int) {
+	inHunk := false
+	for _, line := range strings.Split(d.Content, "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case !inHunk && (strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "---")):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			deleted++
+		}
+	}
+	return added, deleted
+}
+
+// formatEditsAsUnifiedDiff converts udiff.Edit operations applied to original back to
+// unified diff format with the given number of context lines, labelling missing sides
+// as /dev/null like git.
+func formatEditsAsUnifiedDiff(original string, edits []udiff.Edit, change FileChange, context int) string {
+	if len(edits) == 0 {
+		return ""
+	}
+
+	oldLabel := "a/" + change.OldPath
+	if change.Kind == ChangeAdd {
+		oldLabel = "/dev/null"
+	}
+	newLabel := "b/" + change.NewPath
+	if change.Kind == ChangeDelete {
+		newLabel = "/dev/null"
+	}
+
+	unifiedDiff, err := udiff.ToUnified(oldLabel, newLabel, original, edits, context)
+	if err != nil {
+		return ""
+	}
+
+	return unifiedDiff
+}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+)
+
+func TestGetFileDiffsChangeKinds(t *testing.T) {
+	tr := newTestRepo(t)
+	original := "package main\n\nfunc main() {\n\tprintln(\"one\")\n\tprintln(\"two\")\n\tprintln(\"three\")\n}\n"
+	tr.writeFile("old.go", original)
+	tr.writeFile("source.txt", "shared content\n")
+	tr.writeFile("removed.txt", "bye\n")
+	tr.writeFile("script.sh", "echo hi\n")
+	tr.writeFile("image.png", "\x89PNG\x00"+strings.Repeat("a", 12*1024))
+	for _, name := range []string{"old.go", "source.txt", "removed.txt", "script.sh", "image.png"} {
+		tr.stage(name)
+	}
+	base := tr.commit("chore: initial commit", "Alice")
+
+	// Rename with a small edit, an exact copy, a deletion, a mode change and a binary change
+	if err := os.Remove(filepath.Join(tr.dir, "old.go")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	tr.writeFile("cmd/new.go", strings.Replace(original, "three", "four", 1))
+	tr.writeFile("copy.txt", "shared content\n")
+	if err := os.Remove(filepath.Join(tr.dir, "removed.txt")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if err := os.Chmod(filepath.Join(tr.dir, "script.sh"), 0755); err != nil {
+		t.Fatalf("Failed to chmod file: %v", err)
+	}
+	tr.writeFile("image.png", "\x89PNG\x00"+strings.Repeat("b", 14*1024))
+	for _, name := range []string{"old.go", "cmd/new.go", "copy.txt", "removed.txt", "script.sh", "image.png"} {
+		tr.stage(name)
+	}
+	head := tr.commit("refactor: move main", "Alice")
+
+	fileDiffs, err := tr.gitRepo().GetFileDiffs(base.String(), head.String(), DefaultDiffOptions())
+	if err != nil {
+		t.Fatalf("GetFileDiffs failed: %v", err)
+	}
+
+	changes := make(map[string]FileDiff)
+	for _, fileDiff := range fileDiffs {
+		changes[fileDiff.Filename] = fileDiff
+	}
+
+	tests := []struct {
+		path     string
+		kind     ChangeKind
+		oldPath  string
+		contains []string
+	}{
+		{"cmd/new.go", ChangeRename, "old.go", []string{"rename from old.go\nrename to cmd/new.go\n", "-\tprintln(\"three\")\n+\tprintln(\"four\")\n"}},
+		{"copy.txt", ChangeCopy, "source.txt", []string{"similarity index 100%\ncopy from source.txt\ncopy to copy.txt\n"}},
+		{"removed.txt", ChangeDelete, "removed.txt", []string{"deleted file mode 100644\n", "+++ /dev/null\n"}},
+		{"script.sh", ChangeModify, "script.sh", []string{"old mode 100644\nnew mode 100755\n"}},
+		{"image.png", ChangeModify, "image.png", []string{"binary file changed (12KB → 14KB)\n"}},
+	}
+
+	if len(fileDiffs) != len(tests) {
+		t.Fatalf("Expected %d changes, got %d", len(tests), len(fileDiffs))
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			fileDiff, ok := changes[test.path]
+			if !ok {
+				t.Fatalf("Expected a change for %s", test.path)
+			}
+
+			if fileDiff.Change.Kind != test.kind {
+				t.Errorf("Expected kind %s, got %s", test.kind, fileDiff.Change.Kind)
+			}
+			if fileDiff.Change.OldPath != test.oldPath {
+				t.Errorf("Expected old path %s, got %s", test.oldPath, fileDiff.Change.OldPath)
+			}
+			for _, expected := range test.contains {
+				if !strings.Contains(fileDiff.Content, expected) {
+					t.Errorf("Expected diff to contain %q, got:\n%s", expected, fileDiff.Content)
+				}
+			}
+		})
+	}
+
+	rename := changes["cmd/new.go"].Change
+	if rename.Similarity < renameThreshold || rename.Similarity == 100 {
+		t.Errorf("Expected an inexact rename similarity, got %d%%", rename.Similarity)
+	}
+
+	binary := changes["image.png"]
+	if !binary.Change.Binary || len(binary.Edits) != 0 {
+		t.Errorf("Expected a binary change without edits, got %+v", binary.Change)
+	}
+	if strings.Contains(binary.Content, "PNG") {
+		t.Error("Expected binary content not to be included in the diff")
+	}
+
+	if mode := changes["script.sh"].Change.NewMode; mode != filemode.Executable {
+		t.Errorf("Expected new mode to be executable, got %s", mode)
+	}
+}
+
+func TestStagedRename(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.commitFile("a.txt", "one\ntwo\nthree\n", "chore: initial commit", "Alice")
+
+	if err := os.Rename(filepath.Join(tr.dir, "a.txt"), filepath.Join(tr.dir, "b.txt")); err != nil {
+		t.Fatalf("Failed to rename file: %v", err)
+	}
+	tr.stage("a.txt")
+	tr.stage("b.txt")
+
+	fileDiffs, err := tr.gitRepo().GetStagedFileDiffs(DefaultDiffOptions())
+	if err != nil {
+		t.Fatalf("GetStagedFileDiffs failed: %v", err)
+	}
+
+	if len(fileDiffs) != 1 {
+		t.Fatalf("Expected a single rename, got %d changes", len(fileDiffs))
+	}
+
+	change := fileDiffs[0].Change
+	if change.Kind != ChangeRename || change.OldPath != "a.txt" || change.NewPath != "b.txt" || change.Similarity != 100 {
+		t.Errorf("Unexpected change %+v", change)
+	}
+	if strings.Contains(fileDiffs[0].Content, "@@") {
+		t.Errorf("Expected an exact rename to have no hunks, got:\n%s", fileDiffs[0].Content)
+	}
+}
+
+func TestLineStats(t *testing.T) {
+	fileDiff := FileDiff{Content: "diff --git a/schema.sql b/schema.sql\n" +
+		"--- a/schema.sql\n" +
+		"+++ b/schema.sql\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		"--- drop the users table\n" +
+		"---- yaml\n" +
+		"+++i;\n" +
+		" SELECT 1;\n" +
+		"+-- keep the users table\n"}
+
+	if added, deleted := fileDiff.LineStats(); added != 2 || deleted != 2 {
+		t.Errorf("LineStats() = +%d -%d, expected +2 -2", added, deleted)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected string
+	}{
+		{0, "0B"},
+		{512, "512B"},
+		{12 * 1024, "12KB"},
+		{14*1024 + 100, "14KB"},
+		{3 * 1024 * 1024 / 2, "1.5MB"},
+	}
+
+	for _, test := range tests {
+		if result := formatSize(test.size); result != test.expected {
+			t.Errorf("formatSize(%d) = %q, expected %q", test.size, result, test.expected)
+		}
+	}
+}
+
+func TestDiffViewerRendersExtendedHeaders(t *testing.T) {
+	content := "diff --git a/old.go b/new.go\n" +
+		"similarity index 90%\n" +
+		"rename from old.go\n" +
+		"rename to new.go\n" +
+		"diff --git a/logo.png b/logo.png\n" +
+		"binary file changed (12KB → 14KB)\n"
+
+	dv := NewDiffViewer(content, "old.go", true, false, false)
+	dv.Update(tea.WindowSizeMsg{Width: 200, Height: 40})
+
+	rendered := dv.leftViewport.View()
+	for _, expected := range []string{"rename from old.go", "binary file changed (12KB → 14KB)"} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("Expected side-by-side view to show %q", expected)
+		}
+	}
+}
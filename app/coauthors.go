@@ -0,0 +1,186 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/viper"
+)
+
+// MailmapFile is the file in the repository root that maps author identities, see gitmailmap(5).
+const MailmapFile = ".mailmap"
+
+// mailmapEmailPattern matches the "<email>" parts of a mailmap entry, with the name before each.
+var mailmapEmailPattern = regexp.MustCompile(`([^<]*)<([^>]*)>`)
+
+// coAuthorPattern matches the "Name <email>" value of a Co-authored-by trailer.
+var coAuthorPattern = regexp.MustCompile(`^(.*?)\s*<([^>]*)>$`)
+
+// Identity is the name and email of a commit author.
+type Identity struct {
+	Name  string
+	Email string
+}
+
+func (i Identity) String() string {
+	return fmt.Sprintf("%s <%s>", i.Name, i.Email)
+}
+
+// mailmapEntry maps a commit identity, matched by email and optionally by name, to the
+// proper name and email. Empty proper fields keep the commit's value.
+type mailmapEntry struct {
+	properName, properEmail string
+	commitName, commitEmail string
+}
+
+// Mailmap maps the identities authors committed with to their canonical identities.
+type Mailmap struct {
+	entries []mailmapEntry
+}
+
+// ParseMailmap reads entries in the forms gitmailmap(5) documents:
+//
+//	Proper Name <commit@email>
+//	<proper@email> <commit@email>
+//	Proper Name <proper@email> <commit@email>
+//	Proper Name <proper@email> Commit Name <commit@email>
+func ParseMailmap(content string) *Mailmap {
+	m := &Mailmap{}
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		matches := mailmapEmailPattern.FindAllStringSubmatch(line, -1)
+		var entry mailmapEntry
+		switch len(matches) {
+		case 1:
+			entry = mailmapEntry{properName: strings.TrimSpace(matches[0][1]), commitEmail: matches[0][2]}
+		case 2:
+			entry = mailmapEntry{
+				properName:  strings.TrimSpace(matches[0][1]),
+				properEmail: matches[0][2],
+				commitName:  strings.TrimSpace(matches[1][1]),
+				commitEmail: matches[1][2],
+			}
+		default:
+			continue
+		}
+		m.entries = append(m.entries, entry)
+	}
+	return m
+}
+
+// Map returns the canonical identity of a commit identity. Entries matching the name as
+// well as the email win over entries matching only the email, and later entries over
+// earlier ones; emails and names are matched ignoring case.
+func (m *Mailmap) Map(id Identity) Identity {
+	var match *mailmapEntry
+	for i, entry := range m.entries {
+		if !strings.EqualFold(entry.commitEmail, id.Email) {
+			continue
+		}
+		if entry.commitName == "" && (match == nil || match.commitName == "") {
+			match = &m.entries[i]
+		} else if entry.commitName != "" && strings.EqualFold(entry.commitName, id.Name) {
+			match = &m.entries[i]
+		}
+	}
+
+	if match != nil {
+		if match.properName != "" {
+			id.Name = match.properName
+		}
+		if match.properEmail != "" {
+			id.Email = match.properEmail
+		}
+	}
+	return id
+}
+
+// LoadMailmap reads the mailmap-file setting, or the [MailmapFile] of the repository,
+// followed by the entries of the mailmap config list, which take precedence.
+func (g *GitRepo) LoadMailmap() (*Mailmap, error) {
+	var content string
+	if path := viper.GetString("mailmap-file"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mailmap: %w", err)
+		}
+		content = string(data)
+	} else if worktree, err := g.repo.Worktree(); err == nil {
+		data, err := util.ReadFile(worktree.Filesystem, MailmapFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read %s: %w", MailmapFile, err)
+		}
+		content = string(data)
+	} else if !errors.Is(err, git.ErrIsBareRepository) {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	configured := strings.Join(viper.GetStringSlice("mailmap"), "\n")
+	return ParseMailmap(content + "\n" + configured), nil
+}
+
+// CommitAuthors returns the distinct authors of commits and the co-authors named in their
+// Co-authored-by trailers, oldest first, mapped through the mailmap. Identities are
+// compared by email, and the exclude identity is left out.
+func CommitAuthors(commits []CommitInfo, mailmap *Mailmap, exclude Identity) []Identity {
+	seen := map[string]bool{strings.ToLower(exclude.Email): exclude.Email != ""}
+
+	var authors []Identity
+	add := func(id Identity) {
+		id = mailmap.Map(id)
+		key := strings.ToLower(id.Email)
+		if id.Name == "" || key == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		authors = append(authors, id)
+	}
+
+	for i := len(commits) - 1; i >= 0; i-- {
+		commit := commits[i]
+		add(Identity{Name: commit.Author, Email: commit.Email})
+		for _, trailer := range ParseTrailers(commit.Message) {
+			if !strings.EqualFold(trailer.Key, TrailerCoAuthoredBy) {
+				continue
+			}
+			if match := coAuthorPattern.FindStringSubmatch(trailer.Value); match != nil {
+				add(Identity{Name: match[1], Email: match[2]})
+			}
+		}
+	}
+	return authors
+}
+
+// PromptCoAuthors returns Co-authored-by trailers crediting everyone who authored or
+// co-authored a commit of a range, see [GitRepo.GetCommitRange], except the git user, so
+// that squashing the range keeps their credit. It returns nothing when the co-authors
+// setting is disabled.
+func (g *GitRepo) PromptCoAuthors(commits []CommitInfo) ([]Trailer, error) {
+	if viper.IsSet("co-authors") && !viper.GetBool("co-authors") {
+		return nil, nil
+	}
+
+	mailmap, err := g.LoadMailmap()
+	if err != nil {
+		return nil, err
+	}
+
+	var user Identity
+	if name, email, err := g.CurrentUser(); err == nil {
+		user = mailmap.Map(Identity{Name: name, Email: email})
+	}
+
+	var trailers []Trailer
+	for _, author := range CommitAuthors(commits, mailmap, user) {
+		trailers = append(trailers, Trailer{Key: TrailerCoAuthoredBy, Value: author.String()})
+	}
+	return trailers, nil
+}
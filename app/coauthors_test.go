@@ -0,0 +1,101 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestMailmapMap(t *testing.T) {
+	mailmap := ParseMailmap(`# team mailmap
+Jane Doe <jane@example.com>
+<jane@example.com> <jane@laptop.local>
+Joe Developer <joe@example.com> joe <joe@old.example.com>
+Joe Developer <joe@example.com> <joe@old.example.com> # any other name
+`)
+
+	tests := []struct {
+		input    Identity
+		expected Identity
+	}{
+		{Identity{"jdoe", "jane@example.com"}, Identity{"Jane Doe", "jane@example.com"}},
+		{Identity{"Jane", "JANE@laptop.local"}, Identity{"Jane", "jane@example.com"}},
+		{Identity{"joe", "joe@old.example.com"}, Identity{"Joe Developer", "joe@example.com"}},
+		{Identity{"Joseph", "joe@old.example.com"}, Identity{"Joe Developer", "joe@example.com"}},
+		{Identity{"Ada", "ada@example.com"}, Identity{"Ada", "ada@example.com"}},
+	}
+
+	for _, test := range tests {
+		if got := mailmap.Map(test.input); got != test.expected {
+			t.Errorf("Map(%v) = %v, expected %v", test.input, got, test.expected)
+		}
+	}
+}
+
+func TestCommitAuthors(t *testing.T) {
+	commits := []CommitInfo{
+		{Author: "Grace", Email: "GRACE@example.com", Message: "feat: pair on it\n\nCo-authored-by: Linus <linus@example.com>"},
+		{Author: "Ada", Email: "ada@laptop.local", Message: "fix: second\n\nCo-authored-by: Grace <grace@example.com>"},
+		{Author: "Ada", Email: "ada@example.com", Message: "feat: first"},
+	}
+	mailmap := ParseMailmap("Ada Lovelace <ada@example.com> <ada@laptop.local>\nAda Lovelace <ada@example.com>")
+
+	authors := CommitAuthors(commits, mailmap, Identity{Name: "Linus", Email: "linus@example.com"})
+	expected := []Identity{{"Ada Lovelace", "ada@example.com"}, {"Grace", "grace@example.com"}}
+	if len(authors) != len(expected) {
+		t.Fatalf("CommitAuthors() = %v, expected %v", authors, expected)
+	}
+	for i := range expected {
+		if authors[i] != expected[i] {
+			t.Errorf("Author %d = %v, expected %v", i, authors[i], expected[i])
+		}
+	}
+}
+
+func TestPromptCoAuthors(t *testing.T) {
+	defer viper.Reset()
+	tr := newTestRepo(t)
+	base := tr.commitFile("a.txt", "a\n", "feat: start", "Grace")
+	tr.branch("feature", base)
+	tr.commitFile("b.txt", "b\n", "feat: add b\n\nCo-authored-by: Linus <linus@example.com>", "Ada")
+	tr.commitFile("c.txt", "c\n", "feat: add c", "Grace")
+	tr.writeFile(MailmapFile, "Ada Lovelace <ada@example.com>\n")
+
+	cfg, err := tr.repo.Config()
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	cfg.User.Name, cfg.User.Email = "Grace", "grace@example.com"
+	if err := tr.repo.SetConfig(cfg); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	repo := tr.gitRepo()
+	commits, err := repo.GetCommitRange("master", "feature", 0)
+	if err != nil {
+		t.Fatalf("GetCommitRange() error: %v", err)
+	}
+	trailers, err := repo.PromptCoAuthors(commits)
+	if err != nil {
+		t.Fatalf("PromptCoAuthors() error: %v", err)
+	}
+	expected := []string{"Co-authored-by: Ada Lovelace <ada@example.com>", "Co-authored-by: Linus <linus@example.com>"}
+	if len(trailers) != len(expected) {
+		t.Fatalf("PromptCoAuthors() = %v, expected %v", trailers, expected)
+	}
+	for i := range expected {
+		if trailers[i].String() != expected[i] {
+			t.Errorf("Trailer %d = %q, expected %q", i, trailers[i], expected[i])
+		}
+	}
+
+	viper.Set("mailmap", []string{"Ada L. <ada@example.com>"})
+	if trailers, err := repo.PromptCoAuthors(commits); err != nil || trailers[0].Value != "Ada L. <ada@example.com>" {
+		t.Errorf("Expected the configured mailmap to win, got %v, %v", trailers, err)
+	}
+
+	viper.Set("co-authors", false)
+	if trailers, err := repo.PromptCoAuthors(commits); err != nil || len(trailers) != 0 {
+		t.Errorf("Expected no co-authors when disabled, got %v, %v", trailers, err)
+	}
+}
@@ -0,0 +1,209 @@
+package app
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/viper"
+)
+
+// defaultMaxCommits caps the number of commit messages sent to the model when
+// max-commits is not configured.
+const defaultMaxCommits = 20
+
+// CommitInfo holds the metadata of a single commit that is useful as context
+// when generating commit messages and PR descriptions.
+type CommitInfo struct {
+	Hash    string
+	Author  string
+	Email   string
+	When    time.Time
+	Subject string
+	Body    string
+	// Message is the full commit message, with surrounding whitespace trimmed.
+	Message string
+}
+
+// ShortHash returns the abbreviated commit hash.
+func (c CommitInfo) ShortHash() string {
+	if len(c.Hash) > 8 {
+		return c.Hash[:8]
+	}
+	return c.Hash
+}
+
+// newCommitInfo converts a go-git commit object into a CommitInfo.
+func newCommitInfo(commit *object.Commit) CommitInfo {
+	message := strings.TrimSpace(commit.Message)
+	subject, body, _ := strings.Cut(message, "\n")
+
+	return CommitInfo{
+		Hash:    commit.Hash.String(),
+		Author:  commit.Author.Name,
+		Email:   commit.Author.Email,
+		When:    commit.Author.When,
+		Subject: strings.TrimSpace(subject),
+		Body:    strings.TrimSpace(body),
+		Message: message,
+	}
+}
+
+// resolveCommit resolves a revision expression to its commit object.
+func (g *GitRepo) resolveCommit(rev string) (*object.Commit, error) {
+	hash, err := g.resolveHash(rev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve revision %s: %w", rev, err)
+	}
+
+	commit, err := g.repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", rev, err)
+	}
+
+	return commit, nil
+}
+
+// GetCommitRange returns the commits reachable from head that are not reachable
+// from the merge-base of base and head, base..head in git terms, newest first. A
+// limit of zero or less returns every commit in the range.
+func (g *GitRepo) GetCommitRange(base, head string, limit int) ([]CommitInfo, error) {
+	baseCommit, err := g.resolveCommit(base)
+	if err != nil {
+		return nil, err
+	}
+
+	headCommit, err := g.resolveCommit(head)
+	if err != nil {
+		return nil, err
+	}
+
+	mergeBases, err := baseCommit.MergeBase(headCommit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find merge-base of %s and %s: %w", base, head, err)
+	}
+
+	// Stopping at the merge-bases is not enough: when base was merged into head, its
+	// older history is reachable around them.
+	excluded, err := excludedCommits(headCommit, mergeBases)
+	if err != nil {
+		return nil, err
+	}
+
+	iter := object.NewCommitPreorderIter(headCommit, excluded, nil)
+	defer iter.Close()
+
+	var commits []CommitInfo
+	for limit <= 0 || len(commits) < limit {
+		commit, err := iter.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate commits: %w", err)
+		}
+		commits = append(commits, newCommitInfo(commit))
+	}
+
+	return commits, nil
+}
+
+// excludedCommits returns the commits reachable from the merge-bases that the history of
+// head reaches. Like git rev-list, it walks both sides newest first and stops once only
+// excluded commits are left, rather than walking the whole history behind the merge-bases.
+func excludedCommits(head *object.Commit, mergeBases []*object.Commit) (map[plumbing.Hash]bool, error) {
+	excluded := make(map[plumbing.Hash]bool)
+	queued := make(map[plumbing.Hash]bool)
+	popped := make(map[plumbing.Hash]bool)
+	queue := &commitQueue{}
+	pending := 0 // queued commits that are not excluded
+
+	visit := func(commit *object.Commit, exclude bool) {
+		if exclude && !excluded[commit.Hash] {
+			excluded[commit.Hash] = true
+			if queued[commit.Hash] && !popped[commit.Hash] {
+				pending--
+			}
+		}
+		if !queued[commit.Hash] {
+			queued[commit.Hash] = true
+			heap.Push(queue, commit)
+			if !excluded[commit.Hash] {
+				pending++
+			}
+		}
+	}
+
+	visit(head, false)
+	for _, commit := range mergeBases {
+		visit(commit, true)
+	}
+	for pending > 0 {
+		commit := heap.Pop(queue).(*object.Commit)
+		popped[commit.Hash] = true
+		exclude := excluded[commit.Hash]
+		if !exclude {
+			pending--
+		}
+		err := commit.Parents().ForEach(func(parent *object.Commit) error {
+			visit(parent, exclude)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk history of %s: %w", commit.Hash, err)
+		}
+	}
+	return excluded, nil
+}
+
+// commitQueue is a heap of commits, the most recently committed first.
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int           { return len(q) }
+func (q commitQueue) Less(i, j int) bool { return q[i].Committer.When.After(q[j].Committer.When) }
+func (q commitQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)        { *q = append(*q, x.(*object.Commit)) }
+
+func (q *commitQueue) Pop() any {
+	old := *q
+	commit := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return commit
+}
+
+// PromptCommits returns the commits of a range, see [GitRepo.GetCommitRange], that should
+// be sent to the model, honoring the include-commits and max-commits settings.
+func PromptCommits(commits []CommitInfo) []CommitInfo {
+	if !viper.GetBool("include-commits") {
+		return nil
+	}
+
+	limit := viper.GetInt("max-commits")
+	if limit <= 0 {
+		limit = defaultMaxCommits
+	}
+
+	return commits[:min(limit, len(commits))]
+}
+
+// FormatCommitLog renders commits as a markdown list, oldest first, so the
+// model reads them in the order they were written.
+func FormatCommitLog(commits []CommitInfo) string {
+	var b strings.Builder
+	for i := len(commits) - 1; i >= 0; i-- {
+		commit := commits[i]
+		fmt.Fprintf(&b, "- %s %s (%s)\n", commit.ShortHash(), commit.Subject, commit.Author)
+		if commit.Body == "" {
+			continue
+		}
+		for _, line := range strings.Split(commit.Body, "\n") {
+			b.WriteString(strings.TrimRight("  "+line, " ") + "\n")
+		}
+	}
+	return b.String()
+}
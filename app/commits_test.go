@@ -0,0 +1,264 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/viper"
+)
+
+// testRepo wraps a temporary git repository used by tests.
+type testRepo struct {
+	t    *testing.T
+	dir  string
+	repo *git.Repository
+	tick int
+}
+
+// newTestRepo initializes an empty repository in a temporary directory.
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("Failed to init repo: %v", err)
+	}
+
+	return &testRepo{t: t, dir: dir, repo: repo}
+}
+
+// gitRepo returns a GitRepo wrapping the test repository.
+func (r *testRepo) gitRepo() *GitRepo {
+	return &GitRepo{repo: r.repo}
+}
+
+// writeFile writes content to a file relative to the repository root.
+func (r *testRepo) writeFile(name, content string) {
+	r.t.Helper()
+
+	path := filepath.Join(r.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		r.t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		r.t.Fatalf("Failed to write file: %v", err)
+	}
+}
+
+// stage adds a file to the index.
+func (r *testRepo) stage(name string) {
+	r.t.Helper()
+
+	wt, err := r.repo.Worktree()
+	if err != nil {
+		r.t.Fatalf("Failed to get worktree: %v", err)
+	}
+	if _, err := wt.Add(name); err != nil {
+		r.t.Fatalf("Failed to stage %s: %v", name, err)
+	}
+}
+
+// commit records the index as a new commit authored by the given author.
+func (r *testRepo) commit(message, author string) plumbing.Hash {
+	r.t.Helper()
+
+	wt, err := r.repo.Worktree()
+	if err != nil {
+		r.t.Fatalf("Failed to get worktree: %v", err)
+	}
+
+	r.tick++
+	sig := &object.Signature{
+		Name:  author,
+		Email: strings.ToLower(author) + "@example.com",
+		When:  time.Date(2025, 1, 1, 0, r.tick, 0, 0, time.UTC),
+	}
+	hash, err := wt.Commit(message, &git.CommitOptions{Author: sig, Committer: sig})
+	if err != nil {
+		r.t.Fatalf("Failed to commit: %v", err)
+	}
+
+	return hash
+}
+
+// commitFile writes, stages and commits a single file.
+func (r *testRepo) commitFile(name, content, message, author string) plumbing.Hash {
+	r.t.Helper()
+
+	r.writeFile(name, content)
+	r.stage(name)
+	return r.commit(message, author)
+}
+
+// branch creates a branch pointing at the given commit and checks it out.
+func (r *testRepo) branch(name string, at plumbing.Hash) {
+	r.t.Helper()
+
+	wt, err := r.repo.Worktree()
+	if err != nil {
+		r.t.Fatalf("Failed to get worktree: %v", err)
+	}
+	err = wt.Checkout(&git.CheckoutOptions{
+		Hash:   at,
+		Branch: plumbing.NewBranchReferenceName(name),
+		Create: true,
+	})
+	if err != nil {
+		r.t.Fatalf("Failed to create branch %s: %v", name, err)
+	}
+}
+
+func TestGetCommitRange(t *testing.T) {
+	tr := newTestRepo(t)
+	base := tr.commitFile("README.md", "hello\n", "chore: initial commit", "Alice")
+	tr.branch("feature", base)
+	tr.commitFile("a.txt", "a\n", "feat: add a\n\nWe need a for the importer.", "Bob")
+	tr.commitFile("b.txt", "b\n", "fix: correct b", "Carol")
+
+	repo := tr.gitRepo()
+
+	commits, err := repo.GetCommitRange(base.String(), "feature", 0)
+	if err != nil {
+		t.Fatalf("GetCommitRange failed: %v", err)
+	}
+
+	if len(commits) != 2 {
+		t.Fatalf("Expected 2 commits, got %d", len(commits))
+	}
+
+	if commits[0].Subject != "fix: correct b" {
+		t.Errorf("Expected newest commit first, got %q", commits[0].Subject)
+	}
+
+	if commits[1].Body != "We need a for the importer." {
+		t.Errorf("Expected body to be preserved, got %q", commits[1].Body)
+	}
+
+	limited, err := repo.GetCommitRange(base.String(), "feature", 1)
+	if err != nil {
+		t.Fatalf("GetCommitRange with limit failed: %v", err)
+	}
+
+	if len(limited) != 1 {
+		t.Errorf("Expected limit to cap the range at 1 commit, got %d", len(limited))
+	}
+}
+
+func TestGetCommitRangeAfterMergingBase(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.commitFile("a.txt", "a\n", "chore: add a", "Alice")
+	b := tr.commitFile("b.txt", "b\n", "chore: add b", "Alice")
+	tr.branch("feature", b)
+	f1 := tr.commitFile("f1.txt", "f1\n", "feat: add f1", "Bob")
+
+	wt, err := tr.repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	checkout := func(branch string) {
+		if err := wt.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(branch)}); err != nil {
+			t.Fatalf("Failed to check out %s: %v", branch, err)
+		}
+	}
+	checkout("master")
+	c := tr.commitFile("c.txt", "c\n", "chore: add c", "Alice")
+	checkout("feature")
+	sig := &object.Signature{Name: "Bob", Email: "bob@example.com", When: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)}
+	if _, err := wt.Commit("Merge branch 'master' into feature", &git.CommitOptions{Author: sig, Committer: sig, Parents: []plumbing.Hash{f1, c}, AllowEmptyCommits: true}); err != nil {
+		t.Fatalf("Failed to commit merge: %v", err)
+	}
+	tr.commitFile("f2.txt", "f2\n", "feat: add f2", "Bob")
+
+	commits, err := tr.gitRepo().GetCommitRange("master", "feature", 0)
+	if err != nil {
+		t.Fatalf("GetCommitRange failed: %v", err)
+	}
+
+	var subjects []string
+	for _, commit := range commits {
+		subjects = append(subjects, commit.Subject)
+	}
+	expected := []string{"feat: add f2", "Merge branch 'master' into feature", "feat: add f1"}
+	if strings.Join(subjects, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %q, got %q", expected, subjects)
+	}
+}
+
+func TestExcludedCommitsStopAtTheRange(t *testing.T) {
+	tr := newTestRepo(t)
+	for i := range 5 {
+		tr.commitFile("a.txt", fmt.Sprintf("%d\n", i), fmt.Sprintf("chore: change a %d", i), "Alice")
+	}
+	base := tr.commitFile("a.txt", "base\n", "chore: change a", "Alice")
+	tr.branch("feature", base)
+	head := tr.commitFile("b.txt", "b\n", "feat: add b", "Bob")
+
+	baseCommit, err := tr.repo.CommitObject(base)
+	if err != nil {
+		t.Fatalf("Failed to get commit: %v", err)
+	}
+	headCommit, err := tr.repo.CommitObject(head)
+	if err != nil {
+		t.Fatalf("Failed to get commit: %v", err)
+	}
+
+	excluded, err := excludedCommits(headCommit, []*object.Commit{baseCommit})
+	if err != nil {
+		t.Fatalf("excludedCommits() error: %v", err)
+	}
+	if len(excluded) != 1 || !excluded[base] {
+		t.Errorf("Expected only the merge-base to be walked, got %d commits", len(excluded))
+	}
+}
+
+func TestPromptCommits(t *testing.T) {
+	defer viper.Reset()
+	commits := []CommitInfo{{Subject: "fix: third"}, {Subject: "fix: second"}, {Subject: "feat: first"}}
+
+	if PromptCommits(commits) != nil {
+		t.Error("Expected no commits without include-commits")
+	}
+	viper.Set("include-commits", true)
+	viper.Set("max-commits", 2)
+	if limited := PromptCommits(commits); len(limited) != 2 || limited[0].Subject != "fix: third" {
+		t.Errorf("Expected the 2 newest commits, got %v", limited)
+	}
+}
+
+func TestFormatCommitLog(t *testing.T) {
+	commits := []CommitInfo{
+		{Hash: "bbbbbbbbbbbb", Subject: "fix: second", Author: "Bob"},
+		{Hash: "aaaaaaaaaaaa", Subject: "feat: first", Author: "Alice", Body: "Explains why.\n\nMore detail."},
+	}
+
+	expected := "- aaaaaaaa feat: first (Alice)\n  Explains why.\n\n  More detail.\n- bbbbbbbb fix: second (Bob)\n"
+	if result := FormatCommitLog(commits); result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestBuildUserPromptIncludesCommits(t *testing.T) {
+	prompt := renderUserPrompt(t, "diff content", PromptContext{
+		Commits: []CommitInfo{{Hash: "abcdef1234", Subject: "feat: add parser", Author: "Alice"}},
+	})
+
+	if !strings.Contains(prompt, "diff content") {
+		t.Error("Expected prompt to contain the diff")
+	}
+
+	if !strings.Contains(prompt, "feat: add parser") {
+		t.Error("Expected prompt to contain commit subjects")
+	}
+
+	if strings.Contains(renderUserPrompt(t, "diff content", PromptContext{}), "commit messages") {
+		t.Error("Expected no commit section without commits")
+	}
+}
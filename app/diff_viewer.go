@@ -57,6 +57,7 @@ type FileDiff struct {
 	Filename string
 	Content  string
 	Edits    []udiff.Edit
+	Change   FileChange
 }
 
 // DiffViewer represents the diff viewer model
@@ -66,7 +67,10 @@ type DiffViewer struct {
 	unifiedViewport viewport.Model
 
 	content         string
+	fileDiffs       []FileDiff // Set when built from file diffs, to expand generated files
+	showGenerated   bool
 	filename        string // Add filename field for proper syntax highlighting
+	context         int    // Context lines when rendering edits as a unified diff
 	syntaxHighlight bool
 	showWhitespace  bool
 	width           int
@@ -82,16 +86,37 @@ type DiffViewer struct {
 	scrollSync bool
 }
 
-// NewDiffViewerFromEdits creates a new diff viewer from udiff.Edit operations
-func NewDiffViewerFromEdits(edits []udiff.Edit, filename, originalContent string, sideBySide, syntaxHighlight, showWhitespace bool) *DiffViewer {
+// NewDiffViewerFromEdits creates a new diff viewer from udiff.Edit operations, showing
+// the given number of context lines around each hunk
+func NewDiffViewerFromEdits(edits []udiff.Edit, filename, originalContent string, context int, sideBySide, syntaxHighlight, showWhitespace bool) *DiffViewer {
 	oldLabel := "a/" + filename
 	newLabel := "b/" + filename
-	content, err := udiff.ToUnified(oldLabel, newLabel, originalContent, edits, 3)
+	content, err := udiff.ToUnified(oldLabel, newLabel, originalContent, edits, context)
 	if err != nil {
 		// Fallback to empty content if conversion fails
 		content = ""
 	}
-	return NewDiffViewer(content, filename, sideBySide, syntaxHighlight, showWhitespace)
+	dv := NewDiffViewer(content, filename, sideBySide, syntaxHighlight, showWhitespace)
+	dv.context = context
+	return dv
+}
+
+// NewDiffViewerFromFileDiffs creates a new diff viewer for several files. Files marked
+// as generated in .gitattributes are collapsed until toggled with G.
+func NewDiffViewerFromFileDiffs(fileDiffs []FileDiff, sideBySide, syntaxHighlight, showWhitespace bool) *DiffViewer {
+	dv := NewDiffViewer(CombineFileDiffs(CollapseGenerated(fileDiffs)), GetPrimaryFilename(fileDiffs), sideBySide, syntaxHighlight, showWhitespace)
+	dv.fileDiffs = fileDiffs
+	return dv
+}
+
+// hasGenerated reports whether any of the viewer's files is generated.
+func (dv *DiffViewer) hasGenerated() bool {
+	for _, fileDiff := range dv.fileDiffs {
+		if fileDiff.Change.Generated {
+			return true
+		}
+	}
+	return false
 }
 
 // NewDiffViewer creates a new diff viewer with the given content and options
@@ -113,6 +138,7 @@ func NewDiffViewer(content, filename string, sideBySide, syntaxHighlight, showWh
 		filename:        filename,
 		syntaxHighlight: syntaxHighlight,
 		showWhitespace:  showWhitespace,
+		context:         defaultContextLines,
 		mode:            mode,
 		scrollSync:      true, // Enable by default
 	}
@@ -174,6 +200,17 @@ func (dv *DiffViewer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Toggle whitespace display
 			dv.showWhitespace = !dv.showWhitespace
 			dv.renderDiff()
+		case "G":
+			// Toggle collapsing of generated files
+			if dv.hasGenerated() {
+				dv.showGenerated = !dv.showGenerated
+				if dv.showGenerated {
+					dv.content = CombineFileDiffs(dv.fileDiffs)
+				} else {
+					dv.content = CombineFileDiffs(CollapseGenerated(dv.fileDiffs))
+				}
+				dv.renderDiff()
+			}
 		}
 	}
 
@@ -299,7 +336,11 @@ func (dv *DiffViewer) View() string {
 	helpStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("241"))
 
-	help := helpStyle.Render("j/k: scroll | s: toggle side-by-side | h: toggle syntax highlighting | w: toggle whitespace | y: toggle scroll sync | q: quit")
+	helpText := "j/k: scroll | s: toggle side-by-side | h: toggle syntax highlighting | w: toggle whitespace | y: toggle scroll sync"
+	if dv.hasGenerated() {
+		helpText += " | G: toggle generated files"
+	}
+	help := helpStyle.Render(helpText + " | q: quit")
 
 	// Add responsive layout info for narrow terminals
 	if dv.width < 100 {
@@ -364,6 +405,9 @@ func (dv *DiffViewer) renderSideBySidePanes() {
 	// Parse header info for line numbers
 	var oldStart, newStart int
 
+	// Combined diffs span several files, so highlight each with its own lexer
+	filename := dv.filename
+
 	// Group consecutive deletions and additions for alignment
 	i := 0
 	for i < len(lines) {
@@ -404,6 +448,10 @@ func (dv *DiffViewer) renderSideBySidePanes() {
 
 		if strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") ||
 			strings.HasPrefix(line, "diff --git") {
+			if path, ok := diffHeaderPath(line); ok {
+				filename = path
+			}
+
 			// File headers go to both sides without line numbers
 			headerStyle := lipgloss.NewStyle().
 				Foreground(lipgloss.Color("33")).
@@ -415,6 +463,17 @@ func (dv *DiffViewer) renderSideBySidePanes() {
 			continue
 		}
 
+		if isExtendedHeader(line) {
+			// Renames, copies, mode changes and binary summaries go to both sides
+			metaStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color("141"))
+			styledLine := metaStyle.Render(line)
+			leftLines = append(leftLines, dv.fitToWidth(styledLine, leftWidth))
+			rightLines = append(rightLines, dv.fitToWidth(styledLine, rightWidth))
+			i++
+			continue
+		}
+
 		if strings.HasPrefix(line, "-") {
 			// Collect all consecutive deletions
 			var deletions []string
@@ -456,7 +515,7 @@ func (dv *DiffViewer) renderSideBySidePanes() {
 
 				// Apply syntax highlighting if enabled
 				if dv.syntaxHighlight {
-					content = dv.applySyntaxHighlighting(content, dv.filename)
+					content = dv.applySyntaxHighlighting(content, filename)
 				}
 
 				deleteLineNumStyle := lipgloss.NewStyle().
@@ -487,7 +546,7 @@ func (dv *DiffViewer) renderSideBySidePanes() {
 
 				// Apply syntax highlighting if enabled
 				if dv.syntaxHighlight {
-					content = dv.applySyntaxHighlighting(content, dv.filename)
+					content = dv.applySyntaxHighlighting(content, filename)
 				}
 
 				addLineNumStyle := lipgloss.NewStyle().
@@ -531,7 +590,7 @@ func (dv *DiffViewer) renderSideBySidePanes() {
 
 			// Apply syntax highlighting if enabled
 			if dv.syntaxHighlight {
-				content = dv.applySyntaxHighlighting(content, dv.filename)
+				content = dv.applySyntaxHighlighting(content, filename)
 			}
 
 			// Style for context lines
@@ -587,6 +646,37 @@ func (dv *DiffViewer) renderSideBySidePanes() {
 	dv.rightViewport.SetContent(strings.Join(rightLines, "\n"))
 }
 
+// extendedHeaderPrefixes are the git extended header lines that describe a file change
+// beyond its content, plus the summary lines used for binary and collapsed generated files.
+var extendedHeaderPrefixes = []string{
+	"new file mode ", "deleted file mode ", "old mode ", "new mode ",
+	"similarity index ", "rename from ", "rename to ", "copy from ", "copy to ",
+	"index ", "binary file ", "Binary files ", "generated file ",
+}
+
+// isExtendedHeader reports whether a diff line is an extended header line.
+func isExtendedHeader(line string) bool {
+	for _, prefix := range extendedHeaderPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffHeaderPath returns the new path named by a "diff --git" or "+++" header line.
+func diffHeaderPath(line string) (string, bool) {
+	switch {
+	case strings.HasPrefix(line, "diff --git "):
+		if i := strings.LastIndex(line, " b/"); i >= 0 {
+			return line[i+3:], true
+		}
+	case strings.HasPrefix(line, "+++ b/"):
+		return strings.TrimPrefix(line, "+++ b/"), true
+	}
+	return "", false
+}
+
 func (dv *DiffViewer) applySyntaxHighlighting(content, filename string) string {
 	if !dv.syntaxHighlight {
 		return content
@@ -672,7 +762,7 @@ func (dv *DiffViewer) renderSideBySideFromEdits(edits []udiff.Edit, originalCont
 	useSideBySide, leftWidth, rightWidth := dv.calculateLayout()
 	if !useSideBySide {
 		// Terminal too narrow, fall back to unified mode
-		unifiedDiff, err := udiff.ToUnified("a/"+filename, "b/"+filename, originalContent, edits, 3)
+		unifiedDiff, err := udiff.ToUnified("a/"+filename, "b/"+filename, originalContent, edits, dv.context)
 		if err != nil {
 			return "Error generating diff"
 		}
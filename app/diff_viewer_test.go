@@ -50,7 +50,7 @@ func TestNewDiffViewerFromEdits(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			edits := udiff.Strings(tt.from, tt.to)
 			
-			dv := NewDiffViewerFromEdits(edits, tt.filename, tt.from, tt.sideBySide, tt.syntaxHighlight, tt.showWhitespace)
+			dv := NewDiffViewerFromEdits(edits, tt.filename, tt.from, defaultContextLines, tt.sideBySide, tt.syntaxHighlight, tt.showWhitespace)
 			
 			// Basic checks
 			if dv == nil {
@@ -0,0 +1,224 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/viper"
+)
+
+// ExamplesFile is the file of curated examples in the repository, relative to its root,
+// used instead of the history unless examples-file points elsewhere.
+const ExamplesFile = ".gitguy/examples.md"
+
+// defaultMaxExamples caps the commit messages, and the PR descriptions, sampled from the
+// history when max-examples is not configured.
+const defaultMaxExamples = 3
+
+// exampleHistoryDepth is how many commits back from HEAD examples are looked for.
+const exampleHistoryDepth = 200
+
+// maxExampleLength caps each example sent to the model, in characters.
+const maxExampleLength = 1500
+
+// minPRExampleLines is how many lines the message of a merge commit needs to be taken
+// for a PR description rather than a generated "Merge pull request" message.
+const minPRExampleLines = 3
+
+// Examples are commit messages and PR descriptions showing the conventions of a project,
+// sent to the model as few-shot examples.
+type Examples struct {
+	Commits []string
+	PRs     []string
+	// Style are commit messages illustrating the style, shown when the repository has no
+	// commit messages to show, see [Style.Examples].
+	Style []string
+}
+
+// IsZero reports whether there are no examples.
+func (e Examples) IsZero() bool {
+	return len(e.Commits) == 0 && len(e.PRs) == 0 && len(e.Style) == 0
+}
+
+// ParseExamples reads curated examples. Each example starts with a "COMMIT:" or "PR:"
+// line, like the response of the model, and runs until the next one:
+//
+//	COMMIT:
+//	net: fix buffer overflow in parser
+//
+//	The length field was trusted.
+//
+//	PR:
+//	## What changed
+func ParseExamples(text string) Examples {
+	var examples Examples
+	var section string
+	var lines []string
+	flush := func() {
+		example := strings.TrimSpace(strings.Join(lines, "\n"))
+		switch {
+		case example == "":
+		case section == "COMMIT":
+			examples.Commits = append(examples.Commits, example)
+		case section == "PR":
+			examples.PRs = append(examples.PRs, example)
+		}
+		lines = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if marker := strings.TrimSpace(line); marker == "COMMIT:" || marker == "PR:" {
+			flush()
+			section = strings.TrimSuffix(marker, ":")
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return examples
+}
+
+// HistoryExamples samples the most recent well-formed commit messages, those passing the
+// lint rules, and descriptions of merged PRs, from merge commits with a message of their
+// own, reachable from a revision, or from HEAD when it is empty. The revision is the base
+// of the changes being described, so that their own commits are never examples. Fixups
+// and reverts are skipped, and sign-offs and co-authors are left out of the messages.
+func (g *GitRepo) HistoryExamples(rules LintRules, from string, limit int) (Examples, error) {
+	var start plumbing.Hash
+	if from == "" {
+		head, err := g.repo.Head()
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return Examples{}, nil
+		}
+		if err != nil {
+			return Examples{}, fmt.Errorf("failed to get HEAD: %w", err)
+		}
+		start = head.Hash()
+	} else {
+		commit, err := g.resolveCommit(from)
+		if err != nil {
+			return Examples{}, err
+		}
+		start = commit.Hash
+	}
+
+	iter, err := g.repo.Log(&git.LogOptions{From: start})
+	if err != nil {
+		return Examples{}, fmt.Errorf("failed to get log: %w", err)
+	}
+	defer iter.Close()
+
+	var examples Examples
+	seen := make(map[string]bool)
+	for depth := 0; depth < exampleHistoryDepth && (len(examples.Commits) < limit || len(examples.PRs) < limit); depth++ {
+		commit, err := iter.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Examples{}, fmt.Errorf("failed to iterate commits: %w", err)
+		}
+		message := exampleMessage(commit)
+		subject, _, _ := strings.Cut(message, "\n")
+		if seen[subject] || isAutomaticMessage(subject) {
+			continue
+		}
+
+		// Subjects are only seen once accepted, so a rejected message does not hide a
+		// later well-formed one
+		switch {
+		case commit.NumParents() > 1:
+			if _, body, _ := strings.Cut(message, "\n"); len(examples.PRs) < limit && strings.Count(strings.TrimSpace(body), "\n")+1 >= minPRExampleLines {
+				examples.PRs = append(examples.PRs, truncateExample(strings.TrimSpace(body)))
+				seen[subject] = true
+			}
+		case len(examples.Commits) < limit && len(rules.Lint(message)) == 0:
+			examples.Commits = append(examples.Commits, truncateExample(message))
+			seen[subject] = true
+		}
+	}
+	return examples, nil
+}
+
+// exampleMessage returns the message of a commit without Signed-off-by and Co-authored-by
+// trailers, which the model must not write.
+func exampleMessage(commit *object.Commit) string {
+	var lines []string
+	for _, line := range strings.Split(commit.Message, "\n") {
+		match := trailerPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match != nil && (strings.EqualFold(match[1], TrailerCoAuthoredBy) || strings.EqualFold(match[1], TrailerSignedOffBy)) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// isAutomaticMessage reports subjects written by git or by tools rather than developers.
+func isAutomaticMessage(subject string) bool {
+	for _, prefix := range []string{"fixup!", "squash!", "amend!", "Revert \"", "WIP", "wip"} {
+		if strings.HasPrefix(subject, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateExample shortens an example to [maxExampleLength] characters.
+func truncateExample(example string) string {
+	if len([]rune(example)) <= maxExampleLength {
+		return example
+	}
+	return strings.TrimSpace(string([]rune(example)[:maxExampleLength])) + "\n[truncated]"
+}
+
+// ExamplesFromConfig returns the examples in the file of the examples-file setting, or
+// else in the [ExamplesFile] of the repository, or else samples max-examples of each
+// kind from the history of from, see [GitRepo.HistoryExamples], adding the examples of
+// the style when there are no commit messages. A max-examples of zero disables examples.
+func (g *GitRepo) ExamplesFromConfig(style Style, rules LintRules, from string) (Examples, error) {
+	limit := defaultMaxExamples
+	if viper.IsSet("max-examples") {
+		limit = viper.GetInt("max-examples")
+	}
+	if limit <= 0 {
+		return Examples{}, nil
+	}
+
+	if file := viper.GetString("examples-file"); file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return Examples{}, fmt.Errorf("failed to read examples file: %w", err)
+		}
+		return ParseExamples(string(content)), nil
+	}
+
+	worktree, err := g.repo.Worktree()
+	if err != nil && !errors.Is(err, git.ErrIsBareRepository) {
+		return Examples{}, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if worktree != nil {
+		content, err := readOptionalFile(worktree.Filesystem, ExamplesFile)
+		if err != nil {
+			return Examples{}, err
+		}
+		if content != "" {
+			return ParseExamples(content), nil
+		}
+	}
+
+	examples, err := g.HistoryExamples(rules, from, limit)
+	if err != nil {
+		return Examples{}, err
+	}
+	if len(examples.Commits) == 0 {
+		examples.Style = style.Examples[:min(limit, len(style.Examples))]
+	}
+	return examples, nil
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/viper"
+)
+
+func TestParseExamples(t *testing.T) {
+	examples := ParseExamples(`Curated examples for the model.
+
+COMMIT:
+net: fix buffer overflow in parser
+
+The length field was trusted.
+
+PR:
+## What changed
+
+- the parser
+
+---
+
+COMMIT:
+docs: explain the wire format
+`)
+
+	expectedCommits := []string{"net: fix buffer overflow in parser\n\nThe length field was trusted.", "docs: explain the wire format"}
+	if strings.Join(examples.Commits, "|") != strings.Join(expectedCommits, "|") {
+		t.Errorf("Commits = %q, expected %q", examples.Commits, expectedCommits)
+	}
+	if len(examples.PRs) != 1 || examples.PRs[0] != "## What changed\n\n- the parser\n\n---" {
+		t.Errorf("PRs = %q", examples.PRs)
+	}
+}
+
+func TestHistoryExamples(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.commitFile("a.txt", "a\n", "feat(parser): accept tabs\n\nTabs were rejected as invalid indentation.\n\nSigned-off-by: Ada <ada@example.com>\nCo-authored-by: Bob <bob@example.com>", "Ada")
+	tr.commitFile("b.txt", "b\n", "Added some stuff.", "Bob")
+	feature := tr.commitFile("c.txt", "c\n", "fix(parser): keep line numbers", "Ada")
+	tr.commitFile("d.txt", "d\n", "fixup! fix(parser): keep line numbers", "Ada")
+
+	wt, err := tr.repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	head, err := tr.repo.Head()
+	if err != nil {
+		t.Fatalf("Failed to get HEAD: %v", err)
+	}
+	sig := &object.Signature{Name: "Ada", Email: "ada@example.com"}
+	merge := "Merge branch 'feature/tabs' into 'main'\n\n## What changed\n\n- tabs are accepted\n\nSee merge request acme/app!12"
+	if _, err := wt.Commit(merge, &git.CommitOptions{Author: sig, Committer: sig, Parents: []plumbing.Hash{head.Hash(), feature}, AllowEmptyCommits: true}); err != nil {
+		t.Fatalf("Failed to commit merge: %v", err)
+	}
+	latest := tr.commitFile("e.txt", "e\n", "docs: describe tabs", "Ada")
+
+	repo := tr.gitRepo()
+	examples, err := repo.HistoryExamples(DefaultLintRules(), latest.String()+"^", 3)
+	if err != nil {
+		t.Fatalf("HistoryExamples() error: %v", err)
+	}
+
+	expectedCommits := []string{"fix(parser): keep line numbers", "feat(parser): accept tabs\n\nTabs were rejected as invalid indentation."}
+	if strings.Join(examples.Commits, "|") != strings.Join(expectedCommits, "|") {
+		t.Errorf("Commits = %q, expected %q", examples.Commits, expectedCommits)
+	}
+	if len(examples.PRs) != 1 || examples.PRs[0] != "## What changed\n\n- tabs are accepted\n\nSee merge request acme/app!12" {
+		t.Errorf("PRs = %q", examples.PRs)
+	}
+
+	examples, err = repo.HistoryExamples(DefaultLintRules(), "", 1)
+	if err != nil {
+		t.Fatalf("HistoryExamples() error: %v", err)
+	}
+	if len(examples.Commits) != 1 || examples.Commits[0] != "docs: describe tabs" {
+		t.Errorf("Expected only the latest commit, got %q", examples.Commits)
+	}
+}
+
+func TestHistoryExamplesAfterRejectedSubject(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.commitFile("a.txt", "a\n", "fix(parser): keep line numbers\n\nThey were reset after tabs.", "Ada")
+	tr.commitFile("a.txt", "b\n", "fix(parser): keep line numbers\nagain", "Ada")
+
+	examples, err := tr.gitRepo().HistoryExamples(DefaultLintRules(), "", 3)
+	if err != nil {
+		t.Fatalf("HistoryExamples() error: %v", err)
+	}
+	if len(examples.Commits) != 1 || examples.Commits[0] != "fix(parser): keep line numbers\n\nThey were reset after tabs." {
+		t.Errorf("Expected the well-formed message with the rejected subject, got %q", examples.Commits)
+	}
+}
+
+func TestExamplesFromConfig(t *testing.T) {
+	defer viper.Reset()
+	tr := newTestRepo(t)
+	tr.commitFile("a.txt", "a\n", "feat: start", "Ada")
+	repo := tr.gitRepo()
+
+	examples, err := repo.ExamplesFromConfig(Styles[0], DefaultLintRules(), "")
+	if err != nil {
+		t.Fatalf("ExamplesFromConfig() error: %v", err)
+	}
+	if len(examples.Commits) != 1 || examples.Commits[0] != "feat: start" {
+		t.Errorf("Expected the history to be sampled, got %q", examples.Commits)
+	}
+
+	tr.writeFile(ExamplesFile, "COMMIT:\nparser: accept tabs\n")
+	examples, err = repo.ExamplesFromConfig(Styles[0], DefaultLintRules(), "")
+	if err != nil {
+		t.Fatalf("ExamplesFromConfig() error: %v", err)
+	}
+	if len(examples.Commits) != 1 || examples.Commits[0] != "parser: accept tabs" {
+		t.Errorf("Expected the curated examples, got %q", examples.Commits)
+	}
+
+	viper.Set("max-examples", 0)
+	if examples, err := repo.ExamplesFromConfig(Styles[0], DefaultLintRules(), ""); err != nil || !examples.IsZero() {
+		t.Errorf("Expected no examples, got %q (%v)", examples.Commits, err)
+	}
+}
+
+func TestExamplesSkipTheDescribedRange(t *testing.T) {
+	defer viper.Reset()
+	tr := newTestRepo(t)
+	base := tr.commitFile("a.txt", "a\n", "docs: describe a", "Ada")
+	tr.branch("feature", base)
+	tr.commitFile("b.txt", "b\n", "feat: add b", "Ada")
+	viper.Set("include-commits", false)
+
+	fileDiffs := []FileDiff{offlineFileDiff(ChangeAdd, "", "b.txt", "+b\n")}
+	prompt, err := tr.gitRepo().PreparePrompt(fileDiffs, nil, "feature", "master")
+	if err != nil {
+		t.Fatalf("PreparePrompt() error: %v", err)
+	}
+	if examples := prompt.Context.Examples.Commits; len(examples) != 1 || examples[0] != "docs: describe a" {
+		t.Errorf("Expected only the base history as examples, got %q", examples)
+	}
+}
+
+func TestPromptShowsExamples(t *testing.T) {
+	defer viper.Reset()
+	tr := newTestRepo(t)
+	tr.commitFile("app/invoice.go", "package app\n", "feat(invoice): add totals\n\nTotals were computed by every caller.", "Ada")
+	viper.Set("anonymize", true)
+
+	fileDiffs := []FileDiff{offlineFileDiff(ChangeModify, "app/invoice.go", "app/invoice.go", "+tax\n")}
+	prompt, err := tr.gitRepo().PreparePrompt(fileDiffs, nil, "", "")
+	if err != nil {
+		t.Fatalf("PreparePrompt() error: %v", err)
+	}
+	_, user, err := prompt.Render()
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+
+	if !strings.Contains(user, "These are commit messages of this repository") ||
+		!strings.Contains(user, "```\nfeat(file1): add totals\n\nTotals were computed by every caller.\n```") {
+		t.Errorf("Expected the anonymized example in the prompt, got:\n%s", user)
+	}
+	if strings.Contains(user, "PR descriptions from this repository") {
+		t.Errorf("Expected no PR examples, got:\n%s", user)
+	}
+}
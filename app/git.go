@@ -1,27 +1,61 @@
 package app
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/aymanbagabas/go-udiff"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
 // GitRepo provides a wrapper around a go-git repository, simplifying git operations.
 type GitRepo struct {
 	repo *git.Repository
+
+	// includeUntracked shows untracked files as unstaged additions
+	includeUntracked bool
 }
 
-// RefInfo holds information about a git reference, such as a branch or commit.
+// Reference types reported in [RefInfo.Type].
+const (
+	RefTypeStaged      = "staged"
+	RefTypeUnstaged    = "unstaged"
+	RefTypeUncommitted = "uncommitted"
+	RefTypeBranch      = "branch"
+	RefTypeRemote      = "remote"
+	RefTypeTag         = "tag"
+	RefTypeCommit      = "commit"
+)
+
+// RefInfo holds information about a git reference, such as a branch, tag or commit.
 type RefInfo struct {
 	Name   string
 	Hash   string
-	Type   string // "branch" or "commit"
+	Type   string // one of the RefType constants
 	IsHead bool
+
+	// Author and When are only set for commits
+	Author string
+	When   time.Time
+}
+
+// CommitRef converts a commit into a RefInfo that can be selected like any other ref.
+func CommitRef(commit CommitInfo) RefInfo {
+	return RefInfo{
+		Name:   commit.Subject,
+		Hash:   commit.Hash,
+		Type:   RefTypeCommit,
+		Author: commit.Author,
+		When:   commit.When,
+	}
 }
 
 // OpenRepo opens a git repository at the given path.
@@ -47,7 +81,7 @@ func (g *GitRepo) GetBranches() ([]RefInfo, error) {
 		refs = append(refs, RefInfo{
 			Name: name,
 			Hash: ref.Hash().String()[:8],
-			Type: "branch",
+			Type: RefTypeBranch,
 		})
 		return nil
 	})
@@ -59,305 +93,351 @@ func (g *GitRepo) GetBranches() ([]RefInfo, error) {
 	return refs, nil
 }
 
-// GetRecentCommits returns a list of the most recent commits from the current HEAD.
-func (g *GitRepo) GetRecentCommits(limit int) ([]RefInfo, error) {
-	head, err := g.repo.Head()
+// GetRemoteBranches returns a list of all remote-tracking branches in the repository.
+// Symbolic references such as origin/HEAD are skipped.
+func (g *GitRepo) GetRemoteBranches() ([]RefInfo, error) {
+	references, err := g.repo.References()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+		return nil, fmt.Errorf("failed to get references: %w", err)
 	}
 
-	commits, err := g.repo.Log(&git.LogOptions{
-		From: head.Hash(),
+	var refs []RefInfo
+	err = references.ForEach(func(ref *plumbing.Reference) error {
+		if !ref.Name().IsRemote() || ref.Type() != plumbing.HashReference {
+			return nil
+		}
+		refs = append(refs, RefInfo{
+			Name: ref.Name().Short(),
+			Hash: ref.Hash().String()[:8],
+			Type: RefTypeRemote,
+		})
+		return nil
 	})
+
 	if err != nil {
-		return nil, fmt.Errorf("failed to get commits: %w", err)
+		return nil, fmt.Errorf("failed to iterate remote branches: %w", err)
 	}
 
-	var refs []RefInfo
-	count := 0
-	err = commits.ForEach(func(commit *object.Commit) error {
-		if count >= limit {
-			return fmt.Errorf("limit reached")
+	return refs, nil
+}
+
+// GetTags returns a list of all tags in the repository, newest first.
+// Annotated tags are peeled so that Hash always points at the tagged commit.
+func (g *GitRepo) GetTags() ([]RefInfo, error) {
+	tags, err := g.repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	type datedRef struct {
+		ref  RefInfo
+		when int64
+	}
+
+	var dated []datedRef
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		commitHash := ref.Hash()
+		if tag, err := g.repo.TagObject(ref.Hash()); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				// Tags pointing at trees or blobs cannot be compared
+				return nil
+			}
+			commitHash = commit.Hash
 		}
 
-		message := strings.Split(commit.Message, "\n")[0]
-		if len(message) > 50 {
-			message = message[:47] + "..."
+		commit, err := g.repo.CommitObject(commitHash)
+		if err != nil {
+			return nil
 		}
 
-		refs = append(refs, RefInfo{
-			Name: fmt.Sprintf("%s - %s", message, commit.Hash.String()[:8]),
-			Hash: commit.Hash.String(),
-			Type: "commit",
+		dated = append(dated, datedRef{
+			ref: RefInfo{
+				Name: ref.Name().Short(),
+				Hash: commitHash.String()[:8],
+				Type: RefTypeTag,
+			},
+			when: commit.Committer.When.Unix(),
 		})
-		count++
 		return nil
 	})
 
-	if err != nil && err.Error() != "limit reached" {
-		return nil, fmt.Errorf("failed to iterate commits: %w", err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate tags: %w", err)
+	}
+
+	sort.SliceStable(dated, func(i, j int) bool {
+		return dated[i].when > dated[j].when
+	})
+
+	refs := make([]RefInfo, 0, len(dated))
+	for _, d := range dated {
+		refs = append(refs, d.ref)
 	}
 
 	return refs, nil
 }
 
 // GetDiff generates a diff between two git references.
-func (g *GitRepo) GetDiff(from, to string) (string, error) {
-	fromHash, err := g.repo.ResolveRevision(plumbing.Revision(from))
+func (g *GitRepo) GetDiff(from, to string, opts DiffOptions) (string, error) {
+	fileDiffs, err := g.GetFileDiffs(from, to, opts)
 	if err != nil {
-		return "", fmt.Errorf("failed to resolve 'from' revision %s: %w", from, err)
+		return "", err
 	}
 
-	toHash, err := g.repo.ResolveRevision(plumbing.Revision(to))
-	if err != nil {
-		return "", fmt.Errorf("failed to resolve 'to' revision %s: %w", to, err)
-	}
+	return CombineFileDiffs(fileDiffs), nil
+}
 
-	fromCommit, err := g.repo.CommitObject(*fromHash)
+// GetFileDiffs returns individual file diffs between two git references, with renames,
+// copies, mode changes and binary files described by each diff's [FileChange].
+func (g *GitRepo) GetFileDiffs(from, to string, opts DiffOptions) ([]FileDiff, error) {
+	fromCommit, err := g.resolveCommit(from)
 	if err != nil {
-		return "", fmt.Errorf("failed to get 'from' commit: %w", err)
+		return nil, fmt.Errorf("failed to get 'from' commit: %w", err)
 	}
 
-	toCommit, err := g.repo.CommitObject(*toHash)
+	toCommit, err := g.resolveCommit(to)
 	if err != nil {
-		return "", fmt.Errorf("failed to get 'to' commit: %w", err)
+		return nil, fmt.Errorf("failed to get 'to' commit: %w", err)
 	}
 
 	fromTree, err := fromCommit.Tree()
 	if err != nil {
-		return "", fmt.Errorf("failed to get 'from' tree: %w", err)
+		return nil, fmt.Errorf("failed to get 'from' tree: %w", err)
 	}
 
 	toTree, err := toCommit.Tree()
 	if err != nil {
-		return "", fmt.Errorf("failed to get 'to' tree: %w", err)
+		return nil, fmt.Errorf("failed to get 'to' tree: %w", err)
 	}
 
-	changes, err := object.DiffTree(fromTree, toTree)
+	attributes, err := g.LoadAttributes()
 	if err != nil {
-		return "", fmt.Errorf("failed to diff trees: %w", err)
+		return nil, err
 	}
 
-	var diff strings.Builder
-	for _, change := range changes {
-		patch, err := change.Patch()
-		if err != nil {
-			continue
+	fromSnapshot, toSnapshot, err := g.changedSnapshots(fromTree, toTree)
+	if err != nil {
+		return nil, err
+	}
+
+	// Added files may copy a file the change leaves alone, so only then is the whole
+	// 'from' tree read
+	var sources snapshot
+	for path := range toSnapshot {
+		if _, ok := fromSnapshot[path]; !ok {
+			if sources, err = g.treeSnapshot(fromTree); err != nil {
+				return nil, fmt.Errorf("failed to read 'from' tree: %w", err)
+			}
+			break
 		}
-		diff.WriteString(patch.String())
 	}
 
-	return diff.String(), nil
+	return g.fileDiffs(diffSnapshots(fromSnapshot, toSnapshot, sources), attributes, opts)
 }
 
+// WorkingDiffMode selects which two versions of the working copy are compared.
+type WorkingDiffMode string
 
-// GetStagedFileDiffs returns individual file diffs for staged changes
-func (g *GitRepo) GetStagedFileDiffs() ([]FileDiff, error) {
-	hasStagedChanges, err := g.HasStagedChanges()
-	if err != nil {
-		return nil, err
-	}
+const (
+	// DiffStaged compares HEAD with the index, like git diff --staged.
+	DiffStaged WorkingDiffMode = "staged"
+	// DiffUnstaged compares the index with the working tree, like git diff.
+	DiffUnstaged WorkingDiffMode = "unstaged"
+	// DiffUncommitted compares HEAD with the working tree, like git diff HEAD.
+	DiffUncommitted WorkingDiffMode = "uncommitted"
+)
 
-	if !hasStagedChanges {
-		return nil, nil
+// WorkingDiffModes lists the working copy comparisons in the order they are offered.
+var WorkingDiffModes = []WorkingDiffMode{DiffStaged, DiffUnstaged, DiffUncommitted}
+
+// ParseWorkingDiffMode reports whether a ref hash is one of the working copy pseudo-refs
+// returned by [GitRepo.GetWorkingRef], and which comparison it selects.
+func ParseWorkingDiffMode(hash string) (WorkingDiffMode, bool) {
+	for _, mode := range WorkingDiffModes {
+		if hash == string(mode) {
+			return mode, true
+		}
 	}
+	return "", false
+}
 
-	stagedFiles, err := g.GetStagedFilePaths()
+// GetWorkingFileDiffs returns individual file diffs for a working copy comparison.
+func (g *GitRepo) GetWorkingFileDiffs(mode WorkingDiffMode, opts DiffOptions) ([]FileDiff, error) {
+	attributes, err := g.LoadAttributes()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get staged files: %w", err)
+		return nil, err
 	}
 
-	var fileDiffs []FileDiff
-	for _, filename := range stagedFiles {
-		edits, err := g.GetStagedFileEdits(filename)
-		if err != nil {
-			continue // Skip files we can't diff
-		}
-		
-		if len(edits) > 0 {
-			unifiedDiff := g.formatEditsAsUnifiedDiff(edits, filename)
-			fileDiffs = append(fileDiffs, FileDiff{
-				Filename: filename,
-				Content:  unifiedDiff,
-				Edits:    edits,
-			})
-		}
+	from, to, err := g.workingSnapshots(mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s files: %w", mode, err)
 	}
 
-	return fileDiffs, nil
+	return g.fileDiffs(diffSnapshots(from, to, nil), attributes, opts)
 }
 
-// GetStagedDiff generates a diff between HEAD and staged files
-func (g *GitRepo) GetStagedDiff() (string, error) {
-	hasStagedChanges, err := g.HasStagedChanges()
+// workingSnapshots returns the two snapshots compared by a working copy comparison.
+func (g *GitRepo) workingSnapshots(mode WorkingDiffMode) (snapshot, snapshot, error) {
+	index, err := g.indexSnapshot()
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
 
-	if !hasStagedChanges {
-		return "", nil
+	if mode == DiffStaged {
+		head, err := g.headSnapshot()
+		return head, index, err
 	}
 
-	stagedFiles, err := g.GetStagedFilePaths()
+	worktree, err := g.worktreeSnapshot(index)
 	if err != nil {
-		return "", fmt.Errorf("failed to get staged files: %w", err)
+		return nil, nil, err
 	}
 
-	var diffBuilder strings.Builder
-	for _, filename := range stagedFiles {
-		edits, err := g.GetStagedFileEdits(filename)
-		if err != nil {
-			continue // Skip files we can't diff
-		}
-		
-		if len(edits) > 0 {
-			unifiedDiff := g.formatEditsAsUnifiedDiff(edits, filename)
-			diffBuilder.WriteString(unifiedDiff)
-		}
+	switch mode {
+	case DiffUnstaged:
+		return index, worktree, nil
+	case DiffUncommitted:
+		head, err := g.headSnapshot()
+		return head, worktree, err
+	default:
+		return nil, nil, fmt.Errorf("unknown diff mode %q", mode)
 	}
-
-	return diffBuilder.String(), nil
 }
 
-// HasStagedChanges checks if there are any staged changes in the repository.
-func (g *GitRepo) HasStagedChanges() (bool, error) {
-	worktree, err := g.repo.Worktree()
-	if err != nil {
-		return false, fmt.Errorf("failed to get worktree: %w", err)
-	}
-
-	status, err := worktree.Status()
+// GetWorkingDiff generates a unified diff for a working copy comparison.
+func (g *GitRepo) GetWorkingDiff(mode WorkingDiffMode, opts DiffOptions) (string, error) {
+	fileDiffs, err := g.GetWorkingFileDiffs(mode, opts)
 	if err != nil {
-		return false, fmt.Errorf("failed to get status: %w", err)
-	}
-
-	for _, fileStatus := range status {
-		if fileStatus.Staging != '?' && fileStatus.Staging != ' ' {
-			return true, nil
-		}
+		return "", err
 	}
 
-	return false, nil
+	return CombineFileDiffs(fileDiffs), nil
 }
 
-// GetStagedRef returns a special RefInfo for staged files if any exist.
-func (g *GitRepo) GetStagedRef() (*RefInfo, error) {
-	hasStagedChanges, err := g.HasStagedChanges()
+// GetWorkingFilePaths returns the sorted paths of files that differ in a working copy comparison.
+func (g *GitRepo) GetWorkingFilePaths(mode WorkingDiffMode) ([]string, error) {
+	status, err := g.GetStatus()
 	if err != nil {
 		return nil, err
 	}
 
-	if !hasStagedChanges {
-		return nil, nil
+	var paths []string
+	for _, file := range status {
+		if file.InDiff(mode, g.includeUntracked) {
+			paths = append(paths, file.Path)
+		}
 	}
 
-	return &RefInfo{
-		Name: "Staged Changes",
-		Hash: "staged",
-		Type: "staged",
-	}, nil
+	return paths, nil
 }
 
-// GetUnstagedFileDiffs returns individual file diffs for unstaged changes
-func (g *GitRepo) GetUnstagedFileDiffs() ([]FileDiff, error) {
-	unstagedFiles, err := g.GetUnstagedFilePaths()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get unstaged files: %w", err)
-	}
-
-	if len(unstagedFiles) == 0 {
-		return nil, nil
-	}
-
-	var fileDiffs []FileDiff
-	for _, filename := range unstagedFiles {
-		edits, err := g.GetUnstagedFileEdits(filename)
-		if err != nil {
-			continue // Skip files we can't diff
+// workingFileContents returns both sides of a working copy comparison for one file.
+// A side where the file does not exist is returned as empty content.
+func (g *GitRepo) workingFileContents(mode WorkingDiffMode, filename string) (string, string, error) {
+	var original, modified string
+	var err error
+
+	switch mode {
+	case DiffStaged:
+		original, err = g.headFileContentOrEmpty(filename)
+		if err == nil {
+			modified, err = g.stagedFileContentOrEmpty(filename)
 		}
-		
-		if len(edits) > 0 {
-			unifiedDiff := g.formatEditsAsUnifiedDiff(edits, filename)
-			fileDiffs = append(fileDiffs, FileDiff{
-				Filename: filename,
-				Content:  unifiedDiff,
-				Edits:    edits,
-			})
+	case DiffUnstaged:
+		original, err = g.stagedFileContentOrEmpty(filename)
+		if err == nil {
+			modified, err = g.workingTreeFileContentOrEmpty(filename)
 		}
+	case DiffUncommitted:
+		original, err = g.headFileContentOrEmpty(filename)
+		if err == nil {
+			modified, err = g.workingTreeFileContentOrEmpty(filename)
+		}
+	default:
+		err = fmt.Errorf("unknown diff mode %q", mode)
 	}
 
-	return fileDiffs, nil
+	return original, modified, err
 }
 
-// GetUnstagedDiff generates a diff of unstaged changes (working tree vs HEAD).
-func (g *GitRepo) GetUnstagedDiff() (string, error) {
-	unstagedFiles, err := g.GetUnstagedFilePaths()
+// GetWorkingRef returns a pseudo-ref for a working copy comparison if it has any changes.
+// Its hash is the mode itself, see [ParseWorkingDiffMode].
+func (g *GitRepo) GetWorkingRef(mode WorkingDiffMode) (*RefInfo, error) {
+	paths, err := g.GetWorkingFilePaths(mode)
 	if err != nil {
-		return "", fmt.Errorf("failed to get unstaged files: %w", err)
+		return nil, err
 	}
 
-	if len(unstagedFiles) == 0 {
-		return "", nil
+	if len(paths) == 0 {
+		return nil, nil
 	}
 
-	var diffBuilder strings.Builder
-	for _, filename := range unstagedFiles {
-		edits, err := g.GetUnstagedFileEdits(filename)
-		if err != nil {
-			continue // Skip files we can't diff
-		}
-		
-		if len(edits) > 0 {
-			unifiedDiff := g.formatEditsAsUnifiedDiff(edits, filename)
-			diffBuilder.WriteString(unifiedDiff)
-		}
+	names := map[WorkingDiffMode]string{
+		DiffStaged:      "Staged Changes",
+		DiffUnstaged:    "Unstaged Changes",
+		DiffUncommitted: "All Uncommitted Changes",
 	}
 
-	return diffBuilder.String(), nil
+	return &RefInfo{
+		Name: names[mode],
+		Hash: string(mode),
+		Type: string(mode),
+	}, nil
 }
 
-// Returns slice of file paths for staged files
-func (g *GitRepo) GetStagedFilePaths() ([]string, error) {
-	worktree, err := g.repo.Worktree()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get worktree: %w", err)
-	}
+// GetStagedFileDiffs returns individual file diffs for staged changes (HEAD vs index)
+func (g *GitRepo) GetStagedFileDiffs(opts DiffOptions) ([]FileDiff, error) {
+	return g.GetWorkingFileDiffs(DiffStaged, opts)
+}
 
-	status, err := worktree.Status()
+// GetStagedDiff generates a diff between HEAD and staged files
+func (g *GitRepo) GetStagedDiff(opts DiffOptions) (string, error) {
+	return g.GetWorkingDiff(DiffStaged, opts)
+}
+
+// HasStagedChanges checks if there are any staged changes in the repository.
+func (g *GitRepo) HasStagedChanges() (bool, error) {
+	paths, err := g.GetWorkingFilePaths(DiffStaged)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get status: %w", err)
+		return false, err
 	}
 
-	var stagedFiles []string
-	for file, fileStatus := range status {
-		if fileStatus.Staging != '?' && fileStatus.Staging != ' ' {
-			stagedFiles = append(stagedFiles, file)
-		}
-	}
+	return len(paths) > 0, nil
+}
 
-	return stagedFiles, nil
+// GetStagedRef returns a special RefInfo for staged files if any exist.
+func (g *GitRepo) GetStagedRef() (*RefInfo, error) {
+	return g.GetWorkingRef(DiffStaged)
 }
 
-func (g *GitRepo) GetUnstagedFilePaths() ([]string, error) {
-	worktree, err := g.repo.Worktree()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get worktree: %w", err)
-	}
+// GetUnstagedFileDiffs returns individual file diffs for unstaged changes (index vs working tree)
+func (g *GitRepo) GetUnstagedFileDiffs(opts DiffOptions) ([]FileDiff, error) {
+	return g.GetWorkingFileDiffs(DiffUnstaged, opts)
+}
 
-	status, err := worktree.Status()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get status: %w", err)
-	}
+// GetUnstagedDiff generates a diff of unstaged changes (index vs working tree).
+func (g *GitRepo) GetUnstagedDiff(opts DiffOptions) (string, error) {
+	return g.GetWorkingDiff(DiffUnstaged, opts)
+}
 
-	var unstagedFiles []string
-	for file, fileStatus := range status {
-		// Check if file has unstaged changes
-		if fileStatus.Worktree != '?' && fileStatus.Worktree != ' ' {
-			unstagedFiles = append(unstagedFiles, file)
-		}
-	}
+// GetUncommittedFileDiffs returns individual file diffs for all uncommitted changes (HEAD vs working tree)
+func (g *GitRepo) GetUncommittedFileDiffs(opts DiffOptions) ([]FileDiff, error) {
+	return g.GetWorkingFileDiffs(DiffUncommitted, opts)
+}
+
+// GetUncommittedDiff generates a diff of all uncommitted changes (HEAD vs working tree).
+func (g *GitRepo) GetUncommittedDiff(opts DiffOptions) (string, error) {
+	return g.GetWorkingDiff(DiffUncommitted, opts)
+}
+
+// Returns slice of file paths for staged files
+func (g *GitRepo) GetStagedFilePaths() ([]string, error) {
+	return g.GetWorkingFilePaths(DiffStaged)
+}
 
-	return unstagedFiles, nil
+func (g *GitRepo) GetUnstagedFilePaths() ([]string, error) {
+	return g.GetWorkingFilePaths(DiffUnstaged)
 }
 
 func (g *GitRepo) GetStagedFileContent(filename string) (string, error) {
@@ -451,42 +531,74 @@ func (g *GitRepo) getFileContentFromCommit(commitHash, filename string) (string,
 	return content, nil
 }
 
-// GetStagedFileEdits returns udiff.Edit operations for a staged file compared to HEAD
-func (g *GitRepo) GetStagedFileEdits(filename string) ([]udiff.Edit, error) {
-	headContent, err := g.GetHEADFileContent(filename)
-	if err != nil {
-		// File might be new, use empty content for HEAD
-		headContent = ""
+// headFileContentOrEmpty returns a file's content at HEAD, or "" if it does not exist there.
+func (g *GitRepo) headFileContentOrEmpty(filename string) (string, error) {
+	content, err := g.GetHEADFileContent(filename)
+	if errors.Is(err, object.ErrFileNotFound) || errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return "", nil
 	}
+	return content, err
+}
 
-	stagedContent, err := g.GetStagedFileContent(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get staged file content: %w", err)
+// stagedFileContentOrEmpty returns a file's content in the index, or "" if it is not in the index.
+func (g *GitRepo) stagedFileContentOrEmpty(filename string) (string, error) {
+	content, err := g.GetStagedFileContent(filename)
+	if errors.Is(err, index.ErrEntryNotFound) {
+		return "", nil
 	}
+	return content, err
+}
 
-	edits := udiff.Strings(headContent, stagedContent)
-	return edits, nil
+// workingTreeFileContentOrEmpty returns a file's content in the working tree, or "" if it was deleted.
+func (g *GitRepo) workingTreeFileContentOrEmpty(filename string) (string, error) {
+	content, err := g.GetWorkingTreeFileContent(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return content, err
 }
 
-// GetUnstagedFileEdits returns udiff.Edit operations for an unstaged file compared to HEAD
-func (g *GitRepo) GetUnstagedFileEdits(filename string) ([]udiff.Edit, error) {
-	headContent, err := g.GetHEADFileContent(filename)
+// GetStagedFileEdits returns udiff.Edit operations for a staged file compared to HEAD
+func (g *GitRepo) GetStagedFileEdits(filename string, opts DiffOptions) ([]udiff.Edit, error) {
+	return g.workingFileEdits(DiffStaged, filename, opts)
+}
+
+// GetUnstagedFileEdits returns udiff.Edit operations for a working tree file compared to the index
+func (g *GitRepo) GetUnstagedFileEdits(filename string, opts DiffOptions) ([]udiff.Edit, error) {
+	return g.workingFileEdits(DiffUnstaged, filename, opts)
+}
+
+// GetUncommittedFileEdits returns udiff.Edit operations for a working tree file compared to HEAD
+func (g *GitRepo) GetUncommittedFileEdits(filename string, opts DiffOptions) ([]udiff.Edit, error) {
+	return g.workingFileEdits(DiffUncommitted, filename, opts)
+}
+
+func (g *GitRepo) workingFileEdits(mode WorkingDiffMode, filename string, opts DiffOptions) ([]udiff.Edit, error) {
+	original, modified, err := g.workingFileContents(mode, filename)
 	if err != nil {
-		// File might be new, use empty content for HEAD
-		headContent = ""
+		return nil, fmt.Errorf("failed to get %s file content: %w", mode, err)
 	}
 
-	workingTreeContent, err := g.GetWorkingTreeFileContent(filename)
+	attributes, err := g.LoadAttributes()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get working tree file content: %w", err)
+		return nil, err
 	}
 
-	edits := udiff.Strings(headContent, workingTreeContent)
-	return edits, nil
+	return fileEdits(attributes, filename, original, modified, opts), nil
+}
+
+// fileEdits computes the edits between two versions of a file, normalizing line endings
+// as its attributes ask.
+func fileEdits(attributes *Attributes, filename, original, modified string, opts DiffOptions) []udiff.Edit {
+	attrs := attributes.Lookup(filename)
+	original = string(attrs.normalizeEOL([]byte(original)))
+	modified = string(attrs.normalizeEOL([]byte(modified)))
+
+	return ComputeEdits(original, modified, opts)
 }
 
 // GetFileEdits returns udiff.Edit operations between two versions of a file
-func (g *GitRepo) GetFileEdits(filename, fromCommit, toCommit string) ([]udiff.Edit, error) {
+func (g *GitRepo) GetFileEdits(filename, fromCommit, toCommit string, opts DiffOptions) ([]udiff.Edit, error) {
 	fromContent, err := g.getFileContentFromCommit(fromCommit, filename)
 	if err != nil {
 		// File might not exist in from commit, use empty content
@@ -499,32 +611,12 @@ func (g *GitRepo) GetFileEdits(filename, fromCommit, toCommit string) ([]udiff.E
 		toContent = ""
 	}
 
-	edits := udiff.Strings(fromContent, toContent)
-	return edits, nil
-}
-
-// formatEditsAsUnifiedDiff converts udiff.Edit operations back to unified diff format
-func (g *GitRepo) formatEditsAsUnifiedDiff(edits []udiff.Edit, filename string) string {
-	if len(edits) == 0 {
-		return ""
-	}
-
-	// Get the original content to apply edits against
-	headContent, err := g.GetHEADFileContent(filename)
+	attributes, err := g.LoadAttributes()
 	if err != nil {
-		// File might be new, use empty content
-		headContent = ""
-	}
-
-	// Use udiff's ToUnified function
-	oldLabel := "a/" + filename
-	newLabel := "b/" + filename
-	unifiedDiff, err := udiff.ToUnified(oldLabel, newLabel, headContent, edits, 3)
-	if err != nil {
-		return ""
+		return nil, err
 	}
 
-	return unifiedDiff
+	return fileEdits(attributes, filename, fromContent, toContent, opts), nil
 }
 
 // GetPrimaryFilename returns the first filename from a list of FileDiffs, or a default
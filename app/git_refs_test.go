@@ -0,0 +1,89 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestGetRemoteBranches(t *testing.T) {
+	tr := newTestRepo(t)
+	head := tr.commitFile("README.md", "hello\n", "chore: initial commit", "Alice")
+
+	remoteMain := plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", "main"), head)
+	if err := tr.repo.Storer.SetReference(remoteMain); err != nil {
+		t.Fatalf("Failed to create remote ref: %v", err)
+	}
+	remoteHead := plumbing.NewSymbolicReference(plumbing.NewRemoteHEADReferenceName("origin"), remoteMain.Name())
+	if err := tr.repo.Storer.SetReference(remoteHead); err != nil {
+		t.Fatalf("Failed to create remote HEAD: %v", err)
+	}
+
+	refs, err := tr.gitRepo().GetRemoteBranches()
+	if err != nil {
+		t.Fatalf("GetRemoteBranches failed: %v", err)
+	}
+
+	if len(refs) != 1 {
+		t.Fatalf("Expected 1 remote branch (symbolic origin/HEAD skipped), got %d", len(refs))
+	}
+
+	if refs[0].Name != "origin/main" {
+		t.Errorf("Expected name 'origin/main', got %s", refs[0].Name)
+	}
+
+	if refs[0].Type != RefTypeRemote {
+		t.Errorf("Expected type %q, got %q", RefTypeRemote, refs[0].Type)
+	}
+}
+
+func TestGetTags(t *testing.T) {
+	tr := newTestRepo(t)
+	first := tr.commitFile("README.md", "v1\n", "chore: release 1", "Alice")
+	second := tr.commitFile("README.md", "v2\n", "chore: release 2", "Alice")
+
+	if _, err := tr.repo.CreateTag("v1.0.0", first, nil); err != nil {
+		t.Fatalf("Failed to create lightweight tag: %v", err)
+	}
+	_, err := tr.repo.CreateTag("v2.0.0", second, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Alice", Email: "alice@example.com"},
+		Message: "Release 2",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create annotated tag: %v", err)
+	}
+
+	refs, err := tr.gitRepo().GetTags()
+	if err != nil {
+		t.Fatalf("GetTags failed: %v", err)
+	}
+
+	if len(refs) != 2 {
+		t.Fatalf("Expected 2 tags, got %d", len(refs))
+	}
+
+	expected := []struct {
+		name string
+		hash string
+	}{
+		{"v2.0.0", second.String()[:8]},
+		{"v1.0.0", first.String()[:8]},
+	}
+
+	for i, want := range expected {
+		if refs[i].Name != want.name {
+			t.Errorf("Expected tag %d to be %s, got %s", i, want.name, refs[i].Name)
+		}
+		if refs[i].Hash != want.hash {
+			t.Errorf("Expected %s to point at commit %s, got %s", want.name, want.hash, refs[i].Hash)
+		}
+		if refs[i].Type != RefTypeTag {
+			t.Errorf("Expected type %q, got %q", RefTypeTag, refs[i].Type)
+		}
+		if _, err := tr.gitRepo().resolveCommit(refs[i].Hash); err != nil {
+			t.Errorf("Expected tag hash %s to resolve to a commit: %v", refs[i].Hash, err)
+		}
+	}
+}
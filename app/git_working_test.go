@@ -0,0 +1,213 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestWorkingFileDiffs(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.writeFile("both.txt", "one\n")
+	tr.writeFile("deleted.txt", "gone\n")
+	tr.stage("both.txt")
+	tr.stage("deleted.txt")
+	tr.commit("chore: initial commit", "Alice")
+
+	// both.txt has a staged change and a further unstaged change on top of it
+	tr.writeFile("both.txt", "two\n")
+	tr.stage("both.txt")
+	tr.writeFile("both.txt", "three\n")
+
+	// added.txt is a new file that is only staged
+	tr.writeFile("added.txt", "new\n")
+	tr.stage("added.txt")
+
+	// deleted.txt is removed from the working tree but not from the index
+	if err := os.Remove(filepath.Join(tr.dir, "deleted.txt")); err != nil {
+		t.Fatalf("Failed to delete file: %v", err)
+	}
+
+	tests := []struct {
+		mode     WorkingDiffMode
+		expected map[string][]string
+	}{
+		{
+			mode: DiffStaged,
+			expected: map[string][]string{
+				"added.txt": {"+new"},
+				"both.txt":  {"-one", "+two"},
+			},
+		},
+		{
+			mode: DiffUnstaged,
+			expected: map[string][]string{
+				"both.txt":    {"-two", "+three"},
+				"deleted.txt": {"-gone"},
+			},
+		},
+		{
+			mode: DiffUncommitted,
+			expected: map[string][]string{
+				"added.txt":   {"+new"},
+				"both.txt":    {"-one", "+three"},
+				"deleted.txt": {"-gone"},
+			},
+		},
+	}
+
+	repo := tr.gitRepo()
+	for _, test := range tests {
+		t.Run(string(test.mode), func(t *testing.T) {
+			fileDiffs, err := repo.GetWorkingFileDiffs(test.mode, DefaultDiffOptions())
+			if err != nil {
+				t.Fatalf("GetWorkingFileDiffs failed: %v", err)
+			}
+
+			if len(fileDiffs) != len(test.expected) {
+				t.Fatalf("Expected %d files, got %d: %+v", len(test.expected), len(fileDiffs), fileDiffs)
+			}
+
+			for _, fileDiff := range fileDiffs {
+				lines, ok := test.expected[fileDiff.Filename]
+				if !ok {
+					t.Errorf("Unexpected file %s in %s diff", fileDiff.Filename, test.mode)
+					continue
+				}
+				for _, line := range lines {
+					if !strings.Contains(fileDiff.Content, "\n"+line+"\n") {
+						t.Errorf("Expected %s diff of %s to contain %q, got:\n%s", test.mode, fileDiff.Filename, line, fileDiff.Content)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWorkingRefs(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.commitFile("README.md", "hello\n", "chore: initial commit", "Alice")
+	tr.writeFile("README.md", "hello world\n")
+
+	repo := tr.gitRepo()
+
+	staged, err := repo.GetWorkingRef(DiffStaged)
+	if err != nil {
+		t.Fatalf("GetWorkingRef failed: %v", err)
+	}
+	if staged != nil {
+		t.Errorf("Expected no staged ref without staged changes, got %+v", staged)
+	}
+
+	for _, mode := range []WorkingDiffMode{DiffUnstaged, DiffUncommitted} {
+		ref, err := repo.GetWorkingRef(mode)
+		if err != nil {
+			t.Fatalf("GetWorkingRef(%s) failed: %v", mode, err)
+		}
+		if ref == nil {
+			t.Fatalf("Expected a %s ref", mode)
+		}
+
+		parsed, ok := ParseWorkingDiffMode(ref.Hash)
+		if !ok || parsed != mode {
+			t.Errorf("Expected ref hash %q to parse as %s", ref.Hash, mode)
+		}
+	}
+
+	if _, ok := ParseWorkingDiffMode("abc12345"); ok {
+		t.Error("Expected a commit hash not to parse as a working diff mode")
+	}
+}
+
+func TestUntrackedFiles(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.writeFile(".gitignore", "*.log\n")
+	tr.stage(".gitignore")
+	tr.commit("chore: initial commit", "Alice")
+
+	tr.writeFile("notes/todo.txt", "first\nsecond\n")
+	tr.writeFile("debug.log", "ignored\n")
+
+	repo := tr.gitRepo()
+
+	status, err := repo.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if len(status) != 1 || status[0].Path != "notes/todo.txt" || !status[0].Untracked || status[0].Staged {
+		t.Fatalf("Expected only notes/todo.txt to be untracked, got %+v", status)
+	}
+
+	fileDiffs, err := repo.GetUnstagedFileDiffs(DefaultDiffOptions())
+	if err != nil {
+		t.Fatalf("GetUnstagedFileDiffs failed: %v", err)
+	}
+	if len(fileDiffs) != 0 {
+		t.Errorf("Expected untracked files to be hidden by default, got %d diffs", len(fileDiffs))
+	}
+
+	repo.SetIncludeUntracked(true)
+
+	for _, mode := range []WorkingDiffMode{DiffUnstaged, DiffUncommitted} {
+		fileDiffs, err := repo.GetWorkingFileDiffs(mode, DefaultDiffOptions())
+		if err != nil {
+			t.Fatalf("GetWorkingFileDiffs(%s) failed: %v", mode, err)
+		}
+		if len(fileDiffs) != 1 || fileDiffs[0].Filename != "notes/todo.txt" {
+			t.Fatalf("Expected the untracked file in %s changes, got %+v", mode, fileDiffs)
+		}
+		if !strings.Contains(fileDiffs[0].Content, "@@ -0,0 +1,2 @@\n+first\n+second\n") {
+			t.Errorf("Expected a whole-file addition, got:\n%s", fileDiffs[0].Content)
+		}
+	}
+
+	staged, err := repo.GetStagedFileDiffs(DefaultDiffOptions())
+	if err != nil {
+		t.Fatalf("GetStagedFileDiffs failed: %v", err)
+	}
+	if len(staged) != 0 {
+		t.Errorf("Expected untracked files not to be staged, got %+v", staged)
+	}
+}
+
+func TestUntrackedToggle(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.commitFile("README.md", "hello\n", "chore: initial commit", "Alice")
+	tr.writeFile("new.txt", "new\n")
+
+	m := newModel(tr.gitRepo())
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+	m = updated.(model)
+	updated, _ = m.Update(m.loadRefs()())
+	m = updated.(model)
+
+	workingRefs := func() int {
+		count := 0
+		for _, item := range m.currentRefList.Items() {
+			if _, ok := ParseWorkingDiffMode(item.(refItem).ref.Hash); ok {
+				count++
+			}
+		}
+		return count
+	}
+
+	if got := workingRefs(); got != 0 {
+		t.Fatalf("Expected no working copy refs while untracked files are hidden, got %d", got)
+	}
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u")})
+	m = updated.(model)
+	updated, _ = m.Update(cmd())
+	m = updated.(model)
+
+	// Unstaged and all uncommitted changes now include the new file
+	if got := workingRefs(); got != 2 {
+		t.Errorf("Expected 2 working copy refs after showing untracked files, got %d", got)
+	}
+	if got := len(m.currentRefList.Items()); got != 3 {
+		t.Errorf("Expected the branch to be kept alongside the working copy refs, got %d items", got)
+	}
+}
@@ -0,0 +1,80 @@
+package app
+
+import "github.com/aymanbagabas/go-udiff"
+
+// histogramMaxOccurrences bounds how often a line may occur in the old range to be used
+// as an anchor, as in git, so ranges of repetitive lines fall back to Myers.
+const histogramMaxOccurrences = 64
+
+// HistogramEdits computes line-level edits turning before into after with the histogram algorithm.
+func HistogramEdits(before, after string) []udiff.Edit {
+	return ComputeEdits(before, after, DiffOptions{Algorithm: DiffHistogram})
+}
+
+// histogram matches a[a0:a1] and b[b0:b1] by anchoring on the longest common run of
+// lines containing the least frequent line of the old range, then recursing on both
+// sides of it. Unlike patience, lines that are not unique can still serve as anchors.
+func (d *lineDiffer) histogram(a0, a1, b0, b1 int) {
+	a0, a1, b0, b1, suffix := d.trim(a0, a1, b0, b1)
+
+	if a0 < a1 && b0 < b1 {
+		if s1, e1, s2, e2, ok := d.histogramRegion(a0, a1, b0, b1); ok {
+			d.histogram(a0, s1, b0, s2)
+			for k := 0; k < e1-s1; k++ {
+				d.match(s1+k, s2+k)
+			}
+			d.histogram(e1, a1, e2, b1)
+		} else {
+			d.myers(a0, a1, b0, b1)
+		}
+	}
+
+	d.matchSuffix(a1, b1, suffix)
+}
+
+// histogramRegion finds the common run a[s1:e1] == b[s2:e2] whose rarest line occurs the
+// fewest times in a[a0:a1], preferring longer runs between equally rare ones.
+func (d *lineDiffer) histogramRegion(a0, a1, b0, b1 int) (s1, e1, s2, e2 int, ok bool) {
+	positions := make(map[int][]int)
+	for i := a0; i < a1; i++ {
+		positions[d.a[i]] = append(positions[d.a[i]], i)
+	}
+	count := func(line int) int { return len(positions[line]) }
+
+	best := histogramMaxOccurrences + 1
+	for j := b0; j < b1; {
+		next := j + 1
+		occurrences := positions[d.b[j]]
+		if len(occurrences) == 0 || len(occurrences) > best {
+			j = next
+			continue
+		}
+
+		for _, i := range occurrences {
+			rs1, rs2, re1, re2 := i, j, i+1, j+1
+			rarest := len(occurrences)
+			for rs1 > a0 && rs2 > b0 && d.a[rs1-1] == d.b[rs2-1] {
+				rs1--
+				rs2--
+				rarest = min(rarest, count(d.a[rs1]))
+			}
+			for re1 < a1 && re2 < b1 && d.a[re1] == d.b[re2] {
+				rarest = min(rarest, count(d.a[re1]))
+				re1++
+				re2++
+			}
+
+			// Skip the rest of this run in b, since every line in it yields the same region
+			next = max(next, re2)
+
+			if rarest < best || (rarest == best && re1-rs1 > e1-s1) {
+				s1, e1, s2, e2, ok = rs1, re1, rs2, re2, true
+				best = rarest
+			}
+		}
+
+		j = next
+	}
+
+	return s1, e1, s2, e2, ok
+}
@@ -0,0 +1,218 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// HistoryOptions filters the commits returned by [GitRepo.History].
+// Zero values disable the corresponding filter.
+type HistoryOptions struct {
+	// From is the revision history is walked from; defaults to HEAD.
+	From string
+	// Message matches commits whose message contains it, case-insensitively.
+	Message string
+	// Author matches commits whose author name or email contains it, case-insensitively.
+	Author string
+	// Since and Until bound the committer date, like git log --since and --until.
+	Since time.Time
+	Until time.Time
+}
+
+// IsZero reports whether no filters are set.
+func (o HistoryOptions) IsZero() bool {
+	return o.Message == "" && o.Author == "" && o.Since.IsZero() && o.Until.IsZero()
+}
+
+// String renders the options in the query syntax accepted by [ParseHistoryQuery].
+func (o HistoryOptions) String() string {
+	var parts []string
+	if o.Author != "" {
+		parts = append(parts, "author:"+o.Author)
+	}
+	if !o.Since.IsZero() {
+		parts = append(parts, "since:"+o.Since.Format("2006-01-02"))
+	}
+	if !o.Until.IsZero() {
+		parts = append(parts, "until:"+o.Until.Format("2006-01-02"))
+	}
+	if o.Message != "" {
+		parts = append(parts, o.Message)
+	}
+	return strings.Join(parts, " ")
+}
+
+// CommitIterator lazily walks commit history, newest first, applying [HistoryOptions].
+type CommitIterator struct {
+	iter object.CommitIter
+	opts HistoryOptions
+	done bool
+}
+
+// History returns an iterator over the commits reachable from opts.From (or HEAD)
+// ordered by committer time, newest first.
+func (g *GitRepo) History(opts HistoryOptions) (*CommitIterator, error) {
+	from := opts.From
+	if from == "" {
+		from = "HEAD"
+	}
+
+	hash, err := g.resolveHash(from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", from, err)
+	}
+
+	iter, err := g.repo.Log(&git.LogOptions{
+		From:  hash,
+		Order: git.LogOrderCommitterTime,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits: %w", err)
+	}
+
+	opts.Message = strings.ToLower(opts.Message)
+	opts.Author = strings.ToLower(opts.Author)
+
+	return &CommitIterator{iter: iter, opts: opts}, nil
+}
+
+// Next returns the next matching commit, or io.EOF when history is exhausted.
+func (it *CommitIterator) Next() (CommitInfo, error) {
+	for !it.done {
+		commit, err := it.iter.Next()
+		if errors.Is(err, io.EOF) {
+			it.Close()
+			break
+		}
+		if err != nil {
+			return CommitInfo{}, fmt.Errorf("failed to iterate commits: %w", err)
+		}
+
+		when := commit.Committer.When
+		if !it.opts.Since.IsZero() && when.Before(it.opts.Since) {
+			// History is ordered by committer time, so nothing older can match
+			it.Close()
+			break
+		}
+		if !it.opts.Until.IsZero() && when.After(it.opts.Until) {
+			continue
+		}
+		if it.opts.Message != "" && !strings.Contains(strings.ToLower(commit.Message), it.opts.Message) {
+			continue
+		}
+		if it.opts.Author != "" &&
+			!strings.Contains(strings.ToLower(commit.Author.Name), it.opts.Author) &&
+			!strings.Contains(strings.ToLower(commit.Author.Email), it.opts.Author) {
+			continue
+		}
+
+		return newCommitInfo(commit), nil
+	}
+
+	return CommitInfo{}, io.EOF
+}
+
+// NextPage returns up to n matching commits. A page shorter than n means
+// history is exhausted.
+func (it *CommitIterator) NextPage(n int) ([]CommitInfo, error) {
+	var page []CommitInfo
+	for len(page) < n {
+		commit, err := it.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return page, err
+		}
+		page = append(page, commit)
+	}
+	return page, nil
+}
+
+// Done reports whether the iterator has been exhausted.
+func (it *CommitIterator) Done() bool {
+	return it.done
+}
+
+// Close releases the underlying iterator.
+func (it *CommitIterator) Close() {
+	if !it.done {
+		it.iter.Close()
+		it.done = true
+	}
+}
+
+// ParseHistoryQuery parses a history search query. Free text matches the commit
+// message, and the author:, since: and until: qualifiers filter by author and date.
+// Dates accept the same syntax as reflog selectors, e.g. "since:2.weeks.ago" or
+// "until:2025-01-31".
+func ParseHistoryQuery(query string, now time.Time) (HistoryOptions, error) {
+	var opts HistoryOptions
+	var message []string
+
+	for _, field := range strings.Fields(query) {
+		key, value, found := strings.Cut(field, ":")
+		if !found || value == "" {
+			message = append(message, field)
+			continue
+		}
+
+		switch strings.ToLower(key) {
+		case "author":
+			opts.Author = value
+		case "since", "after":
+			since, err := parseApproxidate(value, now)
+			if err != nil {
+				return HistoryOptions{}, err
+			}
+			opts.Since = since
+		case "until", "before":
+			until, err := parseApproxidate(value, now)
+			if err != nil {
+				return HistoryOptions{}, err
+			}
+			opts.Until = until
+		default:
+			message = append(message, field)
+		}
+	}
+
+	opts.Message = strings.Join(message, " ")
+	return opts, nil
+}
+
+// relativeTime formats t relative to now, e.g. "3 days ago".
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	if d < time.Minute {
+		return "just now"
+	}
+
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+
+	switch {
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	case d < 7*24*time.Hour:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d < 30*24*time.Hour:
+		return plural(int(d/(7*24*time.Hour)), "week")
+	case d < 365*24*time.Hour:
+		return plural(int(d/(30*24*time.Hour)), "month")
+	default:
+		return plural(int(d/(365*24*time.Hour)), "year")
+	}
+}
@@ -0,0 +1,228 @@
+package app
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// commitHistory creates n commits alternating between two authors.
+func commitHistory(tr *testRepo, n int) {
+	for i := 0; i < n; i++ {
+		author := "Alice"
+		if i%2 == 1 {
+			author = "Bob"
+		}
+		tr.commitFile("file.txt", fmt.Sprintf("%d\n", i), fmt.Sprintf("chore: change %d", i), author)
+	}
+}
+
+func TestHistoryPagination(t *testing.T) {
+	tr := newTestRepo(t)
+	commitHistory(tr, 7)
+
+	iter, err := tr.gitRepo().History(HistoryOptions{})
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	defer iter.Close()
+
+	var pages [][]CommitInfo
+	for !iter.Done() {
+		page, err := iter.NextPage(3)
+		if err != nil {
+			t.Fatalf("NextPage failed: %v", err)
+		}
+		pages = append(pages, page)
+	}
+
+	if len(pages) != 3 || len(pages[0]) != 3 || len(pages[1]) != 3 || len(pages[2]) != 1 {
+		t.Fatalf("Expected pages of 3, 3 and 1 commits, got %d pages", len(pages))
+	}
+
+	if pages[0][0].Subject != "chore: change 6" {
+		t.Errorf("Expected newest commit first, got %q", pages[0][0].Subject)
+	}
+}
+
+func TestHistoryFilters(t *testing.T) {
+	tr := newTestRepo(t)
+	commitHistory(tr, 6)
+	repo := tr.gitRepo()
+
+	// Test commits are made one minute apart starting at 2025-01-01 00:01 UTC
+	tests := []struct {
+		name     string
+		opts     HistoryOptions
+		expected []string
+	}{
+		{
+			name:     "author",
+			opts:     HistoryOptions{Author: "BOB"},
+			expected: []string{"chore: change 5", "chore: change 3", "chore: change 1"},
+		},
+		{
+			name:     "author email",
+			opts:     HistoryOptions{Author: "alice@example"},
+			expected: []string{"chore: change 4", "chore: change 2", "chore: change 0"},
+		},
+		{
+			name:     "message",
+			opts:     HistoryOptions{Message: "Change 4"},
+			expected: []string{"chore: change 4"},
+		},
+		{
+			name: "date range",
+			opts: HistoryOptions{
+				Since: time.Date(2025, 1, 1, 0, 2, 0, 0, time.UTC),
+				Until: time.Date(2025, 1, 1, 0, 4, 0, 0, time.UTC),
+			},
+			expected: []string{"chore: change 3", "chore: change 2", "chore: change 1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			iter, err := repo.History(test.opts)
+			if err != nil {
+				t.Fatalf("History failed: %v", err)
+			}
+			defer iter.Close()
+
+			commits, err := iter.NextPage(100)
+			if err != nil {
+				t.Fatalf("NextPage failed: %v", err)
+			}
+
+			if len(commits) != len(test.expected) {
+				t.Fatalf("Expected %d commits, got %d", len(test.expected), len(commits))
+			}
+			for i, subject := range test.expected {
+				if commits[i].Subject != subject {
+					t.Errorf("Expected commit %d to be %q, got %q", i, subject, commits[i].Subject)
+				}
+			}
+		})
+	}
+}
+
+func TestParseHistoryQuery(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	opts, err := ParseHistoryQuery("fix parser author:alice since:2.weeks.ago until:2025-06-10", now)
+	if err != nil {
+		t.Fatalf("ParseHistoryQuery failed: %v", err)
+	}
+
+	if opts.Message != "fix parser" {
+		t.Errorf("Expected message 'fix parser', got %q", opts.Message)
+	}
+	if opts.Author != "alice" {
+		t.Errorf("Expected author 'alice', got %q", opts.Author)
+	}
+	if !opts.Since.Equal(now.AddDate(0, 0, -14)) {
+		t.Errorf("Unexpected since %v", opts.Since)
+	}
+	if !opts.Until.Equal(time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected until %v", opts.Until)
+	}
+
+	if _, err := ParseHistoryQuery("since:someday", now); err == nil {
+		t.Error("Expected error for unparseable date")
+	}
+
+	empty, err := ParseHistoryQuery("   ", now)
+	if err != nil || !empty.IsZero() {
+		t.Errorf("Expected empty query to clear all filters, got %+v (err: %v)", empty, err)
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		ago      time.Duration
+		expected string
+	}{
+		{10 * time.Second, "just now"},
+		{time.Minute, "1 minute ago"},
+		{5 * time.Hour, "5 hours ago"},
+		{3 * 24 * time.Hour, "3 days ago"},
+		{14 * 24 * time.Hour, "2 weeks ago"},
+		{65 * 24 * time.Hour, "2 months ago"},
+		{400 * 24 * time.Hour, "1 year ago"},
+	}
+
+	for _, test := range tests {
+		if result := relativeTime(now.Add(-test.ago), now); result != test.expected {
+			t.Errorf("relativeTime(-%v) = %q, expected %q", test.ago, result, test.expected)
+		}
+	}
+}
+
+func TestRefListHistoryPaging(t *testing.T) {
+	tr := newTestRepo(t)
+	commitHistory(tr, historyPageSize+5)
+
+	m := newModel(tr.gitRepo())
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+	m = updated.(model)
+
+	updated, cmd := m.Update(m.loadRefs()())
+	m = updated.(model)
+	updated, _ = m.Update(cmd())
+	m = updated.(model)
+
+	// One branch plus the first page of history
+	if got := len(m.currentRefList.Items()); got != 1+historyPageSize {
+		t.Fatalf("Expected %d items after the first page, got %d", 1+historyPageSize, got)
+	}
+
+	m.currentRefList.Select(len(m.currentRefList.Items()) - 1)
+	m, cmd = m.loadMoreHistory()
+	if cmd == nil {
+		t.Fatal("Expected reaching the end of the list to load another page")
+	}
+	updated, _ = m.Update(cmd())
+	m = updated.(model)
+
+	if got := len(m.currentRefList.Items()); got != 1+historyPageSize+5 {
+		t.Errorf("Expected %d items after the second page, got %d", 1+historyPageSize+5, got)
+	}
+	if !m.history.Done() {
+		t.Error("Expected history to be exhausted")
+	}
+
+	// Searching restarts the walk with only matching commits
+	m, cmd = m.startHistory(HistoryOptions{Author: "bob"})
+	updated, _ = m.Update(cmd())
+	m = updated.(model)
+
+	for _, item := range m.currentRefList.Items()[1:] {
+		if ref := item.(refItem).ref; ref.Author != "Bob" {
+			t.Errorf("Expected only Bob's commits after searching, got %s", ref.Author)
+		}
+	}
+}
+
+func TestRefListHistoryPageError(t *testing.T) {
+	tr := newTestRepo(t)
+	commitHistory(tr, 3)
+	m := newModel(tr.gitRepo())
+
+	m, cmd := m.startHistory(HistoryOptions{From: "missing"})
+	updated, _ := m.Update(cmd())
+	m = updated.(model)
+	if m.historyLoading || m.err == nil {
+		t.Fatalf("Expected the failed page to stop loading and show the error, loading %v, error %v", m.historyLoading, m.err)
+	}
+
+	m, cmd = m.startHistory(HistoryOptions{})
+	updated, _ = m.Update(cmd())
+	m = updated.(model)
+	if m.historyLoading || len(m.historyItems) != 3 {
+		t.Errorf("Expected a later search to load, got %d commits", len(m.historyItems))
+	}
+}
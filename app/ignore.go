@@ -0,0 +1,115 @@
+package app
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// PromptIgnoreFile is the repository file listing paths whose diffs are not sent to the
+// model, using .gitignore syntax.
+const PromptIgnoreFile = ".gitguyignore"
+
+// DefaultPromptIgnorePatterns are left out of the prompt unless re-included with a "!"
+// pattern in [PromptIgnoreFile]: lockfiles, vendored code, minified assets and
+// generated protobufs, whose churn says little about a change but costs many tokens.
+var DefaultPromptIgnorePatterns = []string{
+	"go.sum",
+	"package-lock.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+	"bun.lockb",
+	"Cargo.lock",
+	"Gemfile.lock",
+	"composer.lock",
+	"poetry.lock",
+	"Pipfile.lock",
+	"uv.lock",
+	"vendor/",
+	"node_modules/",
+	"*.min.js",
+	"*.min.css",
+	"*.pb.go",
+	"*.pb.cc",
+	"*.pb.h",
+	"*_pb2.py",
+	"*_pb2_grpc.py",
+	"*_pb.js",
+	"*_pb.d.ts",
+}
+
+// PromptIgnore decides which changed files are left out of the diff sent to the model.
+// They are still shown in the diff viewer.
+type PromptIgnore struct {
+	matcher gitignore.Matcher
+}
+
+// ParsePromptIgnore builds a PromptIgnore from the contents of a [PromptIgnoreFile].
+// Its patterns take precedence over [DefaultPromptIgnorePatterns].
+func ParsePromptIgnore(content string) *PromptIgnore {
+	var patterns []gitignore.Pattern
+	for _, line := range DefaultPromptIgnorePatterns {
+		patterns = append(patterns, gitignore.ParsePattern(line, nil))
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, nil))
+	}
+
+	return &PromptIgnore{matcher: gitignore.NewMatcher(patterns)}
+}
+
+// LoadPromptIgnore reads the [PromptIgnoreFile] at the root of the working tree. Without
+// one, or in a bare repository, only the default patterns apply.
+func (g *GitRepo) LoadPromptIgnore() (*PromptIgnore, error) {
+	worktree, err := g.repo.Worktree()
+	if errors.Is(err, git.ErrIsBareRepository) {
+		return ParsePromptIgnore(""), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	content, err := util.ReadFile(worktree.Filesystem, PromptIgnoreFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return ParsePromptIgnore(""), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", PromptIgnoreFile, err)
+	}
+
+	return ParsePromptIgnore(string(content)), nil
+}
+
+// Match reports whether a path is left out of the prompt.
+func (p *PromptIgnore) Match(path string) bool {
+	return p.matcher.Match(strings.Split(path, "/"), false)
+}
+
+// PromptDiff combines the file diffs that are sent to the model and returns the paths
+// of the files that were left out, either by the ignore patterns or because
+// .gitattributes marks them as generated.
+func (p *PromptIgnore) PromptDiff(fileDiffs []FileDiff) (string, []string) {
+	var kept []FileDiff
+	var omitted []string
+	for _, fileDiff := range fileDiffs {
+		if fileDiff.Change.Generated || p.Match(fileDiff.Filename) {
+			omitted = append(omitted, fileDiff.Filename)
+			continue
+		}
+		kept = append(kept, fileDiff)
+	}
+
+	return CombineFileDiffs(kept), omitted
+}
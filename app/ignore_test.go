@@ -0,0 +1,74 @@
+package app
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPromptIgnoreMatch(t *testing.T) {
+	ignore := ParsePromptIgnore("# generated\ndocs/api/\n!yarn.lock\n")
+
+	tests := map[string]bool{
+		"go.sum":                      true,
+		"tools/go.sum":                true,
+		"vendor/github.com/x/y.go":    true,
+		"web/node_modules/a/index.js": true,
+		"web/app.min.js":              true,
+		"api/v1/service.pb.go":        true,
+		"docs/api/index.html":         true,
+		"yarn.lock":                   false,
+		"main.go":                     false,
+		"docs/guide.md":               false,
+	}
+
+	for path, expected := range tests {
+		if result := ignore.Match(path); result != expected {
+			t.Errorf("Match(%q) = %v, expected %v", path, result, expected)
+		}
+	}
+}
+
+func TestPromptDiff(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.writeFile("main.go", "package main\n")
+	tr.writeFile("go.sum", "a v1\n")
+	tr.writeFile("gen/schema.sql", "create table a;\n")
+	tr.stage("main.go")
+	tr.stage("go.sum")
+	tr.stage("gen/schema.sql")
+	base := tr.commit("chore: initial commit", "Alice")
+
+	tr.writeFile("main.go", "package main\n\nfunc main() {}\n")
+	tr.writeFile("go.sum", "a v2\n")
+	tr.writeFile("gen/schema.sql", "create table b;\n")
+	tr.writeFile(PromptIgnoreFile, "gen/\n")
+	for _, name := range []string{"main.go", "go.sum", "gen/schema.sql", PromptIgnoreFile} {
+		tr.stage(name)
+	}
+	head := tr.commit("feat: add main", "Alice")
+
+	repo := tr.gitRepo()
+	fileDiffs, err := repo.GetFileDiffs(base.String(), head.String(), DefaultDiffOptions())
+	if err != nil {
+		t.Fatalf("GetFileDiffs failed: %v", err)
+	}
+
+	ignore, err := repo.LoadPromptIgnore()
+	if err != nil {
+		t.Fatalf("LoadPromptIgnore failed: %v", err)
+	}
+
+	diff, omitted := ignore.PromptDiff(fileDiffs)
+	if expected := []string{"gen/schema.sql", "go.sum"}; !reflect.DeepEqual(omitted, expected) {
+		t.Errorf("Expected %v to be omitted, got %v", expected, omitted)
+	}
+	if !strings.Contains(diff, "func main") || strings.Contains(diff, "a v2") || strings.Contains(diff, "create table") {
+		t.Errorf("Expected only main.go and the ignore file in the prompt diff, got:\n%s", diff)
+	}
+
+	prompt := renderUserPrompt(t, diff, PromptContext{OmittedFiles: omitted})
+	if !strings.Contains(prompt, "- go.sum\n") {
+		t.Errorf("Expected prompt to list omitted files, got:\n%s", prompt)
+	}
+}
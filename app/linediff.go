@@ -0,0 +1,342 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/aymanbagabas/go-udiff"
+	"github.com/spf13/viper"
+)
+
+// DiffAlgorithm selects how the lines of two file versions are matched up.
+type DiffAlgorithm string
+
+const (
+	// DiffMyers finds a minimal diff, like git's default algorithm.
+	DiffMyers DiffAlgorithm = "myers"
+	// DiffPatience anchors the diff on lines that occur once on both sides.
+	DiffPatience DiffAlgorithm = "patience"
+	// DiffHistogram anchors the diff on the least frequent common lines.
+	DiffHistogram DiffAlgorithm = "histogram"
+)
+
+// defaultContextLines is the number of unchanged lines shown around each hunk, as in git.
+const defaultContextLines = 3
+
+// DiffOptions controls how file versions are compared and rendered as unified diffs.
+type DiffOptions struct {
+	// Context is the number of unchanged lines shown around each hunk.
+	Context int
+	// IgnoreAllSpace ignores whitespace when comparing lines, like git diff -w.
+	IgnoreAllSpace bool
+	// IgnoreSpaceChange ignores changes in the amount of whitespace, like git diff -b.
+	IgnoreSpaceChange bool
+	// IgnoreBlankLines ignores changes whose lines are all blank.
+	IgnoreBlankLines bool
+	// Algorithm selects the diff algorithm; empty means Myers.
+	Algorithm DiffAlgorithm
+	// Pathspec limits the diff to matching files.
+	Pathspec Pathspec
+}
+
+// DefaultDiffOptions returns git's defaults: three lines of context and the Myers algorithm.
+func DefaultDiffOptions() DiffOptions {
+	return DiffOptions{Context: defaultContextLines, Algorithm: DiffMyers}
+}
+
+// ParseDiffAlgorithm parses a diff algorithm name as accepted by git's --diff-algorithm.
+func ParseDiffAlgorithm(name string) (DiffAlgorithm, error) {
+	switch algorithm := DiffAlgorithm(strings.ToLower(strings.TrimSpace(name))); algorithm {
+	case "", "default", DiffMyers:
+		return DiffMyers, nil
+	case DiffPatience, DiffHistogram:
+		return algorithm, nil
+	default:
+		return "", fmt.Errorf("unknown diff algorithm %q (expected myers, patience or histogram)", name)
+	}
+}
+
+// DiffOptionsFromConfig reads diff options from the config file and flags, falling back
+// to [DefaultDiffOptions] for anything that is not set.
+func DiffOptionsFromConfig() (DiffOptions, error) {
+	opts := DefaultDiffOptions()
+
+	if viper.IsSet("diff-context") {
+		opts.Context = viper.GetInt("diff-context")
+	}
+	opts.IgnoreAllSpace = viper.GetBool("ignore-all-space")
+	opts.IgnoreSpaceChange = viper.GetBool("ignore-space-change")
+	opts.IgnoreBlankLines = viper.GetBool("ignore-blank-lines")
+
+	algorithm, err := ParseDiffAlgorithm(viper.GetString("diff-algorithm"))
+	if err != nil {
+		return DiffOptions{}, err
+	}
+	opts.Algorithm = algorithm
+
+	pathspec, err := ParsePathspec(viper.GetStringSlice("pathspec"))
+	if err != nil {
+		return DiffOptions{}, err
+	}
+	opts.Pathspec = pathspec
+
+	if opts.Context < 0 {
+		return DiffOptions{}, fmt.Errorf("diff context must not be negative, got %d", opts.Context)
+	}
+
+	return opts, nil
+}
+
+// ComputeEdits computes line-level edits turning before into after. Lines are compared
+// according to the whitespace options, so lines that only differ in ignored whitespace
+// produce no edits and applying the result may not reproduce after exactly.
+func ComputeEdits(before, after string, opts DiffOptions) []udiff.Edit {
+	if before == after {
+		return nil
+	}
+
+	aLines := splitLines(before)
+	bLines := splitLines(after)
+
+	d := newLineDiffer(aLines, bLines, opts)
+	d.diff(0, len(d.a), 0, len(d.b), opts.Algorithm)
+
+	var edits []udiff.Edit
+	offsets := lineOffsets(aLines)
+	for _, h := range d.hunks() {
+		if opts.IgnoreBlankLines && allBlank(aLines[h.a0:h.a1]) && allBlank(bLines[h.b0:h.b1]) {
+			continue
+		}
+		edits = append(edits, udiff.Edit{
+			Start: offsets[h.a0],
+			End:   offsets[h.a1],
+			New:   strings.Join(bLines[h.b0:h.b1], ""),
+		})
+	}
+
+	return edits
+}
+
+// splitLines splits text into lines, keeping each line's trailing newline.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineOffsets returns the byte offset of each line's start, plus the total length.
+func lineOffsets(lines []string) []int {
+	offsets := make([]int, len(lines)+1)
+	for i, line := range lines {
+		offsets[i+1] = offsets[i] + len(line)
+	}
+	return offsets
+}
+
+// allBlank reports whether every line is empty or whitespace.
+func allBlank(lines []string) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// lineKey returns the form of a line that is compared, applying the whitespace options.
+func lineKey(line string, opts DiffOptions) string {
+	switch {
+	case opts.IgnoreAllSpace:
+		return strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, line)
+	case opts.IgnoreSpaceChange:
+		// Collapse runs of whitespace into a single space and drop trailing whitespace
+		var b strings.Builder
+		space := false
+		for _, r := range strings.TrimRightFunc(line, unicode.IsSpace) {
+			if unicode.IsSpace(r) {
+				space = true
+				continue
+			}
+			if space {
+				b.WriteByte(' ')
+				space = false
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	default:
+		return line
+	}
+}
+
+// lineHunk is a changed region where lines a[a0:a1] are replaced by b[b0:b1].
+type lineHunk struct {
+	a0, a1, b0, b1 int
+}
+
+// lineDiffer matches the lines of two texts. Lines are interned to integers so that
+// comparisons are cheap and whitespace options are applied once.
+type lineDiffer struct {
+	a, b    []int
+	matches [][2]int
+}
+
+func newLineDiffer(aLines, bLines []string, opts DiffOptions) *lineDiffer {
+	ids := make(map[string]int)
+	intern := func(lines []string) []int {
+		result := make([]int, len(lines))
+		for i, line := range lines {
+			key := lineKey(line, opts)
+			id, ok := ids[key]
+			if !ok {
+				id = len(ids)
+				ids[key] = id
+			}
+			result[i] = id
+		}
+		return result
+	}
+
+	return &lineDiffer{a: intern(aLines), b: intern(bLines)}
+}
+
+// match records that a[i] and b[j] are the same line. Matches must be recorded in order.
+func (d *lineDiffer) match(i, j int) {
+	d.matches = append(d.matches, [2]int{i, j})
+}
+
+// diff matches the lines of a[a0:a1] and b[b0:b1] with the given algorithm.
+func (d *lineDiffer) diff(a0, a1, b0, b1 int, algorithm DiffAlgorithm) {
+	switch algorithm {
+	case DiffPatience:
+		d.patience(a0, a1, b0, b1)
+	case DiffHistogram:
+		d.histogram(a0, a1, b0, b1)
+	default:
+		d.myers(a0, a1, b0, b1)
+	}
+}
+
+// hunks converts the recorded matches into the changed regions between them.
+func (d *lineDiffer) hunks() []lineHunk {
+	var hunks []lineHunk
+	i, j := 0, 0
+	for _, m := range append(d.matches, [2]int{len(d.a), len(d.b)}) {
+		if m[0] > i || m[1] > j {
+			hunks = append(hunks, lineHunk{a0: i, a1: m[0], b0: j, b1: m[1]})
+		}
+		i, j = m[0]+1, m[1]+1
+	}
+	return hunks
+}
+
+// trim matches the common prefix of a[a0:a1] and b[b0:b1] and returns the remaining
+// ranges without their common suffix, whose length is returned for [lineDiffer.matchSuffix].
+func (d *lineDiffer) trim(a0, a1, b0, b1 int) (int, int, int, int, int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.match(a0, b0)
+		a0++
+		b0++
+	}
+
+	suffix := 0
+	for a0 < a1 && b0 < b1 && d.a[a1-1] == d.b[b1-1] {
+		a1--
+		b1--
+		suffix++
+	}
+
+	return a0, a1, b0, b1, suffix
+}
+
+// matchSuffix matches the common suffix removed by [lineDiffer.trim], which starts at a1 and b1.
+func (d *lineDiffer) matchSuffix(a1, b1, suffix int) {
+	for k := 0; k < suffix; k++ {
+		d.match(a1+k, b1+k)
+	}
+}
+
+// myers matches a[a0:a1] and b[b0:b1] with Myers' linear-space divide and conquer
+// algorithm, which finds a shortest edit script.
+func (d *lineDiffer) myers(a0, a1, b0, b1 int) {
+	a0, a1, b0, b1, suffix := d.trim(a0, a1, b0, b1)
+
+	// With the common prefix and suffix removed, a non-trivial range has an edit
+	// distance of at least two, so both halves around the middle snake are smaller
+	if a0 < a1 && b0 < b1 {
+		x, y, u, v := d.middleSnake(a0, a1, b0, b1)
+		d.myers(a0, x, b0, y)
+		for k := 0; k < u-x; k++ {
+			d.match(x+k, y+k)
+		}
+		d.myers(u, a1, v, b1)
+	}
+
+	d.matchSuffix(a1, b1, suffix)
+}
+
+// middleSnake finds the middle snake of an optimal path through the edit graph of
+// a[a0:a1] and b[b0:b1], returning its start (x, y) and end (u, v).
+func (d *lineDiffer) middleSnake(a0, a1, b0, b1 int) (x, y, u, v int) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+
+	forward := make([]int, 2*limit+3)
+	backward := make([]int, 2*limit+3)
+
+	for step := 0; step <= limit; step++ {
+		for k := -step; k <= step; k += 2 {
+			var px int
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				px = forward[offset+k+1]
+			} else {
+				px = forward[offset+k-1] + 1
+			}
+			py := px - k
+			sx, sy := px, py
+			for px < n && py < m && d.a[a0+px] == d.b[b0+py] {
+				px++
+				py++
+			}
+			forward[offset+k] = px
+
+			if back := delta - k; odd && back >= -(step-1) && back <= step-1 && px+backward[offset+back] >= n {
+				return a0 + sx, b0 + sy, a0 + px, b0 + py
+			}
+		}
+
+		for k := -step; k <= step; k += 2 {
+			var px int
+			if k == -step || (k != step && backward[offset+k-1] < backward[offset+k+1]) {
+				px = backward[offset+k+1]
+			} else {
+				px = backward[offset+k-1] + 1
+			}
+			py := px - k
+			sx, sy := px, py
+			for px < n && py < m && d.a[a1-1-px] == d.b[b1-1-py] {
+				px++
+				py++
+			}
+			backward[offset+k] = px
+
+			if fwd := delta - k; !odd && fwd >= -step && fwd <= step && px+forward[offset+fwd] >= n {
+				return a1 - px, b1 - py, a1 - sx, b1 - sy
+			}
+		}
+	}
+
+	// Unreachable for non-empty ranges: an optimal path has at most n+m edits
+	return a0, b0, a0, b0
+}
@@ -0,0 +1,186 @@
+package app
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/aymanbagabas/go-udiff"
+)
+
+// lcsLength returns the length of the longest common subsequence of two line slices.
+func lcsLength(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+	return table[0][0]
+}
+
+// randomLines builds a text of up to n lines drawn from a small alphabet, so lines repeat.
+func randomLines(rng *rand.Rand, n int) string {
+	var b strings.Builder
+	for i := rng.Intn(n + 1); i > 0; i-- {
+		b.WriteString(string(rune('a'+rng.Intn(4))) + "\n")
+	}
+	return b.String()
+}
+
+func TestComputeEditsMyers(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	opts := DefaultDiffOptions()
+
+	for i := 0; i < 500; i++ {
+		before := randomLines(rng, 15)
+		after := randomLines(rng, 15)
+
+		edits := ComputeEdits(before, after, opts)
+		result, err := udiff.Apply(before, edits)
+		if err != nil {
+			t.Fatalf("Apply failed for %q -> %q: %v", before, after, err)
+		}
+		if result != after {
+			t.Fatalf("Applying edits to %q produced %q, expected %q", before, result, after)
+		}
+
+		// A shortest edit script keeps a longest common subsequence of lines
+		d := newLineDiffer(splitLines(before), splitLines(after), opts)
+		d.diff(0, len(d.a), 0, len(d.b), DiffMyers)
+		if expected := lcsLength(splitLines(before), splitLines(after)); len(d.matches) != expected {
+			t.Fatalf("Expected %d matched lines for %q -> %q, got %d", expected, before, after, len(d.matches))
+		}
+	}
+}
+
+func TestComputeEditsWhitespace(t *testing.T) {
+	tests := []struct {
+		name        string
+		before      string
+		after       string
+		opts        DiffOptions
+		expectEdits bool
+	}{
+		{"whitespace change shown by default", "a  b\n", "a b\n", DiffOptions{}, true},
+		{"ignore all space", "if (x) {\n", "if(x){\n", DiffOptions{IgnoreAllSpace: true}, false},
+		{"ignore space change", "a  b\t\n", "a b\n", DiffOptions{IgnoreSpaceChange: true}, false},
+		{"space change keeps added space", "ab\n", "a b\n", DiffOptions{IgnoreSpaceChange: true}, true},
+		{"ignore blank lines", "a\nb\n", "a\n\n\nb\n", DiffOptions{IgnoreBlankLines: true}, false},
+		{"blank lines with content change", "a\nb\n", "a\n\nc\n", DiffOptions{IgnoreBlankLines: true}, true},
+		{"missing final newline", "a\nb", "a\nb\n", DiffOptions{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			edits := ComputeEdits(test.before, test.after, test.opts)
+			if hasEdits := len(edits) > 0; hasEdits != test.expectEdits {
+				t.Errorf("Expected edits: %v, got %v", test.expectEdits, edits)
+			}
+		})
+	}
+}
+
+func TestDiffOptionsContext(t *testing.T) {
+	before := "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+	after := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n"
+	change := FileChange{Kind: ChangeModify, OldPath: "n.txt", NewPath: "n.txt"}
+
+	for _, test := range []struct {
+		context int
+		hunk    string
+	}{
+		{0, "@@ -5 +5 @@\n-5\n+five\n"},
+		{1, "@@ -4,3 +4,3 @@\n 4\n-5\n+five\n 6\n"},
+		{3, "@@ -2,7 +2,7 @@\n"},
+	} {
+		edits := ComputeEdits(before, after, DiffOptions{Context: test.context})
+		unified := formatEditsAsUnifiedDiff(before, edits, change, test.context)
+		if !strings.Contains(unified, test.hunk) {
+			t.Errorf("Expected %d lines of context to produce %q, got:\n%s", test.context, test.hunk, unified)
+		}
+	}
+}
+
+func TestParseDiffAlgorithm(t *testing.T) {
+	tests := map[string]DiffAlgorithm{
+		"":          DiffMyers,
+		"default":   DiffMyers,
+		"Myers":     DiffMyers,
+		"patience":  DiffPatience,
+		"histogram": DiffHistogram,
+	}
+
+	for name, expected := range tests {
+		algorithm, err := ParseDiffAlgorithm(name)
+		if err != nil || algorithm != expected {
+			t.Errorf("ParseDiffAlgorithm(%q) = %q, %v; expected %q", name, algorithm, err, expected)
+		}
+	}
+
+	if _, err := ParseDiffAlgorithm("minimal-ish"); err == nil {
+		t.Error("Expected an error for an unknown algorithm")
+	}
+}
+
+func TestComputeEditsAnchoredAlgorithms(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+
+	for _, algorithm := range []DiffAlgorithm{DiffPatience, DiffHistogram} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			opts := DiffOptions{Algorithm: algorithm}
+			for i := 0; i < 500; i++ {
+				before := randomLines(rng, 20)
+				after := randomLines(rng, 20)
+
+				edits := ComputeEdits(before, after, opts)
+				result, err := udiff.Apply(before, edits)
+				if err != nil {
+					t.Fatalf("Apply failed for %q -> %q: %v", before, after, err)
+				}
+				if result != after {
+					t.Fatalf("Applying edits to %q produced %q, expected %q", before, result, after)
+				}
+			}
+		})
+	}
+}
+
+func TestPatienceAndHistogramEdits(t *testing.T) {
+	// Myers keeps the most lines, the two repeated braces, and rewrites the unique
+	// signature; the anchored algorithms keep the signature and move the braces instead
+	before := "}\n}\nfunc main() {\n"
+	after := "func main() {\n}\n}\n"
+	change := FileChange{Kind: ChangeModify, OldPath: "main.go", NewPath: "main.go"}
+
+	myers := formatEditsAsUnifiedDiff(before, ComputeEdits(before, after, DefaultDiffOptions()), change, 0)
+	if !strings.Contains(myers, "-func main() {\n") {
+		t.Fatalf("Expected Myers to rewrite the signature, got:\n%s", myers)
+	}
+
+	tests := map[DiffAlgorithm]func(before, after string) []udiff.Edit{
+		DiffPatience:  PatienceEdits,
+		DiffHistogram: HistogramEdits,
+	}
+
+	for algorithm, edits := range tests {
+		t.Run(string(algorithm), func(t *testing.T) {
+			result := edits(before, after)
+			if applied, err := udiff.Apply(before, result); err != nil || applied != after {
+				t.Fatalf("Expected edits to reproduce after, got %q (%v)", applied, err)
+			}
+
+			unified := formatEditsAsUnifiedDiff(before, result, change, 0)
+			if strings.Contains(unified, "func main") {
+				t.Errorf("Expected the signature to be unchanged, got:\n%s", unified)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/viper"
+)
+
+// defaultLintRepairAttempts is how often the model is asked to repair a commit message
+// that breaks the lint rules, when lint-repair-attempts is not configured.
+const defaultLintRepairAttempts = 2
+
+// DefaultCommitTypes are the types of the Conventional Commits specification and the
+// Angular convention it builds on.
+var DefaultCommitTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+// notImperative are common words ending like past tense or gerund forms that are
+// imperative verbs or nouns nonetheless.
+var notImperative = map[string]bool{
+	"bring": true, "string": true, "spring": true, "swing": true, "sing": true, "ring": true,
+	"ping": true, "thing": true, "king": true, "wing": true, "sling": true, "cling": true,
+	"embed": true, "need": true, "feed": true, "seed": true, "speed": true, "bleed": true,
+	"breed": true, "shred": true, "proceed": true, "exceed": true, "succeed": true,
+	"bed": true, "red": true, "shed": true, "weed": true, "sled": true,
+}
+
+// LintViolation is a commit message rule that failed. Rule names follow commitlint where
+// it has an equivalent rule.
+type LintViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (v LintViolation) String() string {
+	return v.Rule + ": " + v.Message
+}
+
+// LintRules configures which commit messages are accepted.
+type LintRules struct {
+	// Conventional requires a "type(scope): description" header.
+	Conventional bool
+	// MaxHeaderLength limits the length of the first line, zero disables the check.
+	MaxHeaderLength int
+	// Imperative requires the description to start with an imperative verb, e.g. "add"
+	// rather than "added" or "adding".
+	Imperative bool
+	// ThirdPerson also rejects third person forms like "adds". It is off by default, as
+	// subjects often start with a plural noun like "deps" or "settings".
+	ThirdPerson bool
+	// Types and Scopes list the allowed types and scopes; empty allows any.
+	Types  []string
+	Scopes []string
+	// HeaderPattern is the header format of a style that is not conventional, described
+	// by HeaderFormat, and its "subject" group is the description. Nil allows any header.
+	HeaderPattern *regexp.Regexp
+	HeaderFormat  string
+	// SubjectCase requires the description to start with a lowercase or uppercase letter,
+	// see [SubjectLowerCase] and [SubjectUpperCase]; empty allows either.
+	SubjectCase string
+}
+
+// DefaultLintRules returns the rules applied without configuration.
+func DefaultLintRules() LintRules {
+	return LintRules{
+		Conventional:    true,
+		MaxHeaderLength: maxSubjectLength,
+		Imperative:      true,
+		Types:           DefaultCommitTypes,
+	}
+}
+
+// LintRulesFromConfig reads lint-conventional, lint-max-header-length, lint-imperative,
+// lint-third-person, lint-types and lint-scopes on top of the rules of the configured style, see
+// [GitRepo.StyleFromConfig]. Without lint-scopes, the scopes of the scopes config rules are
+// allowed, if any.
+func (g *GitRepo) LintRulesFromConfig() (LintRules, error) {
+	style, err := g.StyleFromConfig()
+	if err != nil {
+		return LintRules{}, err
+	}
+
+	rules := style.LintRules()
+	if viper.IsSet("lint-conventional") {
+		rules.Conventional = viper.GetBool("lint-conventional")
+	}
+	if viper.IsSet("lint-max-header-length") {
+		rules.MaxHeaderLength = viper.GetInt("lint-max-header-length")
+	}
+	if viper.IsSet("lint-imperative") {
+		rules.Imperative = viper.GetBool("lint-imperative")
+	}
+	if viper.IsSet("lint-third-person") {
+		rules.ThirdPerson = viper.GetBool("lint-third-person")
+	}
+	if types := viper.GetStringSlice("lint-types"); len(types) > 0 {
+		rules.Types = types
+	}
+
+	rules.Scopes = viper.GetStringSlice("lint-scopes")
+	if len(rules.Scopes) == 0 {
+		scopeRules, err := ScopeRulesFromConfig()
+		if err != nil {
+			return LintRules{}, err
+		}
+		for _, rule := range scopeRules {
+			if !slices.Contains(rules.Scopes, rule.Scope) {
+				rules.Scopes = append(rules.Scopes, rule.Scope)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+// LintRepairAttempts returns how often a commit message breaking the lint rules is sent
+// back to the model, from lint-repair-attempts. Zero disables repairs.
+func LintRepairAttempts() int {
+	if viper.IsSet("lint-repair-attempts") {
+		return max(viper.GetInt("lint-repair-attempts"), 0)
+	}
+	return defaultLintRepairAttempts
+}
+
+// Lint checks a commit message against the rules and returns every violation.
+func (r LintRules) Lint(message string) []LintViolation {
+	var violations []LintViolation
+	report := func(rule, format string, args ...any) {
+		violations = append(violations, LintViolation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	header, body, hasBody := strings.Cut(strings.TrimRight(message, "\n"), "\n")
+	if strings.TrimSpace(header) == "" {
+		report("header-empty", "the commit message is empty")
+		return violations
+	}
+
+	if r.MaxHeaderLength > 0 && len([]rune(header)) > r.MaxHeaderLength {
+		report("header-max-length", "the header is %d characters long, at most %d are allowed", len([]rune(header)), r.MaxHeaderLength)
+	}
+	if hasBody && strings.TrimSpace(strings.SplitN(body, "\n", 2)[0]) != "" {
+		report("body-leading-blank", "the body must be separated from the header by a blank line")
+	}
+
+	description := header
+	switch {
+	case r.Conventional:
+		match := conventionalHeaderPattern.FindStringSubmatch(header)
+		if match == nil {
+			report("header-format", "the header must look like \"type(scope): description\"")
+			return violations
+		}
+
+		commitType, scope := match[1], match[2]
+		description = match[4]
+		if len(r.Types) > 0 && !slices.Contains(r.Types, commitType) {
+			report("type-enum", "type %q is not one of %s", commitType, strings.Join(r.Types, ", "))
+		}
+		if scope != "" && len(r.Scopes) > 0 {
+			for _, part := range strings.Split(scope, ",") {
+				if part = strings.TrimSpace(part); !slices.Contains(r.Scopes, part) {
+					report("scope-enum", "scope %q is not one of %s", part, strings.Join(r.Scopes, ", "))
+				}
+			}
+		}
+
+	case r.HeaderPattern != nil:
+		match := r.HeaderPattern.FindStringSubmatch(header)
+		if match == nil {
+			report("header-format", "the header must look like %s", r.HeaderFormat)
+			return violations
+		}
+		if i := r.HeaderPattern.SubexpIndex("subject"); i > 0 {
+			description = match[i]
+		}
+	}
+
+	if first, _ := utf8.DecodeRuneInString(description); unicode.IsLetter(first) {
+		switch {
+		case r.SubjectCase == SubjectLowerCase && !unicode.IsLower(first):
+			report("subject-case", "the subject must start with a lowercase letter")
+		case r.SubjectCase == SubjectUpperCase && !unicode.IsUpper(first):
+			report("subject-case", "the subject must start with an uppercase letter")
+		}
+	}
+
+	if strings.HasSuffix(description, ".") {
+		report("subject-full-stop", "the subject must not end with a period")
+	}
+	if r.Imperative {
+		if word, ok := nonImperativeVerb(description, r.ThirdPerson); ok {
+			report("subject-mood", "the subject must use the imperative mood, %q is not", word)
+		}
+	}
+
+	return violations
+}
+
+// nonImperativeVerb reports the first word of a subject if it looks like a past tense
+// ("added") or gerund ("adding") form rather than an imperative, and with thirdPerson
+// also a third person ("adds") form.
+func nonImperativeVerb(subject string, thirdPerson bool) (string, bool) {
+	fields := strings.Fields(subject)
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	word := fields[0]
+	lower := strings.ToLower(word)
+	if notImperative[lower] || len(lower) < 4 {
+		return "", false
+	}
+
+	switch {
+	case strings.HasSuffix(lower, "ed"), strings.HasSuffix(lower, "ing"):
+		return word, true
+	case thirdPerson && strings.HasSuffix(lower, "s") && !strings.HasSuffix(lower, "ss") &&
+		!strings.HasSuffix(lower, "us") && !strings.HasSuffix(lower, "is"):
+		return word, true
+	}
+	return "", false
+}
+
+// FormatViolations renders violations as a markdown list.
+func FormatViolations(violations []LintViolation) string {
+	var b strings.Builder
+	for _, violation := range violations {
+		fmt.Fprintf(&b, "- %s\n", violation)
+	}
+	return b.String()
+}
@@ -0,0 +1,224 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Output formats of [FormatLintReport].
+const (
+	LintFormatText   = "text"
+	LintFormatJSON   = "json"
+	LintFormatGitHub = "github"
+)
+
+// ignoredCommitPattern matches messages written by git or hosting tools rather than
+// developers, which commitlint skips too: merges, reverts and fixups.
+var ignoredCommitPattern = regexp.MustCompile(`^(Merge (pull request|branch|remote-tracking branch|tag) |Merge .+ into |Revert "|Automatic merge|Auto-merged |(fixup|squash|amend)! )`)
+
+// CommitLint is the result of linting the message of one commit.
+type CommitLint struct {
+	Commit     CommitInfo
+	Violations []LintViolation
+	// Rewrite is a suggested message following the rules, if one was requested.
+	Rewrite string
+}
+
+// LintReport holds the results of linting a range of commits.
+type LintReport struct {
+	// Checked counts the linted commits, excluding ignored ones.
+	Checked int
+	// Failed lists the commits breaking a rule, newest first.
+	Failed []CommitLint
+}
+
+// DefaultBranch returns the branch changes are usually merged into: the branch the
+// remote HEAD of origin points to, or else main or master.
+func (g *GitRepo) DefaultBranch() (string, error) {
+	if ref, err := g.repo.Reference(plumbing.NewRemoteHEADReferenceName("origin"), false); err == nil && ref.Type() == plumbing.SymbolicReference {
+		return ref.Target().Short(), nil
+	}
+
+	for _, name := range []string{"main", "master", "origin/main", "origin/master"} {
+		if _, err := g.resolveHash(name); err == nil {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("failed to find the default branch, pass a range such as main..HEAD")
+}
+
+// ParseCommitRange splits a "base..head" range. A single revision is the base of a
+// range ending at HEAD, and an empty range starts at the [GitRepo.DefaultBranch].
+func (g *GitRepo) ParseCommitRange(rangeSpec string) (string, string, error) {
+	base, head, found := strings.Cut(rangeSpec, "..")
+	if found {
+		head = strings.TrimPrefix(head, ".")
+	}
+	if head == "" {
+		head = "HEAD"
+	}
+
+	if base == "" {
+		defaultBranch, err := g.DefaultBranch()
+		if err != nil {
+			return "", "", err
+		}
+		base = defaultBranch
+	}
+	return base, head, nil
+}
+
+// IgnoredCommit reports whether a commit message was generated by git, such as a merge
+// or revert, and is not linted.
+func IgnoredCommit(message string) bool {
+	return ignoredCommitPattern.MatchString(message)
+}
+
+// LintRange checks the message of every commit reachable from head but not from the
+// merge-base of base and head.
+func (g *GitRepo) LintRange(base, head string, rules LintRules) (*LintReport, error) {
+	commits, err := g.GetCommitRange(base, head, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LintReport{}
+	for _, commit := range commits {
+		if IgnoredCommit(commit.Message) {
+			continue
+		}
+		report.Checked++
+		if violations := rules.Lint(commit.Message); len(violations) > 0 {
+			report.Failed = append(report.Failed, CommitLint{Commit: commit, Violations: violations})
+		}
+	}
+	return report, nil
+}
+
+// SuggestRewrites asks the model for a message following the rules for each failed
+// commit, from the diff to its first parent. Root commits get no suggestion.
+func (g *GitRepo) SuggestRewrites(report *LintReport, opts DiffOptions) error {
+	for i, failed := range report.Failed {
+		parent := failed.Commit.Hash + "^"
+		if _, err := g.resolveHash(parent); err != nil {
+			continue
+		}
+
+		fileDiffs, err := g.GetFileDiffs(parent, failed.Commit.Hash, opts)
+		if err != nil {
+			return fmt.Errorf("failed to diff %s: %w", failed.Commit.ShortHash(), err)
+		}
+
+		prompt, err := g.PreparePrompt(fileDiffs, nil, "", parent)
+		if err != nil {
+			return err
+		}
+		prompt.Context.Rejected = prompt.rejected(&LLMResult{CommitMessage: failed.Commit.Message, Violations: failed.Violations})
+
+		result, err := prompt.Generate()
+		if err != nil {
+			return fmt.Errorf("failed to rewrite %s: %w", failed.Commit.ShortHash(), err)
+		}
+		report.Failed[i].Rewrite = result.FullCommitMessage()
+	}
+	return nil
+}
+
+// FormatLintReport renders a report as text for people, as JSON, or as GitHub Actions
+// workflow commands that annotate the run.
+func FormatLintReport(report *LintReport, format string) (string, error) {
+	switch format {
+	case LintFormatText, "":
+		return formatLintText(report), nil
+	case LintFormatJSON:
+		return formatLintJSON(report)
+	case LintFormatGitHub:
+		return formatLintGitHub(report), nil
+	default:
+		return "", fmt.Errorf("unknown lint format %q, expected text, json or github", format)
+	}
+}
+
+func formatLintText(report *LintReport) string {
+	var b strings.Builder
+	for _, failed := range report.Failed {
+		fmt.Fprintf(&b, "%s %s\n", failed.Commit.ShortHash(), failed.Commit.Subject)
+		for _, violation := range failed.Violations {
+			fmt.Fprintf(&b, "  %s\n", violation)
+		}
+		if failed.Rewrite != "" {
+			fmt.Fprintf(&b, "  suggested: %s\n", strings.ReplaceAll(failed.Rewrite, "\n", "\n             "))
+		}
+	}
+
+	if len(report.Failed) == 0 {
+		fmt.Fprintf(&b, "All %d commits follow the commit rules\n", report.Checked)
+	} else {
+		fmt.Fprintf(&b, "%d of %d commits break the commit rules\n", len(report.Failed), report.Checked)
+	}
+	return b.String()
+}
+
+// lintJSON is the JSON form of a [LintReport].
+type lintJSON struct {
+	Checked int              `json:"checked"`
+	Failed  []commitLintJSON `json:"failed"`
+}
+
+type commitLintJSON struct {
+	Hash       string          `json:"hash"`
+	Subject    string          `json:"subject"`
+	Author     string          `json:"author"`
+	Violations []LintViolation `json:"violations"`
+	Rewrite    string          `json:"rewrite,omitempty"`
+}
+
+func formatLintJSON(report *LintReport) (string, error) {
+	out := lintJSON{Checked: report.Checked, Failed: []commitLintJSON{}}
+	for _, failed := range report.Failed {
+		out.Failed = append(out.Failed, commitLintJSON{
+			Hash:       failed.Commit.Hash,
+			Subject:    failed.Commit.Subject,
+			Author:     failed.Commit.Author,
+			Violations: failed.Violations,
+			Rewrite:    failed.Rewrite,
+		})
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal lint report: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
+func formatLintGitHub(report *LintReport) string {
+	var b strings.Builder
+	for _, failed := range report.Failed {
+		for _, violation := range failed.Violations {
+			fmt.Fprintf(&b, "::error title=%s::%s\n",
+				escapeGitHubProperty("Commit "+failed.Commit.ShortHash()+" breaks "+violation.Rule),
+				escapeGitHubData(failed.Commit.Subject+": "+violation.Message))
+		}
+		if failed.Rewrite != "" {
+			fmt.Fprintf(&b, "::notice title=%s::%s\n",
+				escapeGitHubProperty("Suggested message for "+failed.Commit.ShortHash()),
+				escapeGitHubData(failed.Rewrite))
+		}
+	}
+	return b.String()
+}
+
+// escapeGitHubData escapes the message of a GitHub Actions workflow command.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes a property value of a GitHub Actions workflow command.
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLintRange(t *testing.T) {
+	tr := newTestRepo(t)
+	base := tr.commitFile("main.go", "package main\n", "chore: initial commit", "Alice")
+	tr.branch("feature", base)
+	tr.commitFile("api.go", "package main\n\nfunc api() {}\n", "feat(api): add endpoint", "Alice")
+	tr.commitFile("api.go", "package main\n\nfunc api() { return }\n", "Fixed the endpoint.", "Bob")
+	tr.commitFile("api.go", "package main\n", "Merge branch 'main' into feature", "Bob")
+	tr.commitFile("api.go", "package main\n\n", "fix: added guard\nwithout blank line", "Bob")
+
+	repo := tr.gitRepo()
+	from, head, err := repo.ParseCommitRange("")
+	if err != nil {
+		t.Fatalf("ParseCommitRange() error: %v", err)
+	}
+	if from != "master" || head != "HEAD" {
+		t.Errorf("ParseCommitRange(\"\") = %s..%s, expected master..HEAD", from, head)
+	}
+
+	report, err := repo.LintRange(from, head, DefaultLintRules())
+	if err != nil {
+		t.Fatalf("LintRange() error: %v", err)
+	}
+
+	if report.Checked != 3 {
+		t.Errorf("Checked = %d, expected 3 commits with the merge ignored", report.Checked)
+	}
+	if len(report.Failed) != 2 {
+		t.Fatalf("Failed = %+v, expected 2 commits", report.Failed)
+	}
+
+	var rules []string
+	for _, failed := range report.Failed {
+		for _, violation := range failed.Violations {
+			rules = append(rules, violation.Rule)
+		}
+	}
+	if got := strings.Join(rules, ","); got != "body-leading-blank,subject-mood,header-format" {
+		t.Errorf("violated rules = %s", got)
+	}
+}
+
+func TestParseCommitRange(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.commitFile("main.go", "package main\n", "chore: initial commit", "Alice")
+	repo := tr.gitRepo()
+
+	tests := map[string][2]string{
+		"main..feature":  {"main", "feature"},
+		"main...feature": {"main", "feature"},
+		"v1.0..":         {"v1.0", "HEAD"},
+		"HEAD~3":         {"HEAD~3", "HEAD"},
+	}
+	for spec, expected := range tests {
+		base, head, err := repo.ParseCommitRange(spec)
+		if err != nil || base != expected[0] || head != expected[1] {
+			t.Errorf("ParseCommitRange(%q) = %s, %s, %v, expected %s..%s", spec, base, head, err, expected[0], expected[1])
+		}
+	}
+}
+
+func TestFormatLintReport(t *testing.T) {
+	report := &LintReport{
+		Checked: 2,
+		Failed: []CommitLint{{
+			Commit:     CommitInfo{Hash: "0123456789abcdef", Subject: "Fixed it, 100%.", Author: "Bob"},
+			Violations: []LintViolation{{Rule: "subject-full-stop", Message: "the subject must not end with a period"}},
+			Rewrite:    "fix: handle empty input",
+		}},
+	}
+
+	text, err := FormatLintReport(report, LintFormatText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "01234567 Fixed it, 100%.\n  subject-full-stop: the subject must not end with a period\n  suggested: fix: handle empty input\n1 of 2 commits break the commit rules\n"
+	if text != expected {
+		t.Errorf("text report = %q, expected %q", text, expected)
+	}
+
+	github, err := FormatLintReport(report, LintFormatGitHub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "::error title=Commit 01234567 breaks subject-full-stop::Fixed it, 100%25.: the subject must not end with a period\n" +
+		"::notice title=Suggested message for 01234567::fix: handle empty input\n"
+	if github != expected {
+		t.Errorf("github report = %q, expected %q", github, expected)
+	}
+
+	data, err := FormatLintReport(report, LintFormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded lintJSON
+	if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+		t.Fatalf("invalid JSON report: %v\n%s", err, data)
+	}
+	if decoded.Checked != 2 || len(decoded.Failed) != 1 || decoded.Failed[0].Violations[0].Rule != "subject-full-stop" {
+		t.Errorf("JSON report = %+v", decoded)
+	}
+
+	if _, err := FormatLintReport(report, "xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestSuggestRewrites(t *testing.T) {
+	defer viper.Reset()
+	prompts := chatServer(t, "COMMIT: fix(app): guard empty input\n\nPR:\n## What changed\n- guard")
+
+	tr := newTestRepo(t)
+	base := tr.commitFile("app/api.go", "package app\n", "chore: initial commit", "Alice")
+	tr.commitFile("app/api.go", "package app\n\nfunc guard() {}\n", "Fixed it.", "Bob")
+
+	repo := tr.gitRepo()
+	report, err := repo.LintRange(base.String(), "HEAD", DefaultLintRules())
+	if err != nil {
+		t.Fatalf("LintRange() error: %v", err)
+	}
+	if err := repo.SuggestRewrites(report, DefaultDiffOptions()); err != nil {
+		t.Fatalf("SuggestRewrites() error: %v", err)
+	}
+
+	if report.Failed[0].Rewrite != "fix(app): guard empty input" {
+		t.Errorf("Rewrite = %q", report.Failed[0].Rewrite)
+	}
+	if len(*prompts) != 1 || !strings.Contains((*prompts)[0], "Fixed it.") || !strings.Contains((*prompts)[0], "func guard") {
+		t.Errorf("Expected the prompt to contain the old message and the diff, got:\n%s", strings.Join(*prompts, "\n---\n"))
+	}
+}
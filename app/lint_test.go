@@ -0,0 +1,180 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLint(t *testing.T) {
+	rules := DefaultLintRules()
+	rules.Scopes = []string{"app", "llm"}
+
+	tests := []struct {
+		message  string
+		expected []string
+	}{
+		{message: "feat(app): add offline mode", expected: nil},
+		{message: "fix(app,llm)!: retry requests\n\nThe API drops requests under load.", expected: nil},
+		{message: "feat(ui): add offline mode", expected: []string{"scope-enum"}},
+		{message: "feature: add offline mode", expected: []string{"type-enum"}},
+		{message: "Add offline mode", expected: []string{"header-format"}},
+		{message: "feat: add offline mode.", expected: []string{"subject-full-stop"}},
+		{message: "feat: added offline mode", expected: []string{"subject-mood"}},
+		{message: "feat: adding offline mode", expected: []string{"subject-mood"}},
+		{message: "feat: adds offline mode", expected: nil},
+		{message: "chore: deps update", expected: nil},
+		{message: "fix: settings page crash", expected: nil},
+		{message: "refactor: types cleanup", expected: nil},
+		{message: "test: tests for the parser", expected: nil},
+		{message: "fix: windows path handling", expected: nil},
+		{message: "feat: canvas export", expected: nil},
+		{message: "fix: string escaping", expected: nil},
+		{message: "feat: bring back offline mode", expected: nil},
+		{message: "perf: process diffs lazily", expected: nil},
+		{message: "feat: add " + strings.Repeat("x", 70), expected: []string{"header-max-length"}},
+		{message: "feat: add offline mode\nwith a body", expected: []string{"body-leading-blank"}},
+		{message: "", expected: []string{"header-empty"}},
+	}
+
+	for _, test := range tests {
+		var violated []string
+		for _, violation := range rules.Lint(test.message) {
+			violated = append(violated, violation.Rule)
+		}
+		if !reflect.DeepEqual(violated, test.expected) {
+			t.Errorf("Lint(%q) = %v, expected %v", test.message, violated, test.expected)
+		}
+	}
+
+	rules.ThirdPerson = true
+	for message, expected := range map[string][]string{
+		"feat: adds offline mode": {"subject-mood"},
+		"chore: bump deps":        nil,
+		"fix: status bar padding": nil,
+		"fix: process diffs":      nil,
+	} {
+		var violated []string
+		for _, violation := range rules.Lint(message) {
+			violated = append(violated, violation.Rule)
+		}
+		if !reflect.DeepEqual(violated, expected) {
+			t.Errorf("Lint(%q) with third person = %v, expected %v", message, violated, expected)
+		}
+	}
+}
+
+func TestLintRulesFromConfig(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("lint-types", []string{"feat", "fix"})
+	viper.Set("lint-max-header-length", 50)
+	viper.Set("lint-imperative", false)
+	viper.Set("scopes", []map[string]any{{"scope": "viewer", "paths": []string{"app/diff_viewer.go"}}})
+
+	rules, err := newTestRepo(t).gitRepo().LintRulesFromConfig()
+	if err != nil {
+		t.Fatalf("LintRulesFromConfig() error: %v", err)
+	}
+
+	expected := LintRules{
+		Conventional:    true,
+		MaxHeaderLength: 50,
+		Types:           []string{"feat", "fix"},
+		Scopes:          []string{"viewer"},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("LintRulesFromConfig() = %+v, expected %+v", rules, expected)
+	}
+}
+
+// chatServer serves the given completions in order and records the user prompts it got.
+func chatServer(t *testing.T, completions ...string) *[]string {
+	t.Helper()
+
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req APIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		prompts = append(prompts, req.Messages[len(req.Messages)-1].Content)
+
+		content := completions[min(len(prompts), len(completions))-1]
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%q}}]}`, content)
+	}))
+	t.Cleanup(server.Close)
+
+	original := openRouterURL
+	openRouterURL = server.URL
+	t.Cleanup(func() { openRouterURL = original })
+
+	viper.Set("api-key", "test-key")
+	viper.Set("config-dir", t.TempDir())
+	return &prompts
+}
+
+func TestGenerateRepairsCommitMessage(t *testing.T) {
+	defer viper.Reset()
+	prompts := chatServer(t,
+		"COMMIT: feat(app): Added offline mode.\nBODY:\nThe API is unreachable on trains.\n\nPR:\n## What changed\n- offline",
+		"COMMIT: feat(app): add offline mode\n\nPR:\n## What changed\n- offline",
+	)
+
+	prompt := &PreparedPrompt{
+		Diff:      "+offline",
+		fileDiffs: []FileDiff{offlineFileDiff(ChangeModify, "app/api.go", "app/api.go", "+offline\n")},
+		scopes:    []string{"app"},
+		lint:      DefaultLintRules(),
+	}
+
+	result, err := prompt.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if result.CommitMessage != "feat(app): add offline mode" || result.RepairAttempts != 1 || len(result.Violations) != 0 {
+		t.Errorf("Generate() = %q after %d repairs with %v", result.CommitMessage, result.RepairAttempts, result.Violations)
+	}
+
+	if len(*prompts) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(*prompts))
+	}
+	repair := (*prompts)[1]
+	for _, expected := range []string{"feat(app): Added offline mode.\n\nThe API is unreachable on trains.", "subject-full-stop", "subject-mood"} {
+		if !strings.Contains(repair, expected) {
+			t.Errorf("Expected the repair prompt to contain %q, got:\n%s", expected, repair)
+		}
+	}
+}
+
+func TestGenerateFallsBackToOfflineSubject(t *testing.T) {
+	defer viper.Reset()
+	prompts := chatServer(t, "COMMIT: Updated things.\n\nPR:\n## What changed\n- things")
+	viper.Set("lint-repair-attempts", 1)
+
+	prompt := &PreparedPrompt{
+		fileDiffs: []FileDiff{offlineFileDiff(ChangeModify, "app/api.go", "app/api.go", "+offline\n")},
+		scopes:    []string{"app"},
+		lint:      DefaultLintRules(),
+	}
+
+	result, err := prompt.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if len(*prompts) != 2 {
+		t.Errorf("Expected 2 requests, got %d", len(*prompts))
+	}
+	if result.CommitMessage != "chore(app): update api.go" || len(result.Violations) != 0 {
+		t.Errorf("Generate() = %q with %v, expected the offline subject", result.CommitMessage, result.Violations)
+	}
+	if result.PRDescription != "## What changed\n- things" {
+		t.Errorf("Expected the model's PR description to be kept, got %q", result.PRDescription)
+	}
+}
@@ -0,0 +1,279 @@
+package app
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxSubjectLength is the longest commit subject generated, the common git convention.
+const maxSubjectLength = 72
+
+// fileKind classifies a changed file by the role it plays in a repository.
+type fileKind int
+
+const (
+	codeFile fileKind = iota
+	testFile
+	docsFile
+	ciFile
+	buildFile
+)
+
+// commitTypes maps file kinds to the conventional commit type of a change touching
+// only files of that kind.
+var commitTypes = map[fileKind]string{
+	testFile:  "test",
+	docsFile:  "docs",
+	ciFile:    "ci",
+	buildFile: "build",
+}
+
+// buildFileNames are dependency manifests, lockfiles and build scripts.
+var buildFileNames = map[string]bool{
+	"go.mod": true, "go.sum": true, "go.work": true, "Makefile": true, "Dockerfile": true,
+	"docker-compose.yml": true, "docker-compose.yaml": true, "Taskfile.yml": true, "justfile": true,
+	".goreleaser.yml": true, ".goreleaser.yaml": true, "package.json": true, "package-lock.json": true,
+	"yarn.lock": true, "pnpm-lock.yaml": true, "Cargo.toml": true, "Cargo.lock": true,
+	"pyproject.toml": true, "requirements.txt": true, "Gemfile": true, "Gemfile.lock": true,
+	"build.gradle": true, "pom.xml": true,
+}
+
+// docsFileNames are documentation files without a documentation extension.
+var docsFileNames = map[string]bool{"LICENSE": true, "NOTICE": true, "AUTHORS": true, "CODEOWNERS": true}
+
+// conventionalHeaderPattern matches a conventional commit subject, capturing its type,
+// scope, breaking change marker and description.
+var conventionalHeaderPattern = regexp.MustCompile(`^([a-z]+)(?:\(([^()]*)\))?(!?): (.+)$`)
+
+// classifyFile returns the kind of a file from its path.
+func classifyFile(filePath string) fileKind {
+	dir, base := path.Split(filePath)
+	segments := strings.Split(strings.Trim(dir, "/"), "/")
+
+	switch {
+	case strings.HasPrefix(filePath, ".github/workflows/"), strings.HasPrefix(filePath, ".circleci/"),
+		strings.HasPrefix(filePath, ".buildkite/"), base == ".gitlab-ci.yml", base == ".travis.yml",
+		base == "Jenkinsfile", base == "azure-pipelines.yml":
+		return ciFile
+	case buildFileNames[base]:
+		return buildFile
+	case strings.Contains(base, "_test."), strings.Contains(base, ".test."), strings.Contains(base, ".spec."),
+		strings.HasPrefix(base, "test_") && strings.HasSuffix(base, ".py"):
+		return testFile
+	}
+
+	for _, segment := range segments {
+		switch segment {
+		case "test", "tests", "testdata", "__tests__", "spec":
+			return testFile
+		case "docs", "doc":
+			return docsFile
+		}
+	}
+
+	switch strings.ToLower(path.Ext(base)) {
+	case ".md", ".markdown", ".rst", ".adoc", ".txt":
+		return docsFile
+	}
+	if docsFileNames[base] {
+		return docsFile
+	}
+	return codeFile
+}
+
+// GenerateOffline derives a commit message and PR description from the changed files
+// alone, for when no model is available. The type comes from the kind of files changed,
+// e.g. "test" when only tests changed, or from the conventional subjects of the commits
+// in the range. An empty scope leaves it out.
+func GenerateOffline(fileDiffs []FileDiff, commits []CommitInfo, scope, reason string) *LLMResult {
+	commitType := offlineType(fileDiffs, commits)
+
+	header := commitType
+	if scope != "" {
+		header += "(" + scope + ")"
+	}
+	header += ": "
+
+	return &LLMResult{
+		CommitMessage: header + offlineDescription(fileDiffs, maxSubjectLength-len(header)),
+		PRDescription: offlinePRDescription(fileDiffs, commits),
+		OfflineReason: reason,
+	}
+}
+
+// offlineType picks the conventional type of a change. When most commits in the range
+// already follow Conventional Commits, their type wins.
+func offlineType(fileDiffs []FileDiff, commits []CommitInfo) string {
+	types := make(map[string]int)
+	for _, commit := range commits {
+		if match := conventionalHeaderPattern.FindStringSubmatch(commit.Subject); match != nil {
+			types[match[1]]++
+		}
+	}
+	best, bestCount := "", 0
+	for commitType, count := range types {
+		if count > bestCount || (count == bestCount && commitType < best) {
+			best, bestCount = commitType, count
+		}
+	}
+	if bestCount*2 > len(commits) {
+		return best
+	}
+
+	kinds := make(map[fileKind]bool)
+	added, deleted, newCode, movedCode := 0, 0, false, true
+	for _, fileDiff := range fileDiffs {
+		kind := classifyFile(fileDiff.Change.Path())
+		kinds[kind] = true
+		if kind != codeFile {
+			continue
+		}
+
+		a, d := fileDiff.LineStats()
+		added += a
+		deleted += d
+		newCode = newCode || fileDiff.Change.Kind == ChangeAdd
+		movedCode = movedCode && (fileDiff.Change.Kind == ChangeRename || fileDiff.Change.Kind == ChangeDelete)
+	}
+
+	if len(kinds) == 1 {
+		for kind := range kinds {
+			if commitType, ok := commitTypes[kind]; ok {
+				return commitType
+			}
+		}
+	}
+
+	switch {
+	case movedCode && kinds[codeFile], deleted > added:
+		return "refactor"
+	case newCode:
+		return "feat"
+	default:
+		return "chore"
+	}
+}
+
+// offlineDescription describes the change in at most limit characters, naming as many
+// of the changed files as fit, e.g. "update api.go, ui.go and 3 more files".
+func offlineDescription(fileDiffs []FileDiff, limit int) string {
+	if len(fileDiffs) == 1 && fileDiffs[0].Change.Kind == ChangeRename {
+		change := fileDiffs[0].Change
+		description := fmt.Sprintf("rename %s to %s", path.Base(change.OldPath), path.Base(change.NewPath))
+		if len(description) <= limit {
+			return description
+		}
+	}
+
+	verb := "update"
+	kinds := make(map[ChangeKind]bool)
+	for _, fileDiff := range fileDiffs {
+		kinds[fileDiff.Change.Kind] = true
+	}
+	if len(kinds) == 1 {
+		switch fileDiffs[0].Change.Kind {
+		case ChangeAdd, ChangeCopy:
+			verb = "add"
+		case ChangeDelete:
+			verb = "remove"
+		case ChangeRename:
+			verb = "move"
+		}
+	}
+
+	names := make([]string, len(fileDiffs))
+	for i, fileDiff := range fileDiffs {
+		names[i] = path.Base(fileDiff.Change.Path())
+	}
+
+	for shown := len(names); shown > 0; shown-- {
+		description := verb + " " + listNames(names[:shown], len(names)-shown)
+		if len(description) <= limit {
+			return description
+		}
+	}
+	return fmt.Sprintf("%s %d files", verb, len(names))
+}
+
+// listNames joins names into an English list, mentioning how many more were left out.
+func listNames(names []string, more int) string {
+	switch {
+	case more == 1:
+		names = append(names[:len(names):len(names)], "1 more file")
+	case more > 1:
+		names = append(names[:len(names):len(names)], fmt.Sprintf("%d more files", more))
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// offlinePRDescription lists the changed files with their stats, in the sections the
+// model would write.
+func offlinePRDescription(fileDiffs []FileDiff, commits []CommitInfo) string {
+	var b strings.Builder
+	b.WriteString("## What changed\n\n")
+
+	var tests []string
+	for _, fileDiff := range fileDiffs {
+		change := fileDiff.Change
+		if classifyFile(change.Path()) == testFile {
+			tests = append(tests, change.Path())
+		}
+		fmt.Fprintf(&b, "- %s\n", describeFileChange(fileDiff))
+	}
+
+	b.WriteString("\n## Why\n\n")
+	if len(commits) > 0 {
+		b.WriteString("The commits in this range:\n\n")
+		for i := len(commits) - 1; i >= 0; i-- {
+			fmt.Fprintf(&b, "- %s\n", commits[i].Subject)
+		}
+	} else {
+		b.WriteString("_Describe the motivation for this change._\n")
+	}
+
+	b.WriteString("\n## Testing\n\n")
+	if len(tests) > 0 {
+		sort.Strings(tests)
+		b.WriteString("Tests changed in:\n\n")
+		for _, test := range tests {
+			fmt.Fprintf(&b, "- `%s`\n", test)
+		}
+	} else {
+		b.WriteString("_Describe how this change was tested._\n")
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// describeFileChange describes one file of the change, e.g. "Modified `app/api.go` (+10 -2)".
+func describeFileChange(fileDiff FileDiff) string {
+	change := fileDiff.Change
+
+	var description string
+	switch change.Kind {
+	case ChangeAdd:
+		description = fmt.Sprintf("Added `%s`", change.NewPath)
+	case ChangeDelete:
+		description = fmt.Sprintf("Deleted `%s`", change.OldPath)
+	case ChangeRename:
+		description = fmt.Sprintf("Renamed `%s` to `%s`", change.OldPath, change.NewPath)
+	case ChangeCopy:
+		description = fmt.Sprintf("Copied `%s` to `%s`", change.OldPath, change.NewPath)
+	default:
+		description = fmt.Sprintf("Modified `%s`", change.NewPath)
+	}
+
+	if change.Binary {
+		return description + " (" + change.BinarySummary() + ")"
+	}
+	if added, deleted := fileDiff.LineStats(); added > 0 || deleted > 0 {
+		return description + fmt.Sprintf(" (+%d -%d)", added, deleted)
+	}
+	return description
+}
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func offlineFileDiff(kind ChangeKind, oldPath, newPath, content string) FileDiff {
+	change := FileChange{Kind: kind, OldPath: oldPath, NewPath: newPath}
+	return FileDiff{Filename: change.Path(), Content: content, Change: change}
+}
+
+func TestClassifyFile(t *testing.T) {
+	tests := map[string]fileKind{
+		"app/api.go":                    codeFile,
+		"app/api_test.go":               testFile,
+		"web/src/button.spec.ts":        testFile,
+		"tests/test_parser.py":          testFile,
+		"app/testdata/fixture.json":     testFile,
+		"README.md":                     docsFile,
+		"docs/architecture.svg":         docsFile,
+		"LICENSE":                       docsFile,
+		".github/workflows/ci.yml":      ciFile,
+		".gitlab-ci.yml":                ciFile,
+		"go.mod":                        buildFile,
+		"Makefile":                      buildFile,
+		".github/pull_request_template": codeFile,
+	}
+
+	for filePath, expected := range tests {
+		if kind := classifyFile(filePath); kind != expected {
+			t.Errorf("classifyFile(%q) = %v, expected %v", filePath, kind, expected)
+		}
+	}
+}
+
+func TestGenerateOffline(t *testing.T) {
+	modify := "--- a/x\n+++ b/x\n@@ -1 +1,2 @@\n-old\n+new\n+more\n"
+
+	tests := []struct {
+		name      string
+		fileDiffs []FileDiff
+		commits   []CommitInfo
+		expected  string
+	}{
+		{
+			name: "tests only",
+			fileDiffs: []FileDiff{
+				offlineFileDiff(ChangeModify, "app/api_test.go", "app/api_test.go", modify),
+				offlineFileDiff(ChangeAdd, "", "app/offline_test.go", modify),
+			},
+			expected: "test(app): update api_test.go and offline_test.go",
+		},
+		{
+			name: "docs only",
+			fileDiffs: []FileDiff{
+				offlineFileDiff(ChangeModify, "README.md", "README.md", modify),
+				offlineFileDiff(ChangeModify, "docs/usage.md", "docs/usage.md", modify),
+			},
+			expected: "docs: update README.md and usage.md",
+		},
+		{
+			name: "new code",
+			fileDiffs: []FileDiff{
+				offlineFileDiff(ChangeAdd, "", "app/offline.go", modify),
+				offlineFileDiff(ChangeModify, "app/api.go", "app/api.go", modify),
+				offlineFileDiff(ChangeModify, "README.md", "README.md", modify),
+			},
+			expected: "feat(app): update offline.go, api.go and README.md",
+		},
+		{
+			name:      "rename",
+			fileDiffs: []FileDiff{offlineFileDiff(ChangeRename, "app/git.go", "app/repo.go", "")},
+			expected:  "refactor(app): rename git.go to repo.go",
+		},
+		{
+			name:      "shrinking code",
+			fileDiffs: []FileDiff{offlineFileDiff(ChangeModify, "internal/billing/tax.go", "internal/billing/tax.go", "@@ -1,3 +1 @@\n-a\n-b\n-c\n+d\n")},
+			expected:  "refactor(billing): update tax.go",
+		},
+		{
+			name:      "conventional commits decide the type",
+			fileDiffs: []FileDiff{offlineFileDiff(ChangeModify, "app/api.go", "app/api.go", modify)},
+			commits:   []CommitInfo{{Subject: "fix(api): retry on 429"}, {Subject: "fix: handle empty choices"}, {Subject: "wip"}},
+			expected:  "fix(app): update api.go",
+		},
+		{
+			name: "long subjects are shortened",
+			fileDiffs: []FileDiff{
+				offlineFileDiff(ChangeModify, "app/diff_viewer.go", "app/diff_viewer.go", modify),
+				offlineFileDiff(ChangeModify, "app/git_working.go", "app/git_working.go", modify),
+				offlineFileDiff(ChangeModify, "app/revision_parser.go", "app/revision_parser.go", modify),
+				offlineFileDiff(ChangeModify, "app/history_iterator.go", "app/history_iterator.go", modify),
+			},
+			expected: "chore(app): update diff_viewer.go, git_working.go and 2 more files",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var scope string
+			if scopes := InferScopes(test.fileDiffs, nil); len(scopes) > 0 {
+				scope = scopes[0]
+			}
+
+			result := GenerateOffline(test.fileDiffs, test.commits, scope, "offline mode")
+			if result.CommitMessage != test.expected {
+				t.Errorf("CommitMessage = %q, expected %q", result.CommitMessage, test.expected)
+			}
+			if len(result.CommitMessage) > maxSubjectLength {
+				t.Errorf("CommitMessage is %d characters long", len(result.CommitMessage))
+			}
+			if result.OfflineReason != "offline mode" {
+				t.Errorf("OfflineReason = %q", result.OfflineReason)
+			}
+		})
+	}
+}
+
+func TestOfflinePRDescription(t *testing.T) {
+	result := GenerateOffline([]FileDiff{
+		offlineFileDiff(ChangeModify, "app/api.go", "app/api.go", "@@ -1 +1,2 @@\n-a\n+b\n+c\n"),
+		offlineFileDiff(ChangeAdd, "", "app/api_test.go", "@@ -0,0 +1 @@\n+d\n"),
+		offlineFileDiff(ChangeRename, "app/old.go", "app/new.go", ""),
+	}, []CommitInfo{{Subject: "feat: second"}, {Subject: "feat: first"}}, "app", "no API key")
+
+	for _, expected := range []string{
+		"- Modified `app/api.go` (+2 -1)\n",
+		"- Added `app/api_test.go` (+1 -0)\n",
+		"- Renamed `app/old.go` to `app/new.go`\n",
+		"- feat: first\n- feat: second\n",
+		"Tests changed in:\n\n- `app/api_test.go`",
+	} {
+		if !strings.Contains(result.PRDescription, expected) {
+			t.Errorf("Expected PR description to contain %q, got:\n%s", expected, result.PRDescription)
+		}
+	}
+}
+
+func TestGenerateFallsBackOffline(t *testing.T) {
+	defer viper.Reset()
+	t.Setenv("OPENROUTER_API_KEY", "")
+	viper.Set("api-key", "")
+
+	prompt := &PreparedPrompt{
+		fileDiffs: []FileDiff{offlineFileDiff(ChangeModify, "README.md", "README.md", "@@ -1 +1 @@\n-a\n+b\n")},
+	}
+
+	result, err := prompt.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if result.CommitMessage != "docs: update README.md" {
+		t.Errorf("CommitMessage = %q", result.CommitMessage)
+	}
+	if result.OfflineReason != ErrNoAPIKey.Error() {
+		t.Errorf("OfflineReason = %q, expected the missing API key", result.OfflineReason)
+	}
+}
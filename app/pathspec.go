@@ -0,0 +1,189 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pathspec limits diffs to matching paths, like the pathspec arguments of git diff.
+// Paths are matched relative to the repository root. The zero value matches everything.
+type Pathspec struct {
+	include []pathPattern
+	exclude []pathPattern
+}
+
+// pathPattern is a single parsed pathspec element.
+type pathPattern struct {
+	prefix string
+	glob   *regexp.Regexp
+}
+
+// ParsePathspec parses pathspec arguments. Plain paths match themselves and everything
+// below them, wildcards match across directories as in git, and patterns prefixed with
+// ":!", ":^" or ":(exclude)" remove matching paths. ":/" and ":(top)" are accepted for
+// compatibility but have no effect since paths are always relative to the root.
+func ParsePathspec(args []string) (Pathspec, error) {
+	var spec Pathspec
+	for _, arg := range args {
+		pattern, exclude, globMagic, err := parsePathspecMagic(arg)
+		if err != nil {
+			return Pathspec{}, err
+		}
+
+		compiled, err := compilePathPattern(pattern, globMagic)
+		if err != nil {
+			return Pathspec{}, fmt.Errorf("invalid pathspec %q: %w", arg, err)
+		}
+
+		if exclude {
+			spec.exclude = append(spec.exclude, compiled)
+		} else {
+			spec.include = append(spec.include, compiled)
+		}
+	}
+
+	return spec, nil
+}
+
+// parsePathspecMagic strips the short (":!") or long (":(exclude)") magic from a pathspec.
+func parsePathspecMagic(arg string) (pattern string, exclude, glob bool, err error) {
+	if !strings.HasPrefix(arg, ":") {
+		return arg, false, false, nil
+	}
+
+	rest := arg[1:]
+	if strings.HasPrefix(rest, "(") {
+		end := strings.Index(rest, ")")
+		if end < 0 {
+			return "", false, false, fmt.Errorf("invalid pathspec %q: missing ')'", arg)
+		}
+		for _, magic := range strings.Split(rest[1:end], ",") {
+			switch strings.TrimSpace(magic) {
+			case "exclude":
+				exclude = true
+			case "glob":
+				glob = true
+			case "top", "":
+			default:
+				return "", false, false, fmt.Errorf("unsupported pathspec magic %q in %q", magic, arg)
+			}
+		}
+		return rest[end+1:], exclude, glob, nil
+	}
+
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '!', '^':
+			exclude = true
+		case '/':
+		case ':':
+			return rest[1:], exclude, glob, nil
+		default:
+			return rest, exclude, glob, nil
+		}
+		rest = rest[1:]
+	}
+
+	return rest, exclude, glob, nil
+}
+
+// compilePathPattern compiles a pathspec pattern. Without glob magic a "*" also matches
+// "/", as in git; with it only "**" crosses directories.
+func compilePathPattern(pattern string, globMagic bool) (pathPattern, error) {
+	pattern = strings.TrimPrefix(pattern, "./")
+	if pattern == "." {
+		pattern = ""
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		return pathPattern{prefix: strings.TrimSuffix(pattern, "/")}, nil
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			switch {
+			case !globMagic:
+				expr.WriteString(".*")
+			case strings.HasPrefix(pattern[i:], "**/"):
+				expr.WriteString("(?:.*/)?")
+				i += 2
+			case strings.HasPrefix(pattern[i:], "**"):
+				expr.WriteString(".*")
+				i++
+			default:
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			if globMagic {
+				expr.WriteString("[^/]")
+			} else {
+				expr.WriteString(".")
+			}
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return pathPattern{}, fmt.Errorf("missing ']'")
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	// Like a plain path, a pattern matching a directory matches everything below it
+	expr.WriteString("(?:/.*)?$")
+
+	glob, err := regexp.Compile(expr.String())
+	if err != nil {
+		return pathPattern{}, err
+	}
+	return pathPattern{glob: glob}, nil
+}
+
+func (p pathPattern) match(path string) bool {
+	if p.glob != nil {
+		return p.glob.MatchString(path)
+	}
+	return p.prefix == "" || path == p.prefix || strings.HasPrefix(path, p.prefix+"/")
+}
+
+// IsEmpty reports whether the pathspec places no restrictions on paths.
+func (p Pathspec) IsEmpty() bool {
+	return len(p.include) == 0 && len(p.exclude) == 0
+}
+
+// Match reports whether a path is selected by the pathspec.
+func (p Pathspec) Match(path string) bool {
+	for _, pattern := range p.exclude {
+		if pattern.match(path) {
+			return false
+		}
+	}
+
+	if len(p.include) == 0 {
+		return true
+	}
+	for _, pattern := range p.include {
+		if pattern.match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchChange reports whether a change is selected, by either its old or its new path.
+func (p Pathspec) MatchChange(change FileChange) bool {
+	for _, path := range []string{change.NewPath, change.OldPath} {
+		if path != "" && p.Match(path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"testing"
+)
+
+func TestPathspecMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		path     string
+		expected bool
+	}{
+		{"empty matches everything", nil, "a/b.go", true},
+		{"dot matches everything", []string{"."}, "a/b.go", true},
+		{"directory prefix", []string{"src/"}, "src/app/main.go", true},
+		{"directory without slash", []string{"src"}, "src/main.go", true},
+		{"prefix is not a partial name", []string{"src"}, "srcs/main.go", false},
+		{"exact file", []string{"README.md"}, "README.md", true},
+		{"star crosses directories", []string{"*.go"}, "app/git.go", true},
+		{"star does not match other extensions", []string{"*.go"}, "app/git.md", false},
+		{"exclude short magic", []string{":!*.lock"}, "yarn.lock", false},
+		{"exclude keeps other files", []string{":!*.lock"}, "main.go", true},
+		{"caret exclude", []string{"src/", ":^src/gen"}, "src/gen/a.go", false},
+		{"long exclude magic", []string{":(exclude)vendor"}, "vendor/x/y.go", false},
+		{"include and exclude", []string{"src/", ":!*.lock"}, "src/Cargo.lock", false},
+		{"outside include", []string{"src/", ":!*.lock"}, "docs/a.md", false},
+		{"glob magic star stays in directory", []string{":(glob)*.go"}, "app/git.go", false},
+		{"glob magic double star", []string{":(glob)**/*.go"}, "app/git.go", true},
+		{"top magic", []string{":/app"}, "app/git.go", true},
+		{"character class", []string{"app/[gh]*.go"}, "app/history.go", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec, err := ParsePathspec(test.args)
+			if err != nil {
+				t.Fatalf("ParsePathspec failed: %v", err)
+			}
+			if result := spec.Match(test.path); result != test.expected {
+				t.Errorf("Match(%q) with %q = %v, expected %v", test.path, test.args, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestParsePathspecErrors(t *testing.T) {
+	for _, arg := range []string{":(exclude", ":(icase)a", "a[b"} {
+		if _, err := ParsePathspec([]string{arg}); err == nil {
+			t.Errorf("Expected an error for %q", arg)
+		}
+	}
+}
+
+func TestGetFileDiffsPathspec(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.writeFile("src/main.go", "package main\n")
+	tr.writeFile("go.sum", "a v1\n")
+	tr.stage("src/main.go")
+	tr.stage("go.sum")
+	base := tr.commit("chore: initial commit", "Alice")
+
+	tr.writeFile("src/main.go", "package main\n\nfunc main() {}\n")
+	tr.writeFile("go.sum", "a v2\n")
+	tr.writeFile("src/old.lock", "x\n")
+	tr.stage("src/main.go")
+	tr.stage("go.sum")
+	tr.stage("src/old.lock")
+	head := tr.commit("feat: add main", "Alice")
+
+	opts := DefaultDiffOptions()
+	opts.Pathspec, _ = ParsePathspec([]string{"src/", ":!*.lock"})
+
+	fileDiffs, err := tr.gitRepo().GetFileDiffs(base.String(), head.String(), opts)
+	if err != nil {
+		t.Fatalf("GetFileDiffs failed: %v", err)
+	}
+
+	if len(fileDiffs) != 1 || fileDiffs[0].Filename != "src/main.go" {
+		t.Errorf("Expected only src/main.go, got %v", fileDiffs)
+	}
+}
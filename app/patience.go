@@ -0,0 +1,100 @@
+package app
+
+import (
+	"sort"
+
+	"github.com/aymanbagabas/go-udiff"
+)
+
+// PatienceEdits computes line-level edits turning before into after with the patience algorithm.
+func PatienceEdits(before, after string) []udiff.Edit {
+	return ComputeEdits(before, after, DiffOptions{Algorithm: DiffPatience})
+}
+
+// patience matches a[a0:a1] and b[b0:b1] by anchoring on lines that occur exactly once
+// in both ranges, keeping the longest run of anchors that appear in the same order, and
+// recursing between them. Ranges without unique common lines fall back to Myers.
+func (d *lineDiffer) patience(a0, a1, b0, b1 int) {
+	a0, a1, b0, b1, suffix := d.trim(a0, a1, b0, b1)
+
+	if a0 < a1 && b0 < b1 {
+		anchors := d.uniqueAnchors(a0, a1, b0, b1)
+		if len(anchors) == 0 {
+			d.myers(a0, a1, b0, b1)
+		} else {
+			i, j := a0, b0
+			for _, anchor := range anchors {
+				d.patience(i, anchor[0], j, anchor[1])
+				d.match(anchor[0], anchor[1])
+				i, j = anchor[0]+1, anchor[1]+1
+			}
+			d.patience(i, a1, j, b1)
+		}
+	}
+
+	d.matchSuffix(a1, b1, suffix)
+}
+
+// uniqueAnchors returns the pairs of positions of lines that occur exactly once in both
+// a[a0:a1] and b[b0:b1], reduced to the longest sequence increasing on both sides.
+func (d *lineDiffer) uniqueAnchors(a0, a1, b0, b1 int) [][2]int {
+	type occurrence struct {
+		countA, countB int
+		posB           int
+	}
+
+	lines := make(map[int]*occurrence)
+	for i := a0; i < a1; i++ {
+		o, ok := lines[d.a[i]]
+		if !ok {
+			o = &occurrence{}
+			lines[d.a[i]] = o
+		}
+		o.countA++
+	}
+	for j := b0; j < b1; j++ {
+		if o, ok := lines[d.b[j]]; ok {
+			o.countB++
+			o.posB = j
+		}
+	}
+
+	var candidates [][2]int
+	for i := a0; i < a1; i++ {
+		if o := lines[d.a[i]]; o.countA == 1 && o.countB == 1 {
+			candidates = append(candidates, [2]int{i, o.posB})
+		}
+	}
+
+	return longestIncreasing(candidates)
+}
+
+// longestIncreasing returns the longest subsequence of pairs, which are sorted by their
+// first element, whose second elements are increasing, using patience sorting.
+func longestIncreasing(pairs [][2]int) [][2]int {
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	// tails[k] is the index of the smallest tail of an increasing run of length k+1
+	var tails []int
+	prev := make([]int, len(pairs))
+	for n, pair := range pairs {
+		k := sort.Search(len(tails), func(k int) bool { return pairs[tails[k]][1] >= pair[1] })
+		prev[n] = -1
+		if k > 0 {
+			prev[n] = tails[k-1]
+		}
+		if k == len(tails) {
+			tails = append(tails, n)
+		} else {
+			tails[k] = n
+		}
+	}
+
+	result := make([][2]int, len(tails))
+	for k, n := len(tails)-1, tails[len(tails)-1]; k >= 0; k, n = k-1, prev[n] {
+		result[k] = pairs[n]
+	}
+	return result
+}
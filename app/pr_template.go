@@ -0,0 +1,107 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/viper"
+)
+
+// PRTemplateLocations are where GitHub and GitLab look for PR templates, in the order
+// gitguy offers them. Names are matched ignoring case, as GitHub does.
+var PRTemplateLocations = []string{
+	".github/pull_request_template.md",
+	".github/PULL_REQUEST_TEMPLATE/*.md",
+	"docs/pull_request_template.md",
+	".gitlab/merge_request_templates/*.md",
+}
+
+// PRTemplate is a template for PR descriptions.
+type PRTemplate struct {
+	// Path is the template file, relative to the repository root when it was found in
+	// one of the [PRTemplateLocations].
+	Path    string
+	Content string
+}
+
+// FindPRTemplates returns the templates in the [PRTemplateLocations] of the working tree.
+func (g *GitRepo) FindPRTemplates() ([]PRTemplate, error) {
+	worktree, err := g.repo.Worktree()
+	if errors.Is(err, git.ErrIsBareRepository) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	var templates []PRTemplate
+	for _, location := range PRTemplateLocations {
+		paths, err := globFold(worktree.Filesystem, ".", strings.Split(location, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to find PR templates: %w", err)
+		}
+
+		for _, filePath := range paths {
+			content, err := util.ReadFile(worktree.Filesystem, filePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read PR template %s: %w", filePath, err)
+			}
+			templates = append(templates, PRTemplate{Path: filePath, Content: string(content)})
+		}
+	}
+	return templates, nil
+}
+
+// globFold returns the files below dir matching the pattern segments, ignoring case.
+func globFold(fs billy.Filesystem, dir string, segments []string) ([]string, error) {
+	entries, err := fs.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var matches []string
+	for _, entry := range entries {
+		if ok, _ := path.Match(strings.ToLower(segments[0]), strings.ToLower(entry.Name())); !ok {
+			continue
+		}
+
+		entryPath := path.Join(dir, entry.Name())
+		switch {
+		case len(segments) > 1 && entry.IsDir():
+			found, err := globFold(fs, entryPath, segments[1:])
+			if err != nil {
+				return nil, err
+			}
+			matches = append(matches, found...)
+		case len(segments) == 1 && !entry.IsDir():
+			matches = append(matches, entryPath)
+		}
+	}
+	return matches, nil
+}
+
+// PRTemplatesFromConfig returns the template of the pr-template setting, or else the
+// templates found in the repository, see [GitRepo.FindPRTemplates].
+func (g *GitRepo) PRTemplatesFromConfig() ([]PRTemplate, error) {
+	file := viper.GetString("pr-template")
+	if file == "" {
+		return g.FindPRTemplates()
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PR template file: %w", err)
+	}
+	return []PRTemplate{{Path: file, Content: string(content)}}, nil
+}
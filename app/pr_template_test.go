@@ -0,0 +1,109 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/viper"
+)
+
+func TestFindPRTemplates(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.writeFile(".github/PULL_REQUEST_TEMPLATE.md", "## Summary\n")
+	tr.writeFile(".github/PULL_REQUEST_TEMPLATE/release.md", "## Release\n")
+	tr.writeFile(".github/pull_request_template/bugfix.md", "## Bug\n")
+	tr.writeFile(".github/PULL_REQUEST_TEMPLATE/notes.txt", "not a template\n")
+	tr.writeFile("docs/pull_request_template.md", "## Docs\n")
+	tr.writeFile(".gitlab/merge_request_templates/Default.md", "## MR\n")
+	tr.writeFile("pull_request_template.md", "## Root\n")
+
+	templates, err := tr.gitRepo().FindPRTemplates()
+	if err != nil {
+		t.Fatalf("FindPRTemplates() error: %v", err)
+	}
+
+	expected := []PRTemplate{
+		{".github/PULL_REQUEST_TEMPLATE.md", "## Summary\n"},
+		{".github/PULL_REQUEST_TEMPLATE/release.md", "## Release\n"},
+		{".github/pull_request_template/bugfix.md", "## Bug\n"},
+		{"docs/pull_request_template.md", "## Docs\n"},
+		{".gitlab/merge_request_templates/Default.md", "## MR\n"},
+	}
+	if len(templates) != len(expected) {
+		t.Fatalf("FindPRTemplates() = %v, expected %v", templates, expected)
+	}
+	for i := range expected {
+		if templates[i] != expected[i] {
+			t.Errorf("Template %d = %v, expected %v", i, templates[i], expected[i])
+		}
+	}
+}
+
+func TestPreparePromptUsesPRTemplate(t *testing.T) {
+	defer viper.Reset()
+	tr := newTestRepo(t)
+	tr.writeFile("docs/pull_request_template.md", "## Docs\n")
+	tr.writeFile(".github/pull_request_template.md", "## Summary\n")
+	fileDiffs := []FileDiff{offlineFileDiff(ChangeModify, "app/api.go", "app/api.go", "+x\n")}
+
+	prompt, err := tr.gitRepo().PreparePrompt(fileDiffs, nil, "", "")
+	if err != nil {
+		t.Fatalf("PreparePrompt() error: %v", err)
+	}
+	if len(prompt.PRTemplates) != 2 || prompt.PRTemplate != ".github/pull_request_template.md" || prompt.Context.PRTemplate != "## Summary\n" {
+		t.Errorf("Expected the first template to be used, got %q of %v", prompt.PRTemplate, prompt.PRTemplates)
+	}
+
+	file := filepath.Join(t.TempDir(), "template.md")
+	if err := os.WriteFile(file, []byte("## Explicit\n"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	viper.Set("pr-template", file)
+	prompt, err = tr.gitRepo().PreparePrompt(fileDiffs, nil, "", "")
+	if err != nil {
+		t.Fatalf("PreparePrompt() error: %v", err)
+	}
+	if len(prompt.PRTemplates) != 1 || prompt.Context.PRTemplate != "## Explicit\n" {
+		t.Errorf("Expected only the pr-template file, got %v", prompt.PRTemplates)
+	}
+
+	viper.Set("pr-template", filepath.Join(t.TempDir(), "missing.md"))
+	if _, err := tr.gitRepo().PreparePrompt(fileDiffs, nil, "", ""); err == nil {
+		t.Error("Expected an error for a missing pr-template file")
+	}
+}
+
+func TestTemplatePicker(t *testing.T) {
+	tr := newTestRepo(t)
+	m := newModel(tr.gitRepo())
+	m.state = diffView
+	m.prompt = &PreparedPrompt{PRTemplates: []PRTemplate{
+		{Path: ".github/PULL_REQUEST_TEMPLATE/feature.md", Content: "## Feature"},
+		{Path: ".github/PULL_REQUEST_TEMPLATE/release.md", Content: "## Release"},
+	}}
+	m.prompt.UsePRTemplate(m.prompt.PRTemplates[0])
+
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	updated, _ = updated.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g")})
+	m = updated.(model)
+	if m.state != templatePickerView {
+		t.Fatalf("Expected g to open the template picker with several templates, got state %d", m.state)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	updated, cmd := updated.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(model)
+	if m.state != diffView || cmd == nil {
+		t.Fatalf("Expected enter to start generating, got state %d", m.state)
+	}
+	if m.prompt.PRTemplate != ".github/PULL_REQUEST_TEMPLATE/release.md" || m.prompt.Context.PRTemplate != "## Release" {
+		t.Errorf("Expected the release template to be used, got %q", m.prompt.PRTemplate)
+	}
+
+	updated, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g")})
+	if updated.(model).state != diffView || cmd == nil {
+		t.Error("Expected g to generate right away once a template was picked")
+	}
+}
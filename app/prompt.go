@@ -0,0 +1,277 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/spf13/viper"
+)
+
+// PreparedPrompt is what is sent to the model for a set of changes, after files were left
+// out, names anonymized and sensitive values redacted.
+type PreparedPrompt struct {
+	Diff    string
+	Context PromptContext
+	// Redactions lists the values replaced by placeholders before sending.
+	Redactions []Redaction
+	// Anonymizer renamed paths and identifiers in the prompt, or is nil when the
+	// anonymize mode is off.
+	Anonymizer *Anonymizer
+	// TicketDetails were fetched for the ticket of the branch by the configured
+	// [TicketProvider], or TicketError says why they could not be.
+	TicketDetails *TicketDetails
+	TicketError   error
+	// PRTemplates are the PR templates to choose from, see [GitRepo.PRTemplatesFromConfig],
+	// and PRTemplate is the path of the one in use, the first by default.
+	PRTemplates []PRTemplate
+	PRTemplate  string
+
+	// fileDiffs and commits are the original changes, used to generate a result offline
+	fileDiffs []FileDiff
+	commits   []CommitInfo
+	// scopes are the inferred scopes before anonymization, see [InferScopes]
+	scopes   []string
+	style    Style
+	lint     LintRules
+	redactor *Redactor
+	// trailers are added to every result, see [GitRepo.TrailersFromConfig]
+	trailers []Trailer
+	// ticket is the ticket of the branch, referenced as ticketReference says
+	ticket          *Ticket
+	ticketReference string
+}
+
+// PreparePrompt builds the prompt for file diffs, the commits of their range and the
+// branch they were made on, as the configuration of the repository says. Base is the
+// revision the changes were made on, or empty for HEAD.
+func (g *GitRepo) PreparePrompt(fileDiffs []FileDiff, commits []CommitInfo, branch, base string) (*PreparedPrompt, error) {
+	// Files in the PromptIgnoreFile or marked as generated are left out of the diff
+	ignore, err := g.LoadPromptIgnore()
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := ScopeRulesFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	scopes := InferScopes(fileDiffs, rules)
+
+	lint, err := g.LintRulesFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	trailers, err := g.TrailersFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// The ticket named by the branch is referenced in the result and described by the
+	// ticket provider, if any
+	ticket, err := BranchTicket(branch)
+	if err != nil {
+		return nil, err
+	}
+	reference, err := TicketReference()
+	if err != nil {
+		return nil, err
+	}
+	provider, err := g.TicketProviderFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	templates, err := g.PRTemplatesFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	promptTemplates, err := g.PromptTemplatesFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	style, err := g.StyleFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	// Examples show the conventions of the repository, from its history or curated ones
+	examples, err := g.ExamplesFromConfig(style, lint, base)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, omitted := ignore.PromptDiff(fileDiffs)
+	prompt := &PreparedPrompt{
+		Diff: diff,
+		Context: PromptContext{
+			Commits:      commits,
+			OmittedFiles: omitted,
+			Scopes:       scopes,
+			Branch:       branch,
+			Repo:         g.RepoName(),
+			Languages:    DiffLanguages(fileDiffs),
+			Stats:        CountDiffStats(fileDiffs),
+			Style:        style.Name,
+			Examples:     examples,
+			Templates:    promptTemplates,
+		},
+
+		fileDiffs: fileDiffs,
+		commits:   commits,
+		scopes:    scopes,
+		style:     style,
+		lint:      lint,
+		trailers:  trailers,
+
+		ticket:          ticket,
+		ticketReference: reference,
+	}
+	prompt.PRTemplates = templates
+	if len(templates) > 0 {
+		prompt.UsePRTemplate(templates[0])
+	}
+	if ticket != nil {
+		prompt.Context.Ticket = ticket.ID
+	}
+	if ticket != nil && provider != nil {
+		// A tracker that is down should not stop generating, the ticket is only context
+		prompt.TicketDetails, prompt.TicketError = FetchTicketContext(provider, ticket)
+		if prompt.TicketDetails != nil {
+			prompt.Context.TicketTitle = prompt.TicketDetails.Title
+			prompt.Context.TicketDescription = prompt.TicketDetails.Description
+		}
+	}
+	if viper.IsSet("lint-types") {
+		prompt.Context.Types = lint.Types
+	}
+
+	// Anonymize first, so that aliases never replace parts of redaction placeholders
+	anonymizer, err := g.AnonymizerFromConfig(fileDiffs, prompt.Diff, prompt.Context)
+	if err != nil {
+		return nil, err
+	}
+	if anonymizer != nil {
+		prompt.Diff, prompt.Context = anonymizer.AnonymizePrompt(prompt.Diff, prompt.Context)
+		prompt.Anonymizer = anonymizer
+	}
+
+	redactor, err := RedactorFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	if redactor != nil {
+		prompt.Diff, prompt.Context = redactor.RedactPrompt(prompt.Diff, prompt.Context)
+		prompt.Redactions = redactor.Redactions()
+		prompt.redactor = redactor
+		prompt.Context.Redacted = true
+	}
+
+	return prompt, nil
+}
+
+// Render returns the system and user messages the prompt sends to the model.
+func (p *PreparedPrompt) Render() (system, user string, err error) {
+	return p.Context.Templates.Render(p.Diff, p.Context)
+}
+
+// UsePRTemplate makes the model follow a PR template.
+func (p *PreparedPrompt) UsePRTemplate(template PRTemplate) {
+	p.PRTemplate = template.Path
+	p.Context.PRTemplate = template.Content
+}
+
+// AddTrailers adds trailers to every result, such as the co-authors of the range from
+// [GitRepo.PromptCoAuthors]. They come before configured trailers, so that a sign-off
+// stays last.
+func (p *PreparedPrompt) AddTrailers(trailers ...Trailer) {
+	p.trailers = AddTrailers(trailers, p.trailers...)
+}
+
+// Generate sends the prompt to the model and returns the result, checked against the
+// lint rules.
+func (p *PreparedPrompt) Generate() (*LLMResult, error) {
+	// Without the model, the result is generated from the changed files
+	if viper.GetBool("offline") {
+		return p.applyTicket(p.generateOffline("offline mode")), nil
+	}
+
+	pctx := p.Context
+	for attempt := 0; ; attempt++ {
+		result, err := GenerateCommitAndPR(p.Diff, pctx)
+		if errors.Is(err, ErrNoAPIKey) || errors.Is(err, ErrModelUnreachable) {
+			return p.applyTicket(p.generateOffline(err.Error())), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if p.Anonymizer != nil {
+			result = p.Anonymizer.RestoreResult(result)
+		}
+		// The model does not always keep to the scope it was told to use
+		if p.style.Conventional {
+			result.CommitMessage = EnforceScope(result.CommitMessage, p.scopes)
+		}
+		// Configured trailers, such as a sign-off, come last
+		result.Trailers = AddTrailers(result.Trailers, p.trailers...)
+		result.Violations = p.lint.Lint(result.FullCommitMessage())
+		result.RepairAttempts = attempt
+
+		if len(result.Violations) == 0 {
+			return p.applyTicket(result), nil
+		}
+		// After the last repair, the offline header replaces one that still breaks the rules
+		if attempt >= LintRepairAttempts() {
+			offline := p.generateOffline("")
+			if len(p.lint.Lint(FormatCommitMessage(offline.CommitMessage, result.Body, result.Trailers))) == 0 {
+				result.CommitMessage = offline.CommitMessage
+				result.Violations = nil
+			}
+			return p.applyTicket(result), nil
+		}
+
+		// Send the message back to the model with its violations
+		pctx.Rejected = p.rejected(result)
+	}
+}
+
+// generateOffline generates the result from the changed files, see [GenerateOffline], with
+// its header rewritten in the style.
+func (p *PreparedPrompt) generateOffline(reason string) *LLMResult {
+	var scope string
+	if len(p.scopes) > 0 {
+		scope = p.scopes[0]
+	}
+	result := GenerateOffline(p.fileDiffs, p.commits, scope, reason)
+	result.CommitMessage = p.style.FromConventional(result.CommitMessage)
+	result.Trailers = AddTrailers(result.Trailers, p.trailers...)
+	result.Violations = p.lint.Lint(result.FullCommitMessage())
+	return result
+}
+
+// applyTicket references the ticket of the branch in a result, see [ApplyTicket].
+func (p *PreparedPrompt) applyTicket(result *LLMResult) *LLMResult {
+	ApplyTicket(result, p.ticket, p.ticketReference, p.lint.MaxHeaderLength)
+	return result
+}
+
+// rejected describes a result breaking the lint rules for the model, anonymized and
+// redacted like the rest of the prompt.
+func (p *PreparedPrompt) rejected(result *LLMResult) *RejectedMessage {
+	rejected := &RejectedMessage{CommitMessage: result.FullCommitMessage()}
+	for _, violation := range result.Violations {
+		rejected.Violations = append(rejected.Violations, violation.String())
+	}
+
+	if p.Anonymizer != nil {
+		rejected.CommitMessage = p.Anonymizer.Anonymize(rejected.CommitMessage)
+		for i, violation := range rejected.Violations {
+			rejected.Violations[i] = p.Anonymizer.Anonymize(violation)
+		}
+	}
+	if p.redactor != nil {
+		rejected.CommitMessage = p.redactor.Redact(rejected.CommitMessage)
+		for i, violation := range rejected.Violations {
+			rejected.Violations[i] = p.redactor.Redact(violation)
+		}
+	}
+	return rejected
+}
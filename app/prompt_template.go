@@ -0,0 +1,236 @@
+package app
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/viper"
+)
+
+//go:embed templates/system_prompt.md
+var systemPromptTemplate string
+
+//go:embed templates/user_prompt.md
+var userPromptTemplate string
+
+// Prompt template files in the repository, relative to its root, that replace the
+// built-in templates unless system-prompt or user-prompt point elsewhere.
+const (
+	SystemPromptFile = ".gitguy/system_prompt.md"
+	UserPromptFile   = ".gitguy/user_prompt.md"
+)
+
+// promptFuncs are the functions available in prompt templates besides the built-in ones.
+var promptFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// defaultPromptTemplates are the built-in templates, used when none are configured.
+var defaultPromptTemplates = &PromptTemplates{
+	System: template.Must(parsePromptTemplate("system", systemPromptTemplate)),
+	User:   template.Must(parsePromptTemplate("user", userPromptTemplate)),
+}
+
+// PromptTemplates render the system and user messages sent to the model, as text/template
+// templates executed with [PromptData].
+type PromptTemplates struct {
+	System *template.Template
+	User   *template.Template
+	// SystemPath and UserPath are the files the templates were read from, or empty for
+	// the built-in ones.
+	SystemPath string
+	UserPath   string
+}
+
+// DiffStats counts the files and lines a change touches.
+type DiffStats struct {
+	Files     int
+	Additions int
+	Deletions int
+}
+
+// PromptData is what prompt templates are executed with: the fields of the
+// [PromptContext], the diff and what is derived from them.
+type PromptData struct {
+	PromptContext
+	Diff string
+	// CommitLog is the commits of the range, oldest first, see [FormatCommitLog].
+	CommitLog string
+	// Language is the main language of the change, the first of the Languages.
+	Language string
+	// Preset is the commit message style named by Style.
+	Preset Style
+}
+
+// parsePromptTemplate parses a prompt template, with the functions prompt templates
+// can use.
+func parsePromptTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(promptFuncs).Parse(text)
+}
+
+// PromptTemplatesFromConfig returns the prompt templates in the files of the system-prompt
+// and user-prompt settings, or else in [SystemPromptFile] and [UserPromptFile] of the
+// repository. Missing templates are the built-in ones.
+func (g *GitRepo) PromptTemplatesFromConfig() (*PromptTemplates, error) {
+	templates := *defaultPromptTemplates
+
+	system, systemPath, err := g.loadPromptTemplate("system", "system-prompt", SystemPromptFile)
+	if err != nil {
+		return nil, err
+	}
+	if system != nil {
+		templates.System, templates.SystemPath = system, systemPath
+	}
+
+	user, userPath, err := g.loadPromptTemplate("user", "user-prompt", UserPromptFile)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		templates.User, templates.UserPath = user, userPath
+	}
+	return &templates, nil
+}
+
+// loadPromptTemplate parses the template in the file of a setting, or else in a file of
+// the repository, and returns nil if there is neither.
+func (g *GitRepo) loadPromptTemplate(name, key, repoFile string) (*template.Template, string, error) {
+	var text, file string
+	if file = viper.GetString(key); file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read %s prompt template: %w", name, err)
+		}
+		text = string(content)
+	} else {
+		worktree, err := g.repo.Worktree()
+		if errors.Is(err, git.ErrIsBareRepository) {
+			return nil, "", nil
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get worktree: %w", err)
+		}
+		if text, err = readOptionalFile(worktree.Filesystem, repoFile); err != nil {
+			return nil, "", err
+		}
+		if text == "" {
+			return nil, "", nil
+		}
+		file = repoFile
+	}
+
+	tmpl, err := parsePromptTemplate(name, text)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse %s prompt template %s: %w", name, file, err)
+	}
+	return tmpl, file, nil
+}
+
+// Render executes the templates for a diff and its context, returning the system and user
+// messages. Nil templates are the built-in ones.
+func (t *PromptTemplates) Render(diff string, pctx PromptContext) (system, user string, err error) {
+	if t == nil {
+		t = defaultPromptTemplates
+	}
+
+	preset, err := LookupStyle(pctx.Style)
+	if err != nil {
+		return "", "", err
+	}
+
+	data := PromptData{PromptContext: pctx, Diff: diff, Preset: preset}
+	if len(pctx.Commits) > 0 {
+		data.CommitLog = FormatCommitLog(pctx.Commits)
+	}
+	if len(pctx.Languages) > 0 {
+		data.Language = pctx.Languages[0]
+	}
+
+	var b strings.Builder
+	if err := t.System.Execute(&b, data); err != nil {
+		return "", "", fmt.Errorf("failed to render system prompt: %w", err)
+	}
+	system = b.String()
+
+	b.Reset()
+	if err := t.User.Execute(&b, data); err != nil {
+		return "", "", fmt.Errorf("failed to render user prompt: %w", err)
+	}
+	return system, b.String(), nil
+}
+
+// CountDiffStats counts the files, and the lines added and removed, of file diffs.
+func CountDiffStats(fileDiffs []FileDiff) DiffStats {
+	stats := DiffStats{Files: len(fileDiffs)}
+	for _, fileDiff := range fileDiffs {
+		added, deleted := fileDiff.LineStats()
+		stats.Additions += added
+		stats.Deletions += deleted
+	}
+	return stats
+}
+
+// nonProgrammingLexers are syntax highlighter lexers of text, markup and data formats,
+// which do not make anyone a developer of them.
+var nonProgrammingLexers = map[string]bool{
+	"plaintext": true, "markdown": true, "reStructuredText": true, "Org Mode": true, "TeX": true,
+	"YAML": true, "JSON": true, "TOML": true, "INI": true, "XML": true, "Diff": true,
+}
+
+// DiffLanguages returns the programming languages of the changed files, as named by the
+// syntax highlighter, most changed lines first. Files in no known language, or in a text,
+// markup or data format, are skipped.
+func DiffLanguages(fileDiffs []FileDiff) []string {
+	lines := make(map[string]int)
+	for _, fileDiff := range fileDiffs {
+		lexer := lexers.Match(path.Base(fileDiff.Filename))
+		if lexer == nil || nonProgrammingLexers[lexer.Config().Name] {
+			continue
+		}
+		added, deleted := fileDiff.LineStats()
+		lines[lexer.Config().Name] += added + deleted
+	}
+
+	languages := make([]string, 0, len(lines))
+	for language := range lines {
+		languages = append(languages, language)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if lines[languages[i]] != lines[languages[j]] {
+			return lines[languages[i]] > lines[languages[j]]
+		}
+		return languages[i] < languages[j]
+	})
+	return languages
+}
+
+// RepoName returns the name of the repository, from the URL of its origin remote or else
+// the directory of its working tree.
+func (g *GitRepo) RepoName() string {
+	if remote, err := g.repo.Remote("origin"); err == nil {
+		for _, remoteURL := range remote.Config().URLs {
+			name := strings.TrimSuffix(strings.TrimRight(remoteURL, "/"), ".git")
+			if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+				name = name[i+1:]
+			}
+			if name != "" {
+				return name
+			}
+		}
+	}
+
+	worktree, err := g.repo.Worktree()
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(worktree.Filesystem.Root())
+}
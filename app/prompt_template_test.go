@@ -0,0 +1,187 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/spf13/viper"
+)
+
+// renderUserPrompt renders the built-in user prompt for a diff and its context.
+func renderUserPrompt(t *testing.T, diff string, pctx PromptContext) string {
+	t.Helper()
+
+	_, user, err := pctx.Templates.Render(diff, pctx)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	return user
+}
+
+func TestRenderBuiltInPrompt(t *testing.T) {
+	pctx := PromptContext{
+		Repo:       "gitguy",
+		Languages:  []string{"Go", "Markdown"},
+		Stats:      DiffStats{Files: 3, Additions: 12, Deletions: 4},
+		Scopes:     []string{"app", "docs"},
+		PRTemplate: "## Checklist",
+	}
+	system, user, err := pctx.Templates.Render("+x", pctx)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+
+	if !strings.HasPrefix(system, "You are an expert Go developer assistant") {
+		t.Errorf("Expected the main language in the system prompt, got:\n%s", system)
+	}
+	if strings.Contains(system, "placeholders") {
+		t.Errorf("Expected no redaction guideline without redaction, got:\n%s", system)
+	}
+	if !strings.HasSuffix(system, "structure and format of the PR description:\n\n## Checklist\n") {
+		t.Errorf("Expected the PR template at the end of the system prompt, got:\n%s", system)
+	}
+
+	expected := []string{
+		`The change touches 3 files in the "gitguy" repository, adding 12 and removing 4 lines, written in Go, Markdown.`,
+		"```diff\n+x\n```",
+		"listed by how much of the change they cover: app, docs.",
+	}
+	for _, text := range expected {
+		if !strings.Contains(user, text) {
+			t.Errorf("Expected %q in the user prompt, got:\n%s", text, user)
+		}
+	}
+	if strings.Contains(user, "branch") {
+		t.Errorf("Expected no branch without one, got:\n%s", user)
+	}
+}
+
+func TestPromptTemplatesFromConfig(t *testing.T) {
+	defer viper.Reset()
+	tr := newTestRepo(t)
+	tr.writeFile(UserPromptFile, "Describe {{.Diff}} on {{.Branch}} in {{.Style}} style for {{.Repo}}.")
+	repo := tr.gitRepo()
+
+	templates, err := repo.PromptTemplatesFromConfig()
+	if err != nil {
+		t.Fatalf("PromptTemplatesFromConfig() error: %v", err)
+	}
+	if templates.SystemPath != "" || templates.UserPath != UserPromptFile {
+		t.Errorf("Expected the built-in system and the repository's user template, got %q and %q", templates.SystemPath, templates.UserPath)
+	}
+	_, user, err := templates.Render("+x", PromptContext{Branch: "main", Style: DefaultStyle, Repo: "gitguy"})
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if user != "Describe +x on main in conventional style for gitguy." {
+		t.Errorf("Unexpected user prompt %q", user)
+	}
+
+	file := filepath.Join(t.TempDir(), "system.md")
+	if err := os.WriteFile(file, []byte("Write {{join .Scopes \" or \"}} commits."), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	viper.Set("system-prompt", file)
+	templates, err = repo.PromptTemplatesFromConfig()
+	if err != nil {
+		t.Fatalf("PromptTemplatesFromConfig() error: %v", err)
+	}
+	system, _, err := templates.Render("+x", PromptContext{Scopes: []string{"app", "docs"}})
+	if err != nil || system != "Write app or docs commits." || templates.SystemPath != file {
+		t.Errorf("Expected the system-prompt file, got %q from %q (%v)", system, templates.SystemPath, err)
+	}
+
+	tr.writeFile(UserPromptFile, "{{if .Diff}")
+	if _, err := repo.PromptTemplatesFromConfig(); err == nil || !strings.Contains(err.Error(), "failed to parse user prompt template "+UserPromptFile) {
+		t.Errorf("Expected a parse error naming the file, got %v", err)
+	}
+
+	tr.writeFile(UserPromptFile, "{{.Unknown}}")
+	templates, err = repo.PromptTemplatesFromConfig()
+	if err != nil {
+		t.Fatalf("PromptTemplatesFromConfig() error: %v", err)
+	}
+	if _, _, err := templates.Render("+x", PromptContext{}); err == nil || !strings.Contains(err.Error(), "failed to render user prompt") {
+		t.Errorf("Expected a render error, got %v", err)
+	}
+}
+
+func TestPreparePromptVariables(t *testing.T) {
+	defer viper.Reset()
+	tr := newTestRepo(t)
+	if _, err := tr.repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"git@github.com:acme/invoices.git"}}); err != nil {
+		t.Fatalf("Failed to create remote: %v", err)
+	}
+	fileDiffs := []FileDiff{
+		offlineFileDiff(ChangeModify, "app/api.go", "app/api.go", "+a\n+b\n-c\n"),
+		offlineFileDiff(ChangeModify, "README.md", "README.md", "+docs\n"),
+		offlineFileDiff(ChangeModify, "go.sum", "go.sum", "+hash\n"),
+	}
+
+	prompt, err := tr.gitRepo().PreparePrompt(fileDiffs, nil, "main", "")
+	if err != nil {
+		t.Fatalf("PreparePrompt() error: %v", err)
+	}
+	pctx := prompt.Context
+	if pctx.Repo != "invoices" || pctx.Style != DefaultStyle || pctx.Stats != (DiffStats{Files: 3, Additions: 4, Deletions: 1}) {
+		t.Errorf("Unexpected variables: repo %q, style %q, stats %+v", pctx.Repo, pctx.Style, pctx.Stats)
+	}
+	if strings.Join(pctx.Languages, ",") != "Go" {
+		t.Errorf("Expected only Go, got %v", pctx.Languages)
+	}
+	if system, _, _ := prompt.Render(); !pctx.Redacted || !strings.Contains(system, "replaced with placeholders") {
+		t.Errorf("Expected the redaction guideline, got:\n%s", system)
+	}
+
+	docs, err := tr.gitRepo().PreparePrompt(fileDiffs[1:2], nil, "main", "")
+	if err != nil {
+		t.Fatalf("PreparePrompt() error: %v", err)
+	}
+	if system, _, _ := docs.Render(); !strings.HasPrefix(system, "You are an expert developer assistant") {
+		t.Errorf("Expected no language for a markdown change, got:\n%s", system)
+	}
+
+	viper.Set("anonymize", true)
+	prompt, err = tr.gitRepo().PreparePrompt(fileDiffs, nil, "main", "")
+	if err != nil {
+		t.Fatalf("PreparePrompt() error: %v", err)
+	}
+	if _, user, _ := prompt.Render(); strings.Contains(user, "invoices") {
+		t.Errorf("Expected the repository name to be anonymized, got:\n%s", user)
+	}
+}
+
+func TestGenerateKeepsSystemPrompt(t *testing.T) {
+	defer viper.Reset()
+	var systems []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req APIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		systems = append(systems, req.Messages[0].Content)
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%q}}]}`, "COMMIT: feat: add x\n\nPR:\n## What changed")
+	}))
+	defer server.Close()
+	original := openRouterURL
+	openRouterURL = server.URL
+	defer func() { openRouterURL = original }()
+	viper.Set("api-key", "test-key")
+	viper.Set("config-dir", t.TempDir())
+
+	for range 3 {
+		if _, err := GenerateCommitAndPR("+x", PromptContext{PRTemplate: "## Checklist"}); err != nil {
+			t.Fatalf("GenerateCommitAndPR() error: %v", err)
+		}
+	}
+	if len(systems) != 3 || systems[2] != systems[0] || strings.Count(systems[2], "## Checklist") != 1 {
+		t.Errorf("Expected the same system prompt for every generation, got %d prompts", len(systems))
+	}
+}
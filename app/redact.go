@@ -0,0 +1,273 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// RedactionRule detects one kind of sensitive value in text sent to the model.
+type RedactionRule struct {
+	// Name identifies the rule in placeholders and reports, e.g. "aws-access-key".
+	Name string
+	// Pattern matches the sensitive value.
+	Pattern *regexp.Regexp
+	// Group selects the submatch that is redacted, so surrounding context such as a
+	// variable name can be kept. Zero redacts the whole match.
+	Group int
+	// MinEntropy only redacts matches whose Shannon entropy, in bits per character,
+	// is at least this value. Zero redacts every match.
+	MinEntropy float64
+}
+
+// DefaultRedactionRules detect common credentials and personal data. Rules run in
+// order, so specific formats come before the generic email and entropy rules.
+var DefaultRedactionRules = []RedactionRule{
+	{Name: "private-key", Pattern: regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY( BLOCK)?-----.*?-----END [A-Z ]*PRIVATE KEY( BLOCK)?-----`)},
+	{Name: "connection-string", Pattern: regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\s:/@'"]+:[^\s@/'"]+@[^\s'"]+`)},
+	{Name: "aws-access-key", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "github-token", Pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{Name: "api-key", Pattern: regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`)},
+	{Name: "slack-token", Pattern: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{Name: "stripe-key", Pattern: regexp.MustCompile(`\b[rs]k_(?:live|test)_[A-Za-z0-9]{16,}\b`)},
+	{Name: "google-api-key", Pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{Name: "jwt", Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)},
+	{Name: "secret-assignment", Pattern: regexp.MustCompile(`(?i)(?:password|passwd|secret|token|api[_-]?key)["']?\s*[:=]\s*["']([^"'\s]{6,})["']`), Group: 1},
+	{Name: "email", Pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`)},
+	// The first character excludes "+" and "-" so that diff line markers are kept
+	{Name: "high-entropy", Pattern: regexp.MustCompile(`[A-Za-z0-9/_=][A-Za-z0-9+/_=-]{23,}`), MinEntropy: 4.3},
+}
+
+// Redaction reports how often one sensitive value was replaced, and by which placeholder.
+type Redaction struct {
+	Rule        string
+	Placeholder string
+	Count       int
+}
+
+// Redactor replaces sensitive values with placeholders. A value is always replaced by
+// the same placeholder, so the model can still tell that two occurrences are equal.
+type Redactor struct {
+	rules []RedactionRule
+
+	// placeholders maps each redacted value to its entry in redactions
+	placeholders map[string]int
+	redactions   []Redaction
+	ruleCounts   map[string]int
+}
+
+// NewRedactor creates a Redactor applying the given rules in order.
+func NewRedactor(rules []RedactionRule) *Redactor {
+	return &Redactor{
+		rules:        rules,
+		placeholders: make(map[string]int),
+		ruleCounts:   make(map[string]int),
+	}
+}
+
+// redactionRuleConfig is a rule as written in the redaction-rules config list.
+type redactionRuleConfig struct {
+	Name       string  `mapstructure:"name"`
+	Pattern    string  `mapstructure:"pattern"`
+	Group      int     `mapstructure:"group"`
+	MinEntropy float64 `mapstructure:"min-entropy"`
+}
+
+// RedactorFromConfig builds a Redactor from the default rules, minus those listed in
+// redaction-disabled-rules, plus the rules in redaction-rules. It returns nil when
+// redaction is turned off with redact: false.
+func RedactorFromConfig() (*Redactor, error) {
+	if viper.IsSet("redact") && !viper.GetBool("redact") {
+		return nil, nil
+	}
+
+	disabled := make(map[string]bool)
+	for _, name := range viper.GetStringSlice("redaction-disabled-rules") {
+		disabled[name] = true
+	}
+
+	var rules []RedactionRule
+	for _, rule := range DefaultRedactionRules {
+		if !disabled[rule.Name] {
+			rules = append(rules, rule)
+		}
+	}
+
+	var configured []redactionRuleConfig
+	if err := viper.UnmarshalKey("redaction-rules", &configured); err != nil {
+		return nil, fmt.Errorf("failed to read redaction-rules: %w", err)
+	}
+
+	custom := make([]RedactionRule, 0, len(configured))
+	for _, c := range configured {
+		pattern, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for redaction rule %q: %w", c.Name, err)
+		}
+		if c.Name == "" {
+			return nil, fmt.Errorf("redaction rule with pattern %q needs a name", c.Pattern)
+		}
+		if c.Group < 0 || c.Group > pattern.NumSubexp() {
+			return nil, fmt.Errorf("redaction rule %q has no group %d", c.Name, c.Group)
+		}
+		custom = append(custom, RedactionRule{Name: c.Name, Pattern: pattern, Group: c.Group, MinEntropy: c.MinEntropy})
+	}
+
+	// Custom rules usually target specific formats, so they run before the defaults
+	return NewRedactor(append(custom, rules...)), nil
+}
+
+// redactedSpan is a value in text matched by a rule.
+type redactedSpan struct {
+	start, end int
+	rule       string
+}
+
+// Redact replaces every sensitive value in text with its placeholder. Every rule matches
+// the original text, and a match overlapping one of an earlier rule is dropped, so a
+// value gets the same placeholder wherever it appears and is counted once.
+func (r *Redactor) Redact(text string) string {
+	var spans []redactedSpan
+	for _, rule := range r.rules {
+		for _, match := range rule.Pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := match[2*rule.Group], match[2*rule.Group+1]
+			if start < 0 || overlapsSpan(spans, start, end) {
+				continue
+			}
+			if rule.MinEntropy > 0 && !looksRandom(text[start:end], rule.MinEntropy) {
+				continue
+			}
+			spans = append(spans, redactedSpan{start: start, end: end, rule: rule.Name})
+		}
+	}
+	if len(spans) == 0 {
+		return text
+	}
+
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start < spans[j].start
+	})
+
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		b.WriteString(text[last:span.start])
+		b.WriteString(r.placeholder(span.rule, text[span.start:span.end]))
+		last = span.end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// overlapsSpan reports whether text[start:end] overlaps one of the spans.
+func overlapsSpan(spans []redactedSpan, start, end int) bool {
+	for _, span := range spans {
+		if start < span.end && span.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// placeholder returns the placeholder for a value, creating one numbered per rule.
+func (r *Redactor) placeholder(rule, value string) string {
+	i, ok := r.placeholders[value]
+	if !ok {
+		r.ruleCounts[rule]++
+		r.redactions = append(r.redactions, Redaction{
+			Rule:        rule,
+			Placeholder: fmt.Sprintf("<redacted:%s-%d>", rule, r.ruleCounts[rule]),
+		})
+		i = len(r.redactions) - 1
+		r.placeholders[value] = i
+	}
+	r.redactions[i].Count++
+	return r.redactions[i].Placeholder
+}
+
+// RedactPrompt redacts the diff and the commit messages and ticket sent along with it.
+func (r *Redactor) RedactPrompt(diff string, pctx PromptContext) (string, PromptContext) {
+	commits := make([]CommitInfo, len(pctx.Commits))
+	for i, commit := range pctx.Commits {
+		commit.Subject = r.Redact(commit.Subject)
+		commit.Body = r.Redact(commit.Body)
+		commit.Message = r.Redact(commit.Message)
+		commit.Email = ""
+		commits[i] = commit
+	}
+	pctx.Commits = commits
+	pctx.Branch = r.Redact(pctx.Branch)
+	pctx.TicketTitle = r.Redact(pctx.TicketTitle)
+	pctx.TicketDescription = r.Redact(pctx.TicketDescription)
+
+	examples := Examples{Commits: make([]string, len(pctx.Examples.Commits)), PRs: make([]string, len(pctx.Examples.PRs)), Style: pctx.Examples.Style}
+	for i, example := range pctx.Examples.Commits {
+		examples.Commits[i] = r.Redact(example)
+	}
+	for i, example := range pctx.Examples.PRs {
+		examples.PRs[i] = r.Redact(example)
+	}
+	pctx.Examples = examples
+
+	return r.Redact(diff), pctx
+}
+
+// Redactions reports every placeholder used so far, grouped by rule.
+func (r *Redactor) Redactions() []Redaction {
+	redactions := append([]Redaction(nil), r.redactions...)
+	sort.SliceStable(redactions, func(i, j int) bool {
+		return redactions[i].Rule < redactions[j].Rule
+	})
+	return redactions
+}
+
+// SummarizeRedactions describes redactions per rule, e.g. "2 aws-access-key, 1 email".
+func SummarizeRedactions(redactions []Redaction) string {
+	var rules []string
+	counts := make(map[string]int)
+	for _, redaction := range redactions {
+		if counts[redaction.Rule] == 0 {
+			rules = append(rules, redaction.Rule)
+		}
+		counts[redaction.Rule] += redaction.Count
+	}
+
+	parts := make([]string, len(rules))
+	for i, rule := range rules {
+		parts[i] = fmt.Sprintf("%d %s", counts[rule], rule)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// looksRandom reports whether a value mixes letters and digits and has at least the
+// given Shannon entropy, which tells generated secrets from identifiers and words.
+func looksRandom(value string, minEntropy float64) bool {
+	if !strings.ContainsAny(value, "0123456789") || strings.IndexFunc(value, isASCIILetter) < 0 {
+		return false
+	}
+	return shannonEntropy(value) >= minEntropy
+}
+
+func isASCIILetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+// shannonEntropy returns the entropy of a string in bits per character.
+func shannonEntropy(value string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range value {
+		counts[r]++
+	}
+
+	n := float64(len(value))
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
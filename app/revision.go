@@ -0,0 +1,207 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// reflogPattern matches revision expressions of the form <ref>@{<spec>}<suffix>,
+// e.g. main@{yesterday}, @{2} or HEAD@{1}~2.
+var reflogPattern = regexp.MustCompile(`^([^@]*)@\{([^}]+)\}(.*)$`)
+
+// relativeDatePattern matches approxidate expressions such as "3 days ago" or "2.weeks.ago".
+var relativeDatePattern = regexp.MustCompile(`^(\d+)[ .]+(second|minute|hour|day|week|month|year)s?[ .]+ago$`)
+
+// reflogEntry is a single line of a reference's reflog.
+type reflogEntry struct {
+	hash plumbing.Hash
+	when time.Time
+}
+
+// ResolveRevision resolves any revision expression understood by go-git (branches,
+// tags, short SHAs, HEAD~3, v1.2.0^{commit}, HEAD^{/fix}) plus reflog selectors
+// such as main@{yesterday} or HEAD@{2}, and returns the resolved commit.
+func (g *GitRepo) ResolveRevision(rev string) (CommitInfo, error) {
+	commit, err := g.resolveCommit(rev)
+	if err != nil {
+		return CommitInfo{}, err
+	}
+
+	return newCommitInfo(commit), nil
+}
+
+// resolveHash resolves a revision expression to a commit hash.
+func (g *GitRepo) resolveHash(rev string) (plumbing.Hash, error) {
+	rev = strings.TrimSpace(rev)
+
+	if match := reflogPattern.FindStringSubmatch(rev); match != nil {
+		hash, err := g.resolveReflog(match[1], match[2])
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if match[3] == "" {
+			return hash, nil
+		}
+		rev = hash.String() + match[3]
+	}
+
+	hash, err := g.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return *hash, nil
+}
+
+// resolveReflog resolves a reflog selector (@{n} or @{date}) for the given reference.
+// An empty reference name refers to the currently checked out branch.
+func (g *GitRepo) resolveReflog(refName, spec string) (plumbing.Hash, error) {
+	if refName == "" {
+		head, err := g.repo.Head()
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to get HEAD: %w", err)
+		}
+		refName = head.Name().String()
+	}
+
+	entries, err := g.readReflog(refName)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if n, err := strconv.Atoi(spec); err == nil {
+		if n < 0 || n >= len(entries) {
+			return plumbing.ZeroHash, fmt.Errorf("reflog for %s only has %d entries", refName, len(entries))
+		}
+		return entries[len(entries)-1-n].hash, nil
+	}
+
+	at, err := parseApproxidate(spec, time.Now())
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !entries[i].when.After(at) {
+			return entries[i].hash, nil
+		}
+	}
+
+	if len(entries) == 0 {
+		return plumbing.ZeroHash, fmt.Errorf("reflog for %s is empty", refName)
+	}
+
+	// Like git, fall back to the oldest known value when the date predates the reflog
+	return entries[0].hash, nil
+}
+
+// readReflog reads the reflog of a reference from the repository's .git directory,
+// oldest entry first.
+func (g *GitRepo) readReflog(refName string) ([]reflogEntry, error) {
+	storage, ok := g.repo.Storer.(interface{ Filesystem() billy.Filesystem })
+	if !ok {
+		return nil, fmt.Errorf("reflogs are not available for this repository")
+	}
+	fs := storage.Filesystem()
+
+	candidates := []string{
+		refName,
+		"refs/" + refName,
+		"refs/tags/" + refName,
+		"refs/heads/" + refName,
+		"refs/remotes/" + refName,
+	}
+
+	for _, candidate := range candidates {
+		file, err := fs.Open(fs.Join("logs", candidate))
+		if err != nil {
+			continue
+		}
+		defer file.Close()
+
+		var entries []reflogEntry
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if entry, ok := parseReflogLine(scanner.Text()); ok {
+				entries = append(entries, entry)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read reflog for %s: %w", refName, err)
+		}
+
+		return entries, nil
+	}
+
+	return nil, fmt.Errorf("no reflog found for %s", refName)
+}
+
+// parseReflogLine parses a reflog line of the form
+// "<old> <new> Name <email> <unix-timestamp> <tz>\t<message>".
+func parseReflogLine(line string) (reflogEntry, bool) {
+	header, _, _ := strings.Cut(line, "\t")
+	fields := strings.Fields(header)
+	if len(fields) < 4 {
+		return reflogEntry{}, false
+	}
+
+	timestamp, err := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+	if err != nil {
+		return reflogEntry{}, false
+	}
+
+	return reflogEntry{
+		hash: plumbing.NewHash(fields[1]),
+		when: time.Unix(timestamp, 0),
+	}, true
+}
+
+// parseApproxidate parses the subset of git's approxidate syntax that is useful in
+// reflog selectors: "now", "yesterday", "N <unit>s ago" and absolute dates.
+func parseApproxidate(spec string, now time.Time) (time.Time, error) {
+	spec = strings.TrimSpace(spec)
+	lower := strings.ToLower(spec)
+
+	switch lower {
+	case "now":
+		return now, nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	}
+
+	if match := relativeDatePattern.FindStringSubmatch(lower); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		switch match[2] {
+		case "second":
+			return now.Add(-time.Duration(n) * time.Second), nil
+		case "minute":
+			return now.Add(-time.Duration(n) * time.Minute), nil
+		case "hour":
+			return now.Add(-time.Duration(n) * time.Hour), nil
+		case "day":
+			return now.AddDate(0, 0, -n), nil
+		case "week":
+			return now.AddDate(0, 0, -7*n), nil
+		case "month":
+			return now.AddDate(0, -n, 0), nil
+		case "year":
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+
+	layouts := []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, spec, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unsupported date %q in reflog selector", spec)
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestResolveRevision(t *testing.T) {
+	tr := newTestRepo(t)
+	first := tr.commitFile("README.md", "one\n", "chore: first", "Alice")
+	second := tr.commitFile("README.md", "two\n", "fix: second", "Bob")
+	third := tr.commitFile("README.md", "three\n", "feat: third", "Carol")
+
+	if _, err := tr.repo.CreateTag("v1.0.0", second, nil); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	// go-git does not write reflogs, so record one by hand: oldest entry first
+	now := time.Now()
+	reflog := fmt.Sprintf("%s %s Alice <alice@example.com> %d +0000\tcommit: first\n", plumbing.ZeroHash, first, now.Add(-72*time.Hour).Unix()) +
+		fmt.Sprintf("%s %s Bob <bob@example.com> %d +0000\tcommit: second\n", first, second, now.Add(-36*time.Hour).Unix()) +
+		fmt.Sprintf("%s %s Carol <carol@example.com> %d +0000\tcommit: third\n", second, third, now.Add(-time.Hour).Unix())
+	logPath := filepath.Join(tr.dir, ".git", "logs", "refs", "heads", "master")
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		t.Fatalf("Failed to create reflog directory: %v", err)
+	}
+	if err := os.WriteFile(logPath, []byte(reflog), 0644); err != nil {
+		t.Fatalf("Failed to write reflog: %v", err)
+	}
+
+	tests := []struct {
+		rev      string
+		expected plumbing.Hash
+	}{
+		{"HEAD", third},
+		{"HEAD~2", first},
+		{"master^", second},
+		{third.String()[:7], third},
+		{"v1.0.0^{commit}", second},
+		{"master@{0}", third},
+		{"master@{2}", first},
+		{"@{1}", second},
+		{"master@{yesterday}", second},
+		{"master@{2 days ago}", first},
+		{"master@{1}~1", first},
+	}
+
+	repo := tr.gitRepo()
+	for _, test := range tests {
+		t.Run(test.rev, func(t *testing.T) {
+			commit, err := repo.ResolveRevision(test.rev)
+			if err != nil {
+				t.Fatalf("ResolveRevision(%q) failed: %v", test.rev, err)
+			}
+			if commit.Hash != test.expected.String() {
+				t.Errorf("ResolveRevision(%q) = %s, expected %s", test.rev, commit.ShortHash(), test.expected.String()[:8])
+			}
+		})
+	}
+
+	for _, rev := range []string{"does-not-exist", "master@{10}", "master@{next tuesday}"} {
+		t.Run("invalid "+rev, func(t *testing.T) {
+			if _, err := repo.ResolveRevision(rev); err == nil {
+				t.Errorf("Expected error resolving %q", rev)
+			}
+		})
+	}
+}
+
+func TestParseApproxidate(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"now", now},
+		{"yesterday", now.AddDate(0, 0, -1)},
+		{"3 hours ago", now.Add(-3 * time.Hour)},
+		{"2.weeks.ago", now.AddDate(0, 0, -14)},
+		{"1 month ago", now.AddDate(0, -1, 0)},
+		{"2025-01-02", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"2025-01-02T03:04:05Z", time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			result, err := parseApproxidate(test.spec, now)
+			if err != nil {
+				t.Fatalf("parseApproxidate(%q) failed: %v", test.spec, err)
+			}
+			if !result.Equal(test.expected) {
+				t.Errorf("parseApproxidate(%q) = %v, expected %v", test.spec, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestRevisionInputSelection(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.commitFile("README.md", "one\n", "chore: first", "Alice")
+	second := tr.commitFile("README.md", "two\n", "fix: second", "Bob")
+
+	m := newModel(tr.gitRepo())
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(":")})
+	m = updated.(model)
+	if !m.revisionEditing {
+		t.Fatal("Expected ':' to open the revision input")
+	}
+
+	m.revisionInput.SetValue("HEAD")
+	msg := m.resolveRevision("HEAD")()
+	updated, _ = m.Update(msg)
+	m = updated.(model)
+
+	if m.revisionCommit == nil || m.revisionCommit.Hash != second.String() {
+		t.Fatalf("Expected HEAD to resolve to %s, got %+v (err: %v)", second, m.revisionCommit, m.revisionErr)
+	}
+
+	// A stale result for a previous input must not replace the current preview
+	updated, _ = m.Update(revisionResolvedMsg{input: "HEA", err: fmt.Errorf("stale")})
+	m = updated.(model)
+	if m.revisionErr != nil {
+		t.Error("Expected stale resolution result to be ignored")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(model)
+
+	if m.revisionEditing {
+		t.Error("Expected Enter to close the revision input")
+	}
+	if m.selectedCurrent != second.String() {
+		t.Errorf("Expected current ref to be %s, got %s", second, m.selectedCurrent)
+	}
+	if m.selectedCurrentName != "HEAD ("+second.String()[:8]+")" {
+		t.Errorf("Unexpected selected name %q", m.selectedCurrentName)
+	}
+}
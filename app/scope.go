@@ -0,0 +1,133 @@
+package app
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// unscopedDirs are top-level directories too generic to name a scope.
+var unscopedDirs = map[string]bool{
+	"doc": true, "docs": true, "examples": true, "internal": true, "lib": true, "pkg": true,
+	"scripts": true, "src": true, "test": true, "testdata": true, "tests": true, "vendor": true,
+}
+
+// ScopeRule maps the paths matched by a pathspec to a conventional commit scope.
+type ScopeRule struct {
+	Scope string
+	Paths Pathspec
+}
+
+// scopeRuleConfig is a rule as written in the scopes config list.
+type scopeRuleConfig struct {
+	Scope string   `mapstructure:"scope"`
+	Paths []string `mapstructure:"paths"`
+}
+
+// ScopeRulesFromConfig reads the scopes config list. Paths use pathspec syntax, see
+// [ParsePathspec].
+func ScopeRulesFromConfig() ([]ScopeRule, error) {
+	var configured []scopeRuleConfig
+	if err := viper.UnmarshalKey("scopes", &configured); err != nil {
+		return nil, fmt.Errorf("failed to read scopes: %w", err)
+	}
+
+	rules := make([]ScopeRule, 0, len(configured))
+	for _, c := range configured {
+		if c.Scope == "" || len(c.Paths) == 0 {
+			return nil, fmt.Errorf("scope rules need a scope and paths, got scope %q with %d paths", c.Scope, len(c.Paths))
+		}
+		paths, err := ParsePathspec(c.Paths)
+		if err != nil {
+			return nil, fmt.Errorf("invalid paths for scope %q: %w", c.Scope, err)
+		}
+		rules = append(rules, ScopeRule{Scope: c.Scope, Paths: paths})
+	}
+	return rules, nil
+}
+
+// InferScopes returns the scopes a change touches, the scope of the most files first.
+// With rules, a file takes the scope of the first rule matching it and files matching
+// none have no scope. Without rules, Go files are scoped by their package directory and
+// other files by their top-level directory.
+func InferScopes(fileDiffs []FileDiff, rules []ScopeRule) []string {
+	counts := make(map[string]int)
+	for _, fileDiff := range fileDiffs {
+		if scope := fileScope(fileDiff.Change.Path(), rules); scope != "" {
+			counts[scope]++
+		}
+	}
+
+	scopes := make([]string, 0, len(counts))
+	for scope := range counts {
+		scopes = append(scopes, scope)
+	}
+	sort.Slice(scopes, func(i, j int) bool {
+		if counts[scopes[i]] != counts[scopes[j]] {
+			return counts[scopes[i]] > counts[scopes[j]]
+		}
+		return scopes[i] < scopes[j]
+	})
+	return scopes
+}
+
+// fileScope returns the scope of a single file, or "".
+func fileScope(filePath string, rules []ScopeRule) string {
+	if len(rules) > 0 {
+		for _, rule := range rules {
+			if rule.Paths.Match(filePath) {
+				return rule.Scope
+			}
+		}
+		return ""
+	}
+
+	dir := path.Dir(filePath)
+	if dir == "." {
+		return ""
+	}
+
+	scope := strings.Split(dir, "/")[0]
+	if path.Ext(filePath) == ".go" {
+		scope = path.Base(dir)
+	}
+	if strings.HasPrefix(scope, ".") || unscopedDirs[scope] {
+		return ""
+	}
+	return scope
+}
+
+// EnforceScope makes the scope of a conventional commit message one of scopes, replacing
+// a scope the model invented with the first of them, or removing it when the change has
+// no scope. Messages that are not conventional commits are returned unchanged.
+func EnforceScope(message string, scopes []string) string {
+	header, rest, multiline := strings.Cut(message, "\n")
+	match := conventionalHeaderPattern.FindStringSubmatch(header)
+	if match == nil {
+		return message
+	}
+
+	commitType, scope, breaking, description := match[1], match[2], match[3], match[4]
+	valid := scope != "" || len(scopes) == 0
+	for _, part := range strings.Split(scope, ",") {
+		valid = valid && (scope == "" || slices.Contains(scopes, strings.TrimSpace(part)))
+	}
+	if valid {
+		return message
+	}
+
+	header = commitType
+	if len(scopes) > 0 {
+		header += "(" + scopes[0] + ")"
+	}
+	header += breaking + ": " + description
+
+	if multiline {
+		return header + "\n" + rest
+	}
+	return header
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestInferScopes(t *testing.T) {
+	paths := func(paths ...string) []FileDiff {
+		fileDiffs := make([]FileDiff, len(paths))
+		for i, p := range paths {
+			fileDiffs[i] = offlineFileDiff(ChangeModify, p, p, "")
+		}
+		return fileDiffs
+	}
+
+	tests := []struct {
+		name      string
+		fileDiffs []FileDiff
+		expected  []string
+	}{
+		{name: "go package", fileDiffs: paths("app/api.go", "app/api_test.go"), expected: []string{"app"}},
+		{name: "nested go package", fileDiffs: paths("internal/billing/tax.go"), expected: []string{"billing"}},
+		{name: "top-level directory", fileDiffs: paths("app/templates/system_prompt.md"), expected: []string{"app"}},
+		{name: "most files first", fileDiffs: paths("web/a.ts", "app/api.go", "web/b.ts", "README.md"), expected: []string{"web", "app"}},
+		{name: "generic directories", fileDiffs: paths("docs/usage.md", ".github/workflows/ci.yml", "go.mod"), expected: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if scopes := InferScopes(test.fileDiffs, nil); !reflect.DeepEqual(scopes, test.expected) {
+				t.Errorf("InferScopes() = %v, expected %v", scopes, test.expected)
+			}
+		})
+	}
+}
+
+func TestScopeRulesFromConfig(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("scopes", []map[string]any{
+		{"scope": "viewer", "paths": []string{"app/diff_viewer.go"}},
+		{"scope": "llm", "paths": []string{"app/api.go", "app/templates"}},
+	})
+
+	rules, err := ScopeRulesFromConfig()
+	if err != nil {
+		t.Fatalf("ScopeRulesFromConfig() error: %v", err)
+	}
+
+	fileDiffs := []FileDiff{
+		offlineFileDiff(ChangeModify, "app/api.go", "app/api.go", ""),
+		offlineFileDiff(ChangeModify, "app/templates/system_prompt.md", "app/templates/system_prompt.md", ""),
+		offlineFileDiff(ChangeModify, "app/diff_viewer.go", "app/diff_viewer.go", ""),
+		offlineFileDiff(ChangeModify, "app/git.go", "app/git.go", ""),
+	}
+	if scopes := InferScopes(fileDiffs, rules); !reflect.DeepEqual(scopes, []string{"llm", "viewer"}) {
+		t.Errorf("InferScopes() = %v, expected [llm viewer]", scopes)
+	}
+
+	viper.Set("scopes", []map[string]any{{"scope": "empty"}})
+	if _, err := ScopeRulesFromConfig(); err == nil || !strings.Contains(err.Error(), "need a scope and paths") {
+		t.Errorf("Expected an error for a rule without paths, got %v", err)
+	}
+}
+
+func TestEnforceScope(t *testing.T) {
+	tests := []struct {
+		message  string
+		scopes   []string
+		expected string
+	}{
+		{message: "feat(app): add offline mode", scopes: []string{"app"}, expected: "feat(app): add offline mode"},
+		{message: "feat(ui): add offline mode", scopes: []string{"app"}, expected: "feat(app): add offline mode"},
+		{message: "feat: add offline mode", scopes: []string{"app", "web"}, expected: "feat(app): add offline mode"},
+		{message: "feat(web,app): share types", scopes: []string{"app", "web"}, expected: "feat(web,app): share types"},
+		{message: "docs(readme)!: drop v1\n\nBody", scopes: nil, expected: "docs!: drop v1\n\nBody"},
+		{message: "Add offline mode", scopes: []string{"app"}, expected: "Add offline mode"},
+	}
+
+	for _, test := range tests {
+		if result := EnforceScope(test.message, test.scopes); result != test.expected {
+			t.Errorf("EnforceScope(%q, %v) = %q, expected %q", test.message, test.scopes, result, test.expected)
+		}
+	}
+}
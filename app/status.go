@@ -0,0 +1,83 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// FileStatus describes how a file in the working copy differs from HEAD and the index.
+type FileStatus struct {
+	Path string
+	// Staged reports changes between HEAD and the index.
+	Staged bool
+	// Unstaged reports changes between the index and the working tree.
+	Unstaged bool
+	// Untracked reports a file that is neither in the index nor ignored.
+	Untracked bool
+}
+
+// InDiff reports whether the file belongs in a working copy comparison. Untracked
+// files appear as unstaged additions when includeUntracked is set.
+func (s FileStatus) InDiff(mode WorkingDiffMode, includeUntracked bool) bool {
+	unstaged := s.Unstaged || (s.Untracked && includeUntracked)
+
+	switch mode {
+	case DiffStaged:
+		return s.Staged
+	case DiffUnstaged:
+		return unstaged
+	case DiffUncommitted:
+		return s.Staged || unstaged
+	default:
+		return false
+	}
+}
+
+// GetStatus returns the status of every changed or untracked file, sorted by path.
+// Files matched by .gitignore are not reported.
+func (g *GitRepo) GetStatus() ([]FileStatus, error) {
+	worktree, err := g.repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+
+	changed := func(code git.StatusCode) bool {
+		return code != git.Untracked && code != git.Unmodified
+	}
+
+	var files []FileStatus
+	for path, fileStatus := range status {
+		file := FileStatus{
+			Path:      path,
+			Staged:    changed(fileStatus.Staging),
+			Unstaged:  changed(fileStatus.Worktree),
+			Untracked: fileStatus.Worktree == git.Untracked,
+		}
+		if file.Staged || file.Unstaged || file.Untracked {
+			files = append(files, file)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	return files, nil
+}
+
+// IncludeUntracked reports whether untracked files are shown as unstaged additions.
+func (g *GitRepo) IncludeUntracked() bool {
+	return g.includeUntracked
+}
+
+// SetIncludeUntracked controls whether untracked files are shown as unstaged additions.
+func (g *GitRepo) SetIncludeUntracked(include bool) {
+	g.includeUntracked = include
+}
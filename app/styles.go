@@ -0,0 +1,233 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/spf13/viper"
+)
+
+// Commit message styles selectable with the style setting.
+const (
+	StyleConventional = "conventional"
+	StyleAngular      = "angular"
+	StyleGitmoji      = "gitmoji"
+	StyleKernel       = "kernel"
+	StylePlain        = "plain"
+)
+
+// DefaultStyle is the commit message style when the style setting is empty.
+const DefaultStyle = StyleConventional
+
+// Subject cases required by [LintRules.SubjectCase].
+const (
+	SubjectLowerCase = "lower"
+	SubjectUpperCase = "upper"
+)
+
+// AngularCommitTypes are the types of the Angular commit message guidelines.
+var AngularCommitTypes = []string{"build", "ci", "docs", "feat", "fix", "perf", "refactor", "test"}
+
+// gitmojis are the emojis of the gitmoji convention for each conventional commit type.
+var gitmojis = map[string]string{
+	"feat": "✨", "fix": "🐛", "docs": "📝", "style": "🎨", "refactor": "♻️", "perf": "⚡️",
+	"test": "✅", "build": "📦️", "ci": "👷", "chore": "🔧", "revert": "⏪️",
+}
+
+// Header patterns of the styles that are not conventional commits. The subject group is
+// the description checked for case, mood and a trailing period.
+var (
+	gitmojiHeaderPattern = regexp.MustCompile(`^(?:[\x{1F000}-\x{1FAFF}\x{2300}-\x{23FF}\x{2600}-\x{27BF}\x{2B00}-\x{2BFF}]\x{FE0F}?|:[a-z0-9_+-]+:) (?P<subject>\S.*)$`)
+	kernelHeaderPattern  = regexp.MustCompile(`^[\w./-]+(?:: [\w./-]+)*: (?P<subject>\S.*)$`)
+	plainHeaderPattern   = regexp.MustCompile(`^(?P<subject>[^\s:]+(?:\s.*)?)$`)
+)
+
+// Style is a commit message convention: the instructions the model gets, the rules
+// generated and existing messages are checked against, and example messages.
+type Style struct {
+	Name string
+	// Header describes the header to the model, with examples.
+	Header string
+	// Instructions are additional guidelines for the model.
+	Instructions []string
+	// Examples are commit messages in the style, shown to the model when the history of
+	// the repository has none, see [GitRepo.ExamplesFromConfig].
+	Examples []string
+
+	// Conventional headers look like "type(scope): description", with one of Types.
+	Conventional bool
+	Types        []string
+	// HeaderPattern and HeaderFormat check headers that are not conventional commits,
+	// see [LintRules].
+	HeaderPattern *regexp.Regexp
+	HeaderFormat  string
+	SubjectCase   string
+	// Scoped styles name what a change touches in the header, as a scope or subsystem.
+	Scoped bool
+	// SignOff adds a Signed-off-by trailer unless signoff is turned off.
+	SignOff bool
+
+	// fromConventional rewrites the parts of a conventional header in the style, or is
+	// nil for conventional styles.
+	fromConventional func(commitType, scope, description string) string
+}
+
+// Styles are the commit message styles gitguy knows, the default first.
+var Styles = []Style{
+	{
+		Name:         StyleConventional,
+		Header:       `a conventional commit header, "type(scope): description" (e.g., "feat: add user authentication", "fix(parser): resolve memory leak")`,
+		Conventional: true,
+		Types:        DefaultCommitTypes,
+		Scoped:       true,
+		Examples: []string{
+			"feat(parser): accept tabs in indentation\n\nTabs were rejected as invalid indentation, although editors insert them\nby default.",
+			"fix: stop crashing on an empty config file",
+		},
+	},
+	{
+		Name:         StyleAngular,
+		Header:       `an Angular commit header, "type(scope): description" (e.g., "feat(forms): add a reset method to form groups", "fix(router): keep query params on redirect")`,
+		Instructions: []string{"Use only the types " + strings.Join(AngularCommitTypes, ", "), "Start the description with a lowercase letter"},
+		Conventional: true,
+		Types:        AngularCommitTypes,
+		SubjectCase:  SubjectLowerCase,
+		Scoped:       true,
+		Examples: []string{
+			"fix(router): keep query params on redirect\n\nRedirects built a new URL from the path alone, dropping the query params\nof the original navigation.",
+			"feat(forms): add a reset method to form groups",
+		},
+	},
+	{
+		Name:   StyleGitmoji,
+		Header: `a gitmoji header, an emoji followed by a description (e.g., "✨ Add CSV export for invoices", "🐛 Fix crash when the config is empty")`,
+		Instructions: []string{
+			"Start the header with the gitmoji for the intent of the change: ✨ feature, 🐛 bug fix, 📝 documentation, ♻️ refactoring, ⚡️ performance, ✅ tests, 🎨 structure or format, 🔧 configuration, 📦️ build, 👷 CI, 🔥 removed code",
+			"Capitalize the first word after the emoji",
+		},
+		HeaderPattern: gitmojiHeaderPattern,
+		HeaderFormat:  `"<emoji> Description"`,
+		SubjectCase:   SubjectUpperCase,
+		Examples: []string{
+			"✨ Add CSV export for invoices",
+			"🐛 Fix crash when the config is empty\n\nAn empty file decoded to a nil map, which the defaults were then merged\ninto.",
+		},
+		fromConventional: func(commitType, _, description string) string {
+			emoji, ok := gitmojis[commitType]
+			if !ok {
+				emoji = gitmojis["chore"]
+			}
+			return emoji + " " + capitalize(description)
+		},
+	},
+	{
+		Name:   StyleKernel,
+		Header: `a Linux kernel style header, "subsystem: summary" (e.g., "net: ipv4: fix use-after-free in route cache", "docs: describe the tracing options")`,
+		Instructions: []string{
+			"Prefix the summary with the subsystem the change touches, such as the directory, driver or component, and nested subsystems with more colons",
+			"Explain the problem and why the change solves it in the body",
+		},
+		HeaderPattern: kernelHeaderPattern,
+		HeaderFormat:  `"subsystem: summary"`,
+		Scoped:        true,
+		SignOff:       true,
+		Examples: []string{
+			"net: ipv4: fix use-after-free in route cache\n\nThe cache entry was freed before the last reader dropped its reference.\nTake the lock before releasing it.",
+			"docs: describe the tracing options",
+		},
+		fromConventional: func(commitType, scope, description string) string {
+			if scope == "" {
+				scope = commitType
+			}
+			return scope + ": " + description
+		},
+	},
+	{
+		Name:          StylePlain,
+		Header:        `a plain sentence (e.g., "Add CSV export for invoices", "Fix crash when the config is empty")`,
+		Instructions:  []string{"Start the header with a capital letter, without a type, scope or emoji prefix"},
+		HeaderPattern: plainHeaderPattern,
+		HeaderFormat:  `a sentence such as "Fix crash on start"`,
+		SubjectCase:   SubjectUpperCase,
+		Examples: []string{
+			"Add CSV export for invoices",
+			"Fix crash when the config is empty\n\nAn empty file decoded to a nil map, which the defaults were then merged\ninto.",
+		},
+		fromConventional: func(_, _, description string) string {
+			return capitalize(description)
+		},
+	},
+}
+
+// StyleNames returns the names of the [Styles].
+func StyleNames() []string {
+	names := make([]string, len(Styles))
+	for i, style := range Styles {
+		names[i] = style.Name
+	}
+	return names
+}
+
+// LookupStyle returns the style with a name, or the [DefaultStyle] for an empty name.
+func LookupStyle(name string) (Style, error) {
+	if name == "" {
+		name = DefaultStyle
+	}
+	for _, style := range Styles {
+		if style.Name == name {
+			return style, nil
+		}
+	}
+	return Style{}, fmt.Errorf("unknown style %q, expected one of %s", name, strings.Join(StyleNames(), ", "))
+}
+
+// StyleFromConfig returns the style of the style setting, or else of the gitguy.style git
+// config, which selects the style of a single repository with
+// "git config gitguy.style kernel".
+func (g *GitRepo) StyleFromConfig() (Style, error) {
+	name := viper.GetString("style")
+	if name == "" {
+		cfg, err := g.repo.ConfigScoped(config.SystemScope)
+		if err != nil {
+			return Style{}, fmt.Errorf("failed to read git config: %w", err)
+		}
+		name = cfg.Raw.Section("gitguy").Option("style")
+	}
+	return LookupStyle(name)
+}
+
+// LintRules returns the rules commit messages in the style follow.
+func (s Style) LintRules() LintRules {
+	return LintRules{
+		Conventional:    s.Conventional,
+		MaxHeaderLength: maxSubjectLength,
+		Imperative:      true,
+		Types:           s.Types,
+		HeaderPattern:   s.HeaderPattern,
+		HeaderFormat:    s.HeaderFormat,
+		SubjectCase:     s.SubjectCase,
+	}
+}
+
+// FromConventional rewrites a conventional commit header in the style, such as the
+// header of a commit message generated offline. Other headers are returned unchanged.
+func (s Style) FromConventional(header string) string {
+	match := conventionalHeaderPattern.FindStringSubmatch(header)
+	if s.fromConventional == nil || match == nil {
+		return header
+	}
+	return s.fromConventional(match[1], match[2], match[4])
+}
+
+// capitalize upper-cases the first letter of text.
+func capitalize(text string) string {
+	if text == "" {
+		return text
+	}
+	r, size := utf8.DecodeRuneInString(text)
+	return string(unicode.ToUpper(r)) + text[size:]
+}
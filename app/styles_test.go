@@ -0,0 +1,182 @@
+package app
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestStyleLint(t *testing.T) {
+	tests := []struct {
+		style    string
+		message  string
+		expected []string
+	}{
+		{style: StyleAngular, message: "fix(router): keep query params", expected: nil},
+		{style: StyleAngular, message: "chore: bump deps", expected: []string{"type-enum"}},
+		{style: StyleAngular, message: "fix: Keep query params", expected: []string{"subject-case"}},
+		{style: StyleGitmoji, message: "✨ Add CSV export", expected: nil},
+		{style: StyleGitmoji, message: "♻️ Extract the parser", expected: nil},
+		{style: StyleGitmoji, message: ":bug: Fix crash on start", expected: nil},
+		{style: StyleGitmoji, message: "✨ add CSV export", expected: []string{"subject-case"}},
+		{style: StyleGitmoji, message: "feat: add CSV export", expected: []string{"header-format"}},
+		{style: StyleGitmoji, message: "🐛 Fixed crash", expected: []string{"subject-mood"}},
+		{style: StyleKernel, message: "net: ipv4: fix use-after-free", expected: nil},
+		{style: StyleKernel, message: "fix use-after-free", expected: []string{"header-format"}},
+		{style: StyleKernel, message: "docs: describe tracing.", expected: []string{"subject-full-stop"}},
+		{style: StylePlain, message: "Add CSV export", expected: nil},
+		{style: StylePlain, message: "feat: add CSV export", expected: []string{"header-format"}},
+		{style: StylePlain, message: "add CSV export", expected: []string{"subject-case"}},
+	}
+
+	for _, test := range tests {
+		style, err := LookupStyle(test.style)
+		if err != nil {
+			t.Fatalf("LookupStyle(%q) error: %v", test.style, err)
+		}
+		var violated []string
+		for _, violation := range style.LintRules().Lint(test.message) {
+			violated = append(violated, violation.Rule)
+		}
+		if !reflect.DeepEqual(violated, test.expected) {
+			t.Errorf("%s: Lint(%q) = %v, expected %v", test.style, test.message, violated, test.expected)
+		}
+	}
+
+	// Every style accepts its own examples.
+	for _, style := range Styles {
+		for _, example := range style.Examples {
+			if violations := style.LintRules().Lint(example); len(violations) > 0 {
+				t.Errorf("%s: example %q breaks %v", style.Name, example, violations)
+			}
+		}
+	}
+}
+
+func TestStyleFromConventional(t *testing.T) {
+	tests := []struct {
+		style    string
+		header   string
+		expected string
+	}{
+		{style: StyleConventional, header: "feat(app): add export", expected: "feat(app): add export"},
+		{style: StyleGitmoji, header: "fix(app): stop crashing", expected: "🐛 Stop crashing"},
+		{style: StyleGitmoji, header: "wip: try things", expected: "🔧 Try things"},
+		{style: StyleKernel, header: "fix(net): stop crashing", expected: "net: stop crashing"},
+		{style: StyleKernel, header: "docs: describe options", expected: "docs: describe options"},
+		{style: StylePlain, header: "feat!: drop v1", expected: "Drop v1"},
+		{style: StylePlain, header: "Already plain", expected: "Already plain"},
+	}
+
+	for _, test := range tests {
+		style, err := LookupStyle(test.style)
+		if err != nil {
+			t.Fatalf("LookupStyle(%q) error: %v", test.style, err)
+		}
+		if actual := style.FromConventional(test.header); actual != test.expected {
+			t.Errorf("%s: FromConventional(%q) = %q, expected %q", test.style, test.header, actual, test.expected)
+		}
+	}
+}
+
+func TestStyleFromConfig(t *testing.T) {
+	defer viper.Reset()
+	tr := newTestRepo(t)
+	tr.commitFile("README.md", "hello\n", "Initial commit", "Ada")
+	cfg, err := tr.repo.Config()
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	cfg.User.Name, cfg.User.Email = "Grace Hopper", "grace@example.com"
+	if err := tr.repo.SetConfig(cfg); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	repo := tr.gitRepo()
+
+	if style, err := repo.StyleFromConfig(); err != nil || style.Name != DefaultStyle {
+		t.Errorf("Expected the default style, got %q (%v)", style.Name, err)
+	}
+
+	cfg.Raw.Section("gitguy").SetOption("style", StylePlain)
+	if err := tr.repo.SetConfig(cfg); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if style, err := repo.StyleFromConfig(); err != nil || style.Name != StylePlain {
+		t.Errorf("Expected the style of the git config, got %q (%v)", style.Name, err)
+	}
+
+	viper.Set("style", "emoji")
+	if _, err := repo.StyleFromConfig(); err == nil || !strings.Contains(err.Error(), "gitmoji") {
+		t.Errorf("Expected an error listing the styles, got %v", err)
+	}
+	if _, err := repo.LintRulesFromConfig(); err == nil {
+		t.Error("Expected LintRulesFromConfig() to fail for an unknown style")
+	}
+
+	viper.Set("style", StyleKernel)
+	trailers, err := repo.TrailersFromConfig()
+	if err != nil {
+		t.Fatalf("TrailersFromConfig() error: %v", err)
+	}
+	if len(trailers) != 1 || trailers[0].Key != TrailerSignedOffBy {
+		t.Errorf("Expected the kernel style to sign off, got %v", trailers)
+	}
+	viper.Set("signoff", false)
+	if trailers, err := repo.TrailersFromConfig(); err != nil || len(trailers) != 0 {
+		t.Errorf("Expected signoff to turn off the sign-off, got %v, %v", trailers, err)
+	}
+
+	// The history has no kernel style message, so the examples of the style are shown.
+	style, err := repo.StyleFromConfig()
+	if err != nil {
+		t.Fatalf("StyleFromConfig() error: %v", err)
+	}
+	examples, err := repo.ExamplesFromConfig(style, style.LintRules(), "")
+	if err != nil {
+		t.Fatalf("ExamplesFromConfig() error: %v", err)
+	}
+	if len(examples.Commits) != 0 || !reflect.DeepEqual(examples.Style, style.Examples) {
+		t.Errorf("Expected only the style examples, got %q and %q", examples.Commits, examples.Style)
+	}
+}
+
+func TestRenderStylePrompt(t *testing.T) {
+	pctx := PromptContext{Style: StyleGitmoji, Scopes: []string{"app"}, Types: DefaultCommitTypes}
+	system, user, err := (*PromptTemplates)(nil).Render("+x\n", pctx)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+
+	if !strings.Contains(system, "follow the gitmoji format") || !strings.Contains(system, "- Capitalize the first word after the emoji") {
+		t.Errorf("Expected the gitmoji instructions, got:\n%s", system)
+	}
+	if strings.Contains(system, `mark the header with "!"`) {
+		t.Errorf("Expected no conventional breaking change marker, got:\n%s", system)
+	}
+	if strings.Contains(user, "scope") || strings.Contains(user, "commit types") {
+		t.Errorf("Expected no scopes or types for gitmoji, got:\n%s", user)
+	}
+
+	pctx.Style = StyleKernel
+	if _, user, err = (*PromptTemplates)(nil).Render("+x\n", pctx); err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if !strings.Contains(user, `The change touches the "app" subsystem.`) {
+		t.Errorf("Expected the subsystem, got:\n%s", user)
+	}
+
+	pctx.Examples = Examples{Style: []string{"docs: describe the tracing options"}}
+	if _, user, err = (*PromptTemplates)(nil).Render("+x\n", pctx); err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if !strings.Contains(user, "illustrate the kernel style. They are not from this repository") || strings.Contains(user, "commit messages of this repository") {
+		t.Errorf("Expected the style examples in their own section, got:\n%s", user)
+	}
+
+	pctx.Style = "emoji"
+	if _, _, err := (*PromptTemplates)(nil).Render("+x\n", pctx); err == nil {
+		t.Error("Expected an error for an unknown style")
+	}
+}
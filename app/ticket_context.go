@@ -0,0 +1,309 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/viper"
+)
+
+// Ticket providers selectable with ticket-provider.
+const (
+	TicketProviderMarkdown = "markdown"
+	TicketProviderJira     = "jira"
+	TicketProviderGitHub   = "github"
+)
+
+// DefaultTicketDir is where the markdown provider looks for tickets, relative to the
+// repository root, when ticket-dir is not configured.
+const DefaultTicketDir = ".gitguy/tickets"
+
+// defaultGitHubURL is the GitHub REST API, overridden by github-url for GitHub Enterprise.
+const defaultGitHubURL = "https://api.github.com"
+
+// maxTicketDescription caps the ticket description sent to the model, in characters.
+const maxTicketDescription = 4000
+
+// ticketRequestTimeout bounds the requests to ticket trackers, so that a slow tracker
+// does not hold up generating a commit message.
+const ticketRequestTimeout = 10 * time.Second
+
+// ErrTicketNotFound is returned by a [TicketProvider] that has no such ticket.
+var ErrTicketNotFound = errors.New("ticket not found")
+
+// githubRemotePattern matches the owner and name of a repository in a GitHub remote URL,
+// in HTTPS or SSH form.
+var githubRemotePattern = regexp.MustCompile(`github\.com[:/]([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+// TicketDetails is the requirement a ticket describes.
+type TicketDetails struct {
+	ID          string
+	Title       string
+	Description string
+	// URL links to the ticket, if the provider knows it.
+	URL string
+}
+
+// TicketProvider fetches the details of a ticket by its ID, e.g. "PROJ-123" or "#456".
+type TicketProvider interface {
+	FetchTicket(id string) (*TicketDetails, error)
+}
+
+// MarkdownTicketProvider reads tickets from "<ID>.md" files in a directory, such as
+// PROJ-123.md or 456.md for issue #456. The first "# " heading is the title.
+type MarkdownTicketProvider struct {
+	FS  billy.Filesystem
+	Dir string
+}
+
+// FetchTicket reads the markdown file of a ticket.
+func (p *MarkdownTicketProvider) FetchTicket(id string) (*TicketDetails, error) {
+	name := strings.TrimPrefix(id, "#")
+	for _, candidate := range []string{name, strings.ToLower(name)} {
+		content, err := util.ReadFile(p.FS, path.Join(p.Dir, candidate+".md"))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ticket %s: %w", id, err)
+		}
+		return parseMarkdownTicket(id, string(content)), nil
+	}
+	return nil, fmt.Errorf("%w: no %s.md in %s", ErrTicketNotFound, name, p.Dir)
+}
+
+// parseMarkdownTicket splits a markdown ticket into its first "# " heading and the rest.
+func parseMarkdownTicket(id, content string) *TicketDetails {
+	details := &TicketDetails{ID: id, Title: id}
+
+	var description []string
+	titled := false
+	for _, line := range strings.Split(content, "\n") {
+		if !titled && strings.HasPrefix(line, "# ") {
+			details.Title = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+			titled = true
+			continue
+		}
+		description = append(description, line)
+	}
+	details.Description = strings.TrimSpace(strings.Join(description, "\n"))
+	return details
+}
+
+// JiraTicketProvider fetches issues from the REST API v2 of Jira or a compatible tracker.
+// With an email the token is sent with basic auth, as Jira Cloud expects, otherwise as
+// a bearer token, as Jira Data Center personal access tokens are.
+type JiraTicketProvider struct {
+	BaseURL string
+	Email   string
+	Token   string
+	Client  *http.Client
+}
+
+// jiraIssue is the part of a Jira issue that describes it.
+type jiraIssue struct {
+	Fields struct {
+		Summary     string `json:"summary"`
+		Description string `json:"description"`
+	} `json:"fields"`
+}
+
+// FetchTicket fetches the summary and description of a Jira issue.
+func (p *JiraTicketProvider) FetchTicket(id string) (*TicketDetails, error) {
+	endpoint := strings.TrimRight(p.BaseURL, "/") + "/rest/api/2/issue/" + url.PathEscape(id) + "?fields=summary,description"
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	switch {
+	case p.Email != "":
+		req.SetBasicAuth(p.Email, p.Token)
+	case p.Token != "":
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+
+	var issue jiraIssue
+	if err := fetchJSON(p.Client, req, &issue); err != nil {
+		return nil, fmt.Errorf("failed to fetch Jira issue %s: %w", id, err)
+	}
+	return &TicketDetails{
+		ID:          id,
+		Title:       issue.Fields.Summary,
+		Description: issue.Fields.Description,
+		URL:         strings.TrimRight(p.BaseURL, "/") + "/browse/" + url.PathEscape(id),
+	}, nil
+}
+
+// GitHubTicketProvider fetches issues of a repository from the GitHub REST API.
+type GitHubTicketProvider struct {
+	BaseURL string
+	// Repo is the repository as "owner/name".
+	Repo   string
+	Token  string
+	Client *http.Client
+}
+
+// githubIssue is the part of a GitHub issue that describes it.
+type githubIssue struct {
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+}
+
+// FetchTicket fetches the title and body of a GitHub issue. Only issue numbers such as
+// "#456" are GitHub issues.
+func (p *GitHubTicketProvider) FetchTicket(id string) (*TicketDetails, error) {
+	number := strings.TrimPrefix(id, "#")
+	if number == id || strings.Trim(number, "0123456789") != "" {
+		return nil, fmt.Errorf("%w: %s is not a GitHub issue number", ErrTicketNotFound, id)
+	}
+
+	endpoint := strings.TrimRight(p.BaseURL, "/") + "/repos/" + p.Repo + "/issues/" + number
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+
+	var issue githubIssue
+	if err := fetchJSON(p.Client, req, &issue); err != nil {
+		return nil, fmt.Errorf("failed to fetch GitHub issue %s: %w", id, err)
+	}
+	return &TicketDetails{ID: id, Title: issue.Title, Description: issue.Body, URL: issue.HTMLURL}, nil
+}
+
+// fetchJSON sends a request and decodes its JSON response. A 404 is [ErrTicketNotFound].
+func fetchJSON(client *http.Client, req *http.Request, v any) error {
+	if client == nil {
+		client = &http.Client{Timeout: ticketRequestTimeout}
+	}
+	req.Header.Set("User-Agent", "gitguy")
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrTicketNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// TicketProviderFromConfig returns the provider selected by ticket-provider, or nil when
+// none is configured:
+//   - markdown reads ticket-dir, [DefaultTicketDir] by default, in the repository
+//   - jira needs jira-url, and takes jira-email and jira-token or JIRA_API_TOKEN
+//   - github takes github-url, github-repo, inferred from the origin remote by default,
+//     and github-token or GITHUB_TOKEN
+func (g *GitRepo) TicketProviderFromConfig() (TicketProvider, error) {
+	switch provider := viper.GetString("ticket-provider"); provider {
+	case "":
+		return nil, nil
+
+	case TicketProviderMarkdown:
+		worktree, err := g.repo.Worktree()
+		if errors.Is(err, git.ErrIsBareRepository) {
+			// There are no ticket files without a working tree
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get worktree: %w", err)
+		}
+		dir := viper.GetString("ticket-dir")
+		if dir == "" {
+			dir = DefaultTicketDir
+		}
+		return &MarkdownTicketProvider{FS: worktree.Filesystem, Dir: dir}, nil
+
+	case TicketProviderJira:
+		baseURL := viper.GetString("jira-url")
+		if baseURL == "" {
+			return nil, fmt.Errorf("the jira ticket provider needs jira-url")
+		}
+		return &JiraTicketProvider{
+			BaseURL: baseURL,
+			Email:   viper.GetString("jira-email"),
+			Token:   configOrEnv("jira-token", "JIRA_API_TOKEN"),
+		}, nil
+
+	case TicketProviderGitHub:
+		baseURL := viper.GetString("github-url")
+		if baseURL == "" {
+			baseURL = defaultGitHubURL
+		}
+		repo := viper.GetString("github-repo")
+		if repo == "" {
+			repo = g.githubRepo()
+		}
+		if repo == "" {
+			return nil, fmt.Errorf("the github ticket provider needs github-repo, as the origin remote is not on GitHub")
+		}
+		return &GitHubTicketProvider{BaseURL: baseURL, Repo: repo, Token: configOrEnv("github-token", "GITHUB_TOKEN")}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown ticket-provider %q, expected markdown, jira or github", provider)
+	}
+}
+
+// configOrEnv returns a setting, or the environment variable when it is not set.
+func configOrEnv(key, env string) string {
+	if value := viper.GetString(key); value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
+
+// githubRepo returns "owner/name" of the origin remote, if it is hosted on GitHub.
+func (g *GitRepo) githubRepo() string {
+	remote, err := g.repo.Remote("origin")
+	if err != nil {
+		return ""
+	}
+	for _, remoteURL := range remote.Config().URLs {
+		if match := githubRemotePattern.FindStringSubmatch(remoteURL); match != nil {
+			return match[1] + "/" + match[2]
+		}
+	}
+	return ""
+}
+
+// FetchTicketContext fetches the details of a ticket, with its description shortened to
+// what is worth sending to the model.
+func FetchTicketContext(provider TicketProvider, ticket *Ticket) (*TicketDetails, error) {
+	details, err := provider.FetchTicket(ticket.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len([]rune(details.Description)) > maxTicketDescription {
+		details.Description = string([]rune(details.Description)[:maxTicketDescription]) + "\n[truncated]"
+	}
+	return details, nil
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/spf13/viper"
+)
+
+// Where the ticket of a branch is referenced in the commit message, from ticket-reference.
+const (
+	TicketInTrailer = "trailer"
+	TicketInSubject = "subject"
+	TicketNowhere   = "none"
+)
+
+// DefaultTicketPatterns match Jira-style keys such as PROJ-123 at the start of a branch
+// name segment, and GitHub issue numbers such as #456, in branch names.
+var DefaultTicketPatterns = []string{`(?:^|/)([A-Z][A-Z0-9]+-[0-9]+)\b`, `#[0-9]+\b`}
+
+// standardNames are names of standards and protocols that look like Jira keys, e.g. in
+// "fix/UTF-8-decoding", and are never taken for tickets.
+var standardNames = []string{"UTF", "UCS", "HTTP", "ISO", "RFC", "SHA", "MD", "TLS", "SSL", "IPV", "X", "ES", "ECMA"}
+
+// Ticket is the issue or ticket a branch was created for.
+type Ticket struct {
+	// ID is the ticket as written in the branch name, e.g. "PROJ-123" or "#456".
+	ID string
+	// Branch is the branch name the ticket was found in.
+	Branch string
+}
+
+// IsIssue reports whether the ticket is an issue number of the hosting service, which a
+// PR closes with "Closes #456".
+func (t Ticket) IsIssue() bool {
+	return strings.HasPrefix(t.ID, "#")
+}
+
+// TicketPatternsFromConfig compiles the ticket-patterns setting, or [DefaultTicketPatterns].
+// A pattern with a capture group uses the first group as ticket ID, e.g. `issue-([0-9]+)`
+// to turn "issue-456" into "#456" when the group is digits only.
+func TicketPatternsFromConfig() ([]*regexp.Regexp, error) {
+	sources := viper.GetStringSlice("ticket-patterns")
+	if len(sources) == 0 {
+		sources = DefaultTicketPatterns
+	}
+
+	patterns := make([]*regexp.Regexp, len(sources))
+	for i, source := range sources {
+		pattern, err := regexp.Compile(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ticket pattern %q: %w", source, err)
+		}
+		patterns[i] = pattern
+	}
+	return patterns, nil
+}
+
+// ParseTicket returns the ticket of the first pattern matching a branch name. Bare
+// numbers captured by a group are GitHub issues, e.g. "#456", and names of standards
+// such as UTF-8 are skipped.
+func ParseTicket(branch string, patterns []*regexp.Regexp) (*Ticket, bool) {
+	for _, pattern := range patterns {
+		for _, match := range pattern.FindAllStringSubmatch(branch, -1) {
+			id := match[0]
+			if len(match) > 1 && match[1] != "" {
+				id = match[1]
+				if strings.Trim(id, "0123456789") == "" {
+					id = "#" + id
+				}
+			}
+
+			key, _, _ := strings.Cut(id, "-")
+			if slices.Contains(standardNames, strings.ToUpper(key)) {
+				continue
+			}
+			return &Ticket{ID: id, Branch: branch}, true
+		}
+	}
+	return nil, false
+}
+
+// BranchName returns the branch a revision names: a local branch, a remote-tracking branch
+// without its remote, or the checked out branch for HEAD and the working copy pseudo-refs.
+// Other revisions, such as commits and tags, have no branch.
+func (g *GitRepo) BranchName(rev string) string {
+	if _, ok := ParseWorkingDiffMode(rev); ok || rev == "HEAD" {
+		head, err := g.repo.Head()
+		if err != nil || !head.Name().IsBranch() {
+			return ""
+		}
+		return head.Name().Short()
+	}
+
+	if _, err := g.repo.Reference(plumbing.NewBranchReferenceName(rev), false); err == nil {
+		return rev
+	}
+	if ref, err := g.repo.Reference(plumbing.ReferenceName("refs/remotes/"+rev), false); err == nil {
+		if _, branch, found := strings.Cut(ref.Name().Short(), "/"); found {
+			return branch
+		}
+	}
+	return ""
+}
+
+// BranchTicket returns the ticket of the first branch naming one, see [ParseTicket].
+func BranchTicket(branches ...string) (*Ticket, error) {
+	patterns, err := TicketPatternsFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, branch := range branches {
+		if branch == "" {
+			continue
+		}
+		if ticket, ok := ParseTicket(branch, patterns); ok {
+			return ticket, nil
+		}
+	}
+	return nil, nil
+}
+
+// TicketReference returns where the ticket is referenced in the commit message, from
+// ticket-reference: in a Refs trailer, by default, at the end of the subject, or nowhere.
+func TicketReference() (string, error) {
+	switch reference := viper.GetString("ticket-reference"); reference {
+	case "", TicketInTrailer:
+		return TicketInTrailer, nil
+	case TicketInSubject, TicketNowhere:
+		return reference, nil
+	default:
+		return "", fmt.Errorf("unknown ticket-reference %q, expected trailer, subject or none", reference)
+	}
+}
+
+// ApplyTicket references a ticket in a result. The subject gets a " (PROJ-123)" suffix
+// when reference is [TicketInSubject] and it fits in the header length, and a Refs
+// trailer otherwise. Issues are closed by the PR with a "Closes #456" line.
+func ApplyTicket(result *LLMResult, ticket *Ticket, reference string, maxHeaderLength int) {
+	if ticket == nil {
+		return
+	}
+	result.Ticket = ticket.ID
+
+	mentioned := mentionsTicket(result.CommitMessage, ticket.ID)
+	for _, trailer := range result.Trailers {
+		mentioned = mentioned || mentionsTicket(trailer.Value, ticket.ID)
+	}
+
+	switch {
+	case mentioned, reference == TicketNowhere:
+	case reference == TicketInSubject && (maxHeaderLength <= 0 || len([]rune(result.CommitMessage))+len(ticket.ID)+3 <= maxHeaderLength):
+		result.CommitMessage += " (" + ticket.ID + ")"
+	default:
+		// Keep the reference with the model's trailers, before co-authors and sign-offs
+		at := len(result.Trailers)
+		for i := len(result.Trailers) - 1; i >= 0; i-- {
+			if key := result.Trailers[i].Key; key == TrailerCoAuthoredBy || key == TrailerSignedOffBy {
+				at = i
+			}
+		}
+		result.Trailers = slices.Insert(result.Trailers, at, Trailer{Key: TrailerRefs, Value: ticket.ID})
+	}
+
+	closes := "Closes " + ticket.ID
+	if ticket.IsIssue() && !mentionsTicket(result.PRDescription, closes) {
+		result.PRDescription = strings.TrimRight(result.PRDescription, "\n") + "\n\n" + closes
+	}
+}
+
+// mentionsTicket reports whether text names a ticket as a whole word, so that "#45" is
+// not taken for mentioned in "#456".
+func mentionsTicket(text, id string) bool {
+	return regexp.MustCompile(`(?:^|[^\w#-])` + regexp.QuoteMeta(id) + `(?:$|\W)`).MatchString(text)
+}
@@ -0,0 +1,183 @@
+package app
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestParseTicket(t *testing.T) {
+	defaults := make([]*regexp.Regexp, len(DefaultTicketPatterns))
+	for i, pattern := range DefaultTicketPatterns {
+		defaults[i] = regexp.MustCompile(pattern)
+	}
+
+	tests := []struct {
+		branch   string
+		patterns []*regexp.Regexp
+		expected string
+	}{
+		{"feature/PROJ-123-foo", defaults, "PROJ-123"},
+		{"fix/#456", defaults, "#456"},
+		{"fix/#456-crash-on-start", defaults, "#456"},
+		{"PROJ-7/export", defaults, "PROJ-7"},
+		{"fix/UTF-8-decoding", defaults, ""},
+		{"feature/HTTP-2-support", defaults, ""},
+		{"feature/HTTP-2-PROJ-9", defaults, ""},
+		{"feature/utf-8/PROJ-9", defaults, "PROJ-9"},
+		{"feature/add-export", defaults, ""},
+		{"main", defaults, ""},
+		{"fix/issue-789-crash", []*regexp.Regexp{regexp.MustCompile(`issue-([0-9]+)`)}, "#789"},
+		{"team/abc-42-export", []*regexp.Regexp{regexp.MustCompile(`(?i)\b(abc-[0-9]+)`)}, "abc-42"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.branch, func(t *testing.T) {
+			ticket, ok := ParseTicket(test.branch, test.patterns)
+			if test.expected == "" {
+				if ok {
+					t.Errorf("Expected no ticket, got %q", ticket.ID)
+				}
+				return
+			}
+			if !ok || ticket.ID != test.expected || ticket.Branch != test.branch {
+				t.Errorf("ParseTicket(%q) = %v, expected %q", test.branch, ticket, test.expected)
+			}
+		})
+	}
+}
+
+func TestBranchName(t *testing.T) {
+	tr := newTestRepo(t)
+	base := tr.commitFile("a.txt", "a\n", "feat: start", "Ada")
+	tr.branch("feature/PROJ-7-export", base)
+	repo := tr.gitRepo()
+
+	tests := map[string]string{
+		"feature/PROJ-7-export": "feature/PROJ-7-export",
+		"master":                "master",
+		"HEAD":                  "feature/PROJ-7-export",
+		string(DiffStaged):      "feature/PROJ-7-export",
+		base.String():           "",
+		"does-not-exist":        "",
+	}
+	for rev, expected := range tests {
+		if got := repo.BranchName(rev); got != expected {
+			t.Errorf("BranchName(%q) = %q, expected %q", rev, got, expected)
+		}
+	}
+}
+
+func TestApplyTicket(t *testing.T) {
+	signoff := Trailer{TrailerSignedOffBy, "Ada <ada@example.com>"}
+
+	tests := []struct {
+		name        string
+		ticket      Ticket
+		reference   string
+		header      string
+		trailers    []Trailer
+		pr          string
+		expected    string
+		expectedPR  string
+		maxHeaderLn int
+	}{
+		{
+			name:      "trailer before sign-off",
+			ticket:    Ticket{ID: "PROJ-123"},
+			reference: TicketInTrailer,
+			header:    "feat: add export",
+			trailers:  []Trailer{{TrailerBreakingChange, "v1 is gone"}, signoff},
+			pr:        "## What changed",
+			expected:  "feat: add export\n\nBREAKING CHANGE: v1 is gone\nRefs: PROJ-123\nSigned-off-by: Ada <ada@example.com>",
+		},
+		{
+			name:      "subject",
+			ticket:    Ticket{ID: "PROJ-123"},
+			reference: TicketInSubject,
+			header:    "feat: add export",
+			expected:  "feat: add export (PROJ-123)",
+		},
+		{
+			name:        "subject too long falls back to the trailer",
+			ticket:      Ticket{ID: "PROJ-123"},
+			reference:   TicketInSubject,
+			header:      "feat: add export",
+			maxHeaderLn: 20,
+			expected:    "feat: add export\n\nRefs: PROJ-123",
+		},
+		{
+			name:      "already referenced",
+			ticket:    Ticket{ID: "PROJ-123"},
+			reference: TicketInTrailer,
+			header:    "feat: add export",
+			trailers:  []Trailer{{TrailerRefs, "PROJ-123"}},
+			expected:  "feat: add export\n\nRefs: PROJ-123",
+		},
+		{
+			name:       "longer issue number is not a mention",
+			ticket:     Ticket{ID: "#45"},
+			reference:  TicketInTrailer,
+			header:     "fix: stop crashing on start",
+			trailers:   []Trailer{{TrailerRefs, "#456"}},
+			pr:         "Closes #456",
+			expected:   "fix: stop crashing on start\n\nRefs: #456\nRefs: #45",
+			expectedPR: "Closes #456\n\nCloses #45",
+		},
+		{
+			name:       "issues are closed by the PR",
+			ticket:     Ticket{ID: "#456"},
+			reference:  TicketNowhere,
+			header:     "fix: stop crashing on start",
+			pr:         "## What changed\n",
+			expected:   "fix: stop crashing on start",
+			expectedPR: "## What changed\n\nCloses #456",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := &LLMResult{CommitMessage: test.header, Trailers: test.trailers, PRDescription: test.pr}
+			ApplyTicket(result, &test.ticket, test.reference, test.maxHeaderLn)
+
+			if got := result.FullCommitMessage(); got != test.expected {
+				t.Errorf("Commit message =\n%s\nexpected\n%s", got, test.expected)
+			}
+			if test.expectedPR != "" && result.PRDescription != test.expectedPR {
+				t.Errorf("PR description = %q, expected %q", result.PRDescription, test.expectedPR)
+			}
+			if result.Ticket != test.ticket.ID {
+				t.Errorf("Ticket = %q, expected %q", result.Ticket, test.ticket.ID)
+			}
+		})
+	}
+}
+
+func TestGenerateReferencesBranchTicket(t *testing.T) {
+	defer viper.Reset()
+	prompts := chatServer(t, "COMMIT: fix(app): stop crashing on start\n\nPR:\n## What changed\n- crash")
+
+	tr := newTestRepo(t)
+	fileDiffs := []FileDiff{offlineFileDiff(ChangeModify, "app/api.go", "app/api.go", "+fixed\n")}
+	prompt, err := tr.gitRepo().PreparePrompt(fileDiffs, nil, "fix/#456-crash", "")
+	if err != nil {
+		t.Fatalf("PreparePrompt() error: %v", err)
+	}
+
+	result, err := prompt.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	if !strings.Contains((*prompts)[0], `branch "fix/#456-crash" for ticket #456`) {
+		t.Errorf("Expected the prompt to name the branch and ticket, got:\n%s", (*prompts)[0])
+	}
+	if result.FullCommitMessage() != "fix(app): stop crashing on start\n\nRefs: #456" {
+		t.Errorf("Unexpected commit message %q", result.FullCommitMessage())
+	}
+	if !strings.HasSuffix(result.PRDescription, "\n\nCloses #456") {
+		t.Errorf("Expected the PR to close the issue, got %q", result.PRDescription)
+	}
+}
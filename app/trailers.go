@@ -0,0 +1,292 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/spf13/viper"
+)
+
+// bodyWidth is the column commit message bodies are wrapped at, the git convention.
+const bodyWidth = 72
+
+// Trailer keys gitguy understands.
+const (
+	TrailerBreakingChange = "BREAKING CHANGE"
+	TrailerRefs           = "Refs"
+	TrailerCoAuthoredBy   = "Co-authored-by"
+	TrailerSignedOffBy    = "Signed-off-by"
+)
+
+// trailerPattern matches a "Key: value" trailer line. Keys are single tokens, except for
+// the "BREAKING CHANGE" key of the Conventional Commits specification.
+var trailerPattern = regexp.MustCompile(`^(BREAKING CHANGE|[A-Za-z][A-Za-z0-9-]*): (.+)$`)
+
+// listItemPattern matches the marker of a list item in a commit body, e.g. "- " or "1. ".
+var listItemPattern = regexp.MustCompile(`^(\s*(?:[-*+]|\d+[.)])\s+)`)
+
+// Trailer is a "Key: value" line at the end of a commit message, see git-interpret-trailers.
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+func (t Trailer) String() string {
+	return t.Key + ": " + t.Value
+}
+
+// ParseTrailers reads one trailer per line, skipping lines that are not trailers.
+func ParseTrailers(text string) []Trailer {
+	var trailers []Trailer
+	for _, line := range strings.Split(text, "\n") {
+		if match := trailerPattern.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			key := match[1]
+			if strings.EqualFold(key, "BREAKING-CHANGE") {
+				key = TrailerBreakingChange
+			}
+			trailers = append(trailers, Trailer{Key: key, Value: strings.TrimSpace(match[2])})
+		}
+	}
+	return trailers
+}
+
+// modelTrailers keeps the trailers a model can know from the diff. Authors and sign-offs
+// are dropped, since a model can only invent them; they are added by gitguy instead.
+func modelTrailers(trailers []Trailer) []Trailer {
+	var kept []Trailer
+	for _, trailer := range trailers {
+		if !strings.EqualFold(trailer.Key, TrailerCoAuthoredBy) && !strings.EqualFold(trailer.Key, TrailerSignedOffBy) {
+			kept = append(kept, trailer)
+		}
+	}
+	return kept
+}
+
+// AddTrailers appends the extra trailers that are not present yet, ignoring case.
+func AddTrailers(trailers []Trailer, extra ...Trailer) []Trailer {
+	for _, trailer := range extra {
+		exists := false
+		for _, existing := range trailers {
+			exists = exists || (strings.EqualFold(existing.Key, trailer.Key) && strings.EqualFold(existing.Value, trailer.Value))
+		}
+		if !exists {
+			trailers = append(trailers, trailer)
+		}
+	}
+	return trailers
+}
+
+// FormatCommitMessage joins a header, a body wrapped at 72 columns and trailers into a
+// commit message, separating them by blank lines.
+func FormatCommitMessage(header, body string, trailers []Trailer) string {
+	parts := []string{strings.TrimSpace(header)}
+	if body = strings.TrimSpace(body); body != "" {
+		parts = append(parts, WrapText(body, bodyWidth))
+	}
+	if len(trailers) > 0 {
+		lines := make([]string, len(trailers))
+		for i, trailer := range trailers {
+			lines[i] = trailer.String()
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// WrapText wraps the paragraphs and list items of text at width columns. List items are
+// continued with a hanging indent, and indented lines such as code are kept as they are.
+// Words longer than the width are not broken.
+func WrapText(text string, width int) string {
+	var out []string
+	var paragraph []string
+	prefix := ""
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			out = append(out, wrapWords(strings.Join(paragraph, " "), prefix, width)...)
+		}
+		paragraph, prefix = nil, ""
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+			out = append(out, "")
+		case listItemPattern.MatchString(line):
+			flush()
+			prefix = listItemPattern.FindString(line)
+			paragraph = []string{strings.TrimSpace(line[len(prefix):])}
+		case (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")) && prefix == "":
+			flush()
+			out = append(out, strings.TrimRight(line, " \t"))
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+
+	return strings.Join(out, "\n")
+}
+
+// wrapWords breaks text into lines of at most width columns, the first starting with
+// prefix and the others indented to line up with it.
+func wrapWords(text, prefix string, width int) []string {
+	indent := strings.Repeat(" ", len([]rune(prefix)))
+
+	var lines []string
+	line := prefix
+	lineHasWord := false
+	for _, word := range strings.Fields(text) {
+		if lineHasWord && len([]rune(line))+1+len([]rune(word)) > width {
+			lines = append(lines, line)
+			line, lineHasWord = indent, false
+		}
+		if lineHasWord {
+			line += " "
+		}
+		line += word
+		lineHasWord = true
+	}
+	return append(lines, line)
+}
+
+// CurrentUser returns the name and email of the git user.name and user.email settings,
+// read from the repository, global and system configs.
+func (g *GitRepo) CurrentUser() (string, string, error) {
+	cfg, err := g.repo.ConfigScoped(config.SystemScope)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read git config: %w", err)
+	}
+	if cfg.User.Name == "" || cfg.User.Email == "" {
+		return "", "", fmt.Errorf("user.name and user.email must be set in the git config")
+	}
+	return cfg.User.Name, cfg.User.Email, nil
+}
+
+// TrailersFromConfig returns the trailers gitguy adds to every generated commit message:
+// a Signed-off-by trailer for the current user when signoff is enabled, by default for
+// styles that sign off, see [Style.SignOff].
+func (g *GitRepo) TrailersFromConfig() ([]Trailer, error) {
+	style, err := g.StyleFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	signoff := style.SignOff
+	if viper.IsSet("signoff") {
+		signoff = viper.GetBool("signoff")
+	}
+
+	var trailers []Trailer
+	if signoff {
+		name, email, err := g.CurrentUser()
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign off: %w", err)
+		}
+		trailers = append(trailers, Trailer{Key: TrailerSignedOffBy, Value: fmt.Sprintf("%s <%s>", name, email)})
+	}
+	return trailers, nil
+}
+
+// commitHooks are the hooks git runs when committing, which can reject or rewrite a commit.
+var commitHooks = []string{"pre-commit", "prepare-commit-msg", "commit-msg"}
+
+// CheckCommitPolicies returns an error when the repository has commit hooks or signs
+// commits, since committing without git would bypass them.
+func (g *GitRepo) CheckCommitPolicies() error {
+	cfg, err := g.repo.ConfigScoped(config.SystemScope)
+	if err != nil {
+		return fmt.Errorf("failed to read git config: %w", err)
+	}
+	switch strings.ToLower(cfg.Raw.Section("commit").Option("gpgsign")) {
+	case "true", "yes", "on", "1":
+		return fmt.Errorf("commit.gpgsign is set and gitguy cannot sign commits, commit with git instead")
+	}
+
+	hooksDir := cfg.Raw.Section("core").Option("hooksPath")
+	if hooksDir != "" && !filepath.IsAbs(hooksDir) {
+		// A relative hooksPath is relative to the root of the working tree
+		worktree, err := g.repo.Worktree()
+		if err != nil {
+			return fmt.Errorf("failed to get worktree: %w", err)
+		}
+		hooksDir = filepath.Join(worktree.Filesystem.Root(), hooksDir)
+	}
+	if storage, ok := g.repo.Storer.(*filesystem.Storage); ok && hooksDir == "" {
+		hooksDir = filepath.Join(storage.Filesystem().Root(), "hooks")
+	}
+	if hooksDir == "" {
+		return nil
+	}
+
+	for _, hook := range commitHooks {
+		if info, err := os.Stat(filepath.Join(hooksDir, hook)); err == nil && info.Mode().IsRegular() {
+			return fmt.Errorf("the repository has a %s hook, which gitguy cannot run, commit with git instead", hook)
+		}
+	}
+	return nil
+}
+
+// CommitStaged commits the staged changes with a message, as the current git user. It
+// refuses to commit when that would bypass hooks or signing, see
+// [GitRepo.CheckCommitPolicies].
+func (g *GitRepo) CommitStaged(message string) (*CommitInfo, error) {
+	if err := g.CheckCommitPolicies(); err != nil {
+		return nil, err
+	}
+
+	wt, err := g.repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	hash, err := wt.Commit(message, &git.CommitOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit staged changes: %w", err)
+	}
+
+	commit, err := g.repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", hash, err)
+	}
+	info := newCommitInfo(commit)
+	return &info, nil
+}
+
+// FrontMatter is the YAML front matter of a saved PR description.
+type FrontMatter struct {
+	// Title is the commit header.
+	Title string
+	Base  string
+	Head  string
+	// Ticket is the ticket of the branch, if any, see [ApplyTicket].
+	Ticket   string
+	Trailers []Trailer
+}
+
+// String renders the front matter between "---" lines, followed by a blank line.
+func (f FrontMatter) String() string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "title: %q\n", f.Title)
+	fmt.Fprintf(&b, "base: %s\n", f.Base)
+	fmt.Fprintf(&b, "head: %s\n", f.Head)
+	if f.Ticket != "" {
+		fmt.Fprintf(&b, "ticket: %q\n", f.Ticket)
+	}
+	if len(f.Trailers) > 0 {
+		b.WriteString("trailers:\n")
+		for _, trailer := range f.Trailers {
+			fmt.Fprintf(&b, "  - %q\n", trailer.String())
+		}
+	}
+	b.WriteString("---\n\n")
+	return b.String()
+}
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		width    int
+		expected string
+	}{
+		{
+			name:     "paragraph",
+			text:     "Generated messages only had a header, so the reasons for a change were lost once the PR was merged.",
+			width:    40,
+			expected: "Generated messages only had a header, so\nthe reasons for a change were lost once\nthe PR was merged.",
+		},
+		{
+			name:     "paragraphs are kept apart",
+			text:     "First paragraph\ncontinues here.\n\nSecond one.",
+			width:    72,
+			expected: "First paragraph continues here.\n\nSecond one.",
+		},
+		{
+			name:     "list items get a hanging indent",
+			text:     "- wrap the body at the usual git width\n- keep trailers",
+			width:    24,
+			expected: "- wrap the body at the\n  usual git width\n- keep trailers",
+		},
+		{
+			name:     "indented code is kept",
+			text:     "Run it with:\n\n    gitguy --non-interactive --commit --signoff",
+			width:    20,
+			expected: "Run it with:\n\n    gitguy --non-interactive --commit --signoff",
+		},
+		{
+			name:     "long words are not broken",
+			text:     "see https://example.com/a/very/long/path",
+			width:    10,
+			expected: "see\nhttps://example.com/a/very/long/path",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := WrapText(test.text, test.width); got != test.expected {
+				t.Errorf("WrapText() =\n%s\nexpected\n%s", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseResponseBodyAndTrailers(t *testing.T) {
+	response := `COMMIT: feat(api)!: remove the v1 endpoints
+
+BODY:
+The v1 endpoints were deprecated a year ago.
+Clients must move to v2.
+
+TRAILERS:
+BREAKING CHANGE: the /v1 routes are gone
+Refs: #42
+Co-authored-by: Invented Person <invented@example.com>
+Signed-off-by: Invented Person <invented@example.com>
+
+PR:
+## What changed
+- Removed v1`
+
+	result, err := parseResponse(response)
+	if err != nil {
+		t.Fatalf("parseResponse() error: %v", err)
+	}
+
+	if result.CommitMessage != "feat(api)!: remove the v1 endpoints" {
+		t.Errorf("Unexpected header %q", result.CommitMessage)
+	}
+	if result.Body != "The v1 endpoints were deprecated a year ago.\nClients must move to v2." {
+		t.Errorf("Unexpected body %q", result.Body)
+	}
+	expected := []Trailer{{TrailerBreakingChange, "the /v1 routes are gone"}, {TrailerRefs, "#42"}}
+	if len(result.Trailers) != len(expected) {
+		t.Fatalf("Expected trailers %v, got %v", expected, result.Trailers)
+	}
+	for i, trailer := range expected {
+		if result.Trailers[i] != trailer {
+			t.Errorf("Trailer %d = %v, expected %v", i, result.Trailers[i], trailer)
+		}
+	}
+	if result.PRDescription != "## What changed\n- Removed v1" {
+		t.Errorf("Unexpected PR description %q", result.PRDescription)
+	}
+
+	full := result.FullCommitMessage()
+	expectedFull := "feat(api)!: remove the v1 endpoints\n\n" +
+		"The v1 endpoints were deprecated a year ago. Clients must move to v2.\n\n" +
+		"BREAKING CHANGE: the /v1 routes are gone\nRefs: #42"
+	if full != expectedFull {
+		t.Errorf("FullCommitMessage() =\n%s\nexpected\n%s", full, expectedFull)
+	}
+}
+
+func TestAddTrailers(t *testing.T) {
+	trailers := []Trailer{{TrailerRefs, "#1"}}
+	trailers = AddTrailers(trailers, Trailer{"refs", "#1"}, Trailer{TrailerSignedOffBy, "Ada <ada@example.com>"})
+
+	if len(trailers) != 2 || trailers[1].Key != TrailerSignedOffBy {
+		t.Errorf("AddTrailers() = %v", trailers)
+	}
+}
+
+func TestFrontMatter(t *testing.T) {
+	got := FrontMatter{Title: `fix: handle "quoted" names`, Base: "main", Head: "feature", Ticket: "#7", Trailers: []Trailer{{TrailerRefs, "#7"}}}.String()
+	expected := "---\ntitle: \"fix: handle \\\"quoted\\\" names\"\nbase: main\nhead: feature\nticket: \"#7\"\ntrailers:\n  - \"Refs: #7\"\n---\n\n"
+	if got != expected {
+		t.Errorf("FrontMatter.String() =\n%s\nexpected\n%s", got, expected)
+	}
+
+	if got := (FrontMatter{Title: "fix: x", Base: "a", Head: "b"}).String(); strings.Contains(got, "trailers") || strings.Contains(got, "ticket") {
+		t.Errorf("Expected no ticket or trailers keys without them, got:\n%s", got)
+	}
+}
+
+func TestSignoffAndCommitStaged(t *testing.T) {
+	defer viper.Reset()
+	tr := newTestRepo(t)
+	tr.commitFile("README.md", "hello\n", "docs: add readme", "Ada")
+
+	cfg, err := tr.repo.Config()
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	cfg.User.Name, cfg.User.Email = "Grace Hopper", "grace@example.com"
+	if err := tr.repo.SetConfig(cfg); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	repo := tr.gitRepo()
+	if trailers, err := repo.TrailersFromConfig(); err != nil || len(trailers) != 0 {
+		t.Errorf("Expected no trailers without signoff, got %v, %v", trailers, err)
+	}
+
+	viper.Set("signoff", true)
+	trailers, err := repo.TrailersFromConfig()
+	if err != nil {
+		t.Fatalf("TrailersFromConfig() error: %v", err)
+	}
+	if len(trailers) != 1 || trailers[0].String() != "Signed-off-by: Grace Hopper <grace@example.com>" {
+		t.Errorf("Unexpected trailers %v", trailers)
+	}
+
+	tr.writeFile("README.md", "hello world\n")
+	tr.stage("README.md")
+	message := FormatCommitMessage("docs: greet the world", "Say hello to everyone.", trailers)
+	commit, err := repo.CommitStaged(message)
+	if err != nil {
+		t.Fatalf("CommitStaged() error: %v", err)
+	}
+	if commit.Message != message || commit.Author != "Grace Hopper" {
+		t.Errorf("Committed %q by %q", commit.Message, commit.Author)
+	}
+
+	if _, err := repo.CommitStaged(message); err == nil {
+		t.Error("Expected an error when nothing is staged")
+	}
+}
+
+func TestCommitStagedRefusesHooksAndSigning(t *testing.T) {
+	tr := newTestRepo(t)
+	tr.commitFile("README.md", "hello\n", "docs: add readme", "Ada")
+	repo := tr.gitRepo()
+
+	if err := repo.CheckCommitPolicies(); err != nil {
+		t.Fatalf("Expected no policies, got %v", err)
+	}
+
+	tr.writeFile(".git/hooks/commit-msg.sample", "#!/bin/sh\n")
+	tr.writeFile(".git/hooks/commit-msg", "#!/bin/sh\nexit 1\n")
+	tr.writeFile("README.md", "hello world\n")
+	tr.stage("README.md")
+	if _, err := repo.CommitStaged("docs: greet the world"); err == nil || !strings.Contains(err.Error(), "commit-msg hook") {
+		t.Errorf("Expected the commit-msg hook to be reported, got %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(tr.dir, ".git/hooks/commit-msg")); err != nil {
+		t.Fatalf("Failed to remove hook: %v", err)
+	}
+	cfg, err := tr.repo.Config()
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	cfg.Raw.Section("commit").SetOption("gpgSign", "true")
+	if err := tr.repo.SetConfig(cfg); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := repo.CheckCommitPolicies(); err == nil || !strings.Contains(err.Error(), "commit.gpgsign") {
+		t.Errorf("Expected signing to be reported, got %v", err)
+	}
+}
@@ -8,9 +8,11 @@ import (
 
 	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
 	"github.com/spf13/viper"
 )
 
@@ -20,9 +22,16 @@ type sessionState int
 const (
 	refSelectionView sessionState = iota
 	diffView
+	templatePickerView
 	resultView
 )
 
+// revisionInputHeight is the number of lines the revision input takes up inside a ref pane.
+const revisionInputHeight = 3
+
+// searchInputHeight is the number of lines the history search input takes up below the ref panes.
+const searchInputHeight = 3
+
 // refSide represents which side of the diff is currently active in the ref selection view.
 type refSide int
 
@@ -45,13 +54,47 @@ type model struct {
 	selectedCurrentName  string
 	selectedIncomingName string
 	diff                 string
+	fileDiffs            []FileDiff
+	showGenerated        bool
+	prompt               *PreparedPrompt
 	commitMessage        string
+	commitBody           string
+	trailers             []Trailer
+	ticket               string
 	prDescription        string
+	offlineReason        string
+	violations           []LintViolation
+	committed            *CommitInfo
 	width                int
 	height               int
 	err                  error
 	lastKeypress         string
 	keypressTimer        int
+
+	// Branches of the selected refs, empty for commits and tags, see [GitRepo.BranchName]
+	selectedCurrentBranch  string
+	selectedIncomingBranch string
+
+	// PR template picker, shown before generating when the repository has several
+	templateList   list.Model
+	templatePicked bool
+
+	// Free-form revision input for the active side of the ref selection view
+	revisionInput   textinput.Model
+	revisionEditing bool
+	revisionCommit  *CommitInfo
+	revisionErr     error
+
+	// Lazily paginated commit history shown below the other refs
+	refItems          []list.Item
+	historyItems      []list.Item
+	history           *CommitIterator
+	historyOptions    HistoryOptions
+	historyGeneration int
+	historyLoading    bool
+	searchInput       textinput.Model
+	searchEditing     bool
+	searchErr         error
 }
 
 // refItem represents an item in the reference selection list.
@@ -59,9 +102,40 @@ type refItem struct {
 	ref RefInfo
 }
 
-func (i refItem) FilterValue() string { return i.ref.Name }
+// templateItem is a PR template offered in the template picker.
+type templateItem struct {
+	template PRTemplate
+}
+
+func (i templateItem) FilterValue() string { return i.template.Path }
+func (i templateItem) Title() string       { return i.template.Path }
+func (i templateItem) Description() string {
+	for _, line := range strings.Split(i.template.Content, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "<!--") {
+			return line
+		}
+	}
+	return ""
+}
+
+func (i refItem) FilterValue() string { return i.ref.Type + " " + i.ref.Name }
 func (i refItem) Title() string       { return i.ref.Name }
-func (i refItem) Description() string { return fmt.Sprintf("%s (%s)", i.ref.Hash, i.ref.Type) }
+func (i refItem) Description() string {
+	if i.ref.Type != RefTypeCommit {
+		return fmt.Sprintf("%s (%s)", i.ref.Hash, i.ref.Type)
+	}
+
+	return fmt.Sprintf("%s (%s) · %s · %s", shortHash(i.ref.Hash), i.ref.Type, i.ref.Author, relativeTime(i.ref.When, time.Now()))
+}
+
+// shortHash abbreviates a commit hash to 8 characters. Shorter values, such as the
+// working copy pseudo-refs, are returned unchanged.
+func shortHash(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
 
 // Init creates the initial model for the TUI application.
 func Init() model {
@@ -69,7 +143,13 @@ func Init() model {
 	if err != nil {
 		return model{err: err}
 	}
+	repo.SetIncludeUntracked(viper.GetBool("untracked"))
 
+	return newModel(repo)
+}
+
+// newModel creates the initial TUI model for an opened repository.
+func newModel(repo *GitRepo) model {
 	currentList := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
 	currentList.Title = "Current Ref"
 
@@ -79,6 +159,17 @@ func Init() model {
 	diffViewport := viewport.New(0, 0)
 	resultViewport := viewport.New(0, 0)
 
+	templateList := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	templateList.Title = "Pick a PR Template"
+
+	revisionInput := textinput.New()
+	revisionInput.Prompt = "rev> "
+	revisionInput.Placeholder = "HEAD~3, main@{yesterday}, v1.2.0^{commit}, a1b2c3d"
+
+	searchInput := textinput.New()
+	searchInput.Prompt = "search history> "
+	searchInput.Placeholder = "text author:alice since:2.weeks.ago until:2025-01-31"
+
 	return model{
 		state:           refSelectionView,
 		repo:            repo,
@@ -86,7 +177,10 @@ func Init() model {
 		incomingRefList: incomingList,
 		diffViewport:    diffViewport,
 		resultViewport:  resultViewport,
+		templateList:    templateList,
 		activeSide:      currentSide,
+		revisionInput:   revisionInput,
+		searchInput:     searchInput,
 	}
 }
 
@@ -106,23 +200,26 @@ func tickCmd() tea.Cmd {
 	})
 }
 
-// loadRefs loads the git references (branches, commits, and staged files) into the model.
+// loadRefs loads the git references (working copy changes, branches, remote branches and tags) into
+// the model. Items are grouped by type in that order, and the type is part of each item's
+// filter value so typing e.g. "tag" narrows the list to tags. Commit history is loaded
+// separately, a page at a time, by loadHistoryPage.
 func (m model) loadRefs() tea.Cmd {
 	return func() tea.Msg {
-		var items []list.Item
+		// Add staged, unstaged and all uncommitted changes if they exist
+		items := m.workingRefItems()
 
-		// Add staged files if they exist
-		stagedRef, err := m.repo.GetStagedRef()
-		if err == nil && stagedRef != nil {
-			items = append(items, refItem{ref: *stagedRef})
+		branches, err := m.repo.GetBranches()
+		if err != nil {
+			return errMsg{err}
 		}
 
-		branches, err := m.repo.GetBranches()
+		remotes, err := m.repo.GetRemoteBranches()
 		if err != nil {
 			return errMsg{err}
 		}
 
-		commits, err := m.repo.GetRecentCommits(10)
+		tags, err := m.repo.GetTags()
 		if err != nil {
 			return errMsg{err}
 		}
@@ -130,19 +227,137 @@ func (m model) loadRefs() tea.Cmd {
 		for _, branch := range branches {
 			items = append(items, refItem{ref: branch})
 		}
-		for _, commit := range commits {
-			items = append(items, refItem{ref: commit})
+		for _, remote := range remotes {
+			items = append(items, refItem{ref: remote})
+		}
+		for _, tag := range tags {
+			items = append(items, refItem{ref: tag})
 		}
 
 		return refsLoadedMsg{items}
 	}
 }
 
+// workingRefItems returns the pseudo-refs for working copy comparisons that have changes.
+func (m model) workingRefItems() []list.Item {
+	var items []list.Item
+	for _, mode := range WorkingDiffModes {
+		workingRef, err := m.repo.GetWorkingRef(mode)
+		if err == nil && workingRef != nil {
+			items = append(items, refItem{ref: *workingRef})
+		}
+	}
+	return items
+}
+
+// loadWorkingRefs reloads only the working copy pseudo-refs, e.g. after toggling untracked files.
+func (m model) loadWorkingRefs() tea.Cmd {
+	return func() tea.Msg {
+		return workingRefsLoadedMsg{m.workingRefItems()}
+	}
+}
+
+// historyPageSize is the number of commits loaded at a time into the ref lists.
+const historyPageSize = 50
+
+// historyPrefetchDistance is how close to the end of the list the cursor gets before the next page loads.
+const historyPrefetchDistance = 5
+
+// loadHistoryPage loads the next page of commit history. A nil iterator starts a new walk with
+// the given options. The generation ties the page to the search that requested it.
+func (m model) loadHistoryPage(iter *CommitIterator, opts HistoryOptions, generation int) tea.Cmd {
+	return func() tea.Msg {
+		if iter == nil {
+			var err error
+			iter, err = m.repo.History(opts)
+			if err != nil {
+				return historyErrMsg{generation: generation, err: err}
+			}
+		}
+
+		commits, err := iter.NextPage(historyPageSize)
+		if err != nil {
+			return historyErrMsg{generation: generation, iter: iter, err: err}
+		}
+
+		items := make([]list.Item, 0, len(commits))
+		for _, commit := range commits {
+			items = append(items, refItem{ref: CommitRef(commit)})
+		}
+
+		return historyPageMsg{generation: generation, iter: iter, items: items}
+	}
+}
+
+// startHistory discards loaded commits and starts walking history with new search options.
+func (m model) startHistory(opts HistoryOptions) (model, tea.Cmd) {
+	// A page still loading is using the iterator; it is closed when the stale page arrives
+	if m.history != nil && !m.historyLoading {
+		m.history.Close()
+	}
+
+	m.history = nil
+	m.historyItems = nil
+	m.historyOptions = opts
+	m.historyGeneration++
+	m.historyLoading = true
+	m.setRefItems()
+
+	return m, m.loadHistoryPage(nil, opts, m.historyGeneration)
+}
+
+// loadMoreHistory requests the next page of history once the cursor nears the end of the active list.
+func (m model) loadMoreHistory() (model, tea.Cmd) {
+	if m.historyLoading || m.history == nil || m.history.Done() {
+		return m, nil
+	}
+
+	active := m.currentRefList
+	if m.activeSide == incomingSide {
+		active = m.incomingRefList
+	}
+	if active.FilterState() != list.Unfiltered || active.Index() < len(active.Items())-historyPrefetchDistance {
+		return m, nil
+	}
+
+	m.historyLoading = true
+	return m, m.loadHistoryPage(m.history, m.historyOptions, m.historyGeneration)
+}
+
+// setRefItems shows the refs followed by the loaded commit history in both lists.
+func (m *model) setRefItems() {
+	items := make([]list.Item, 0, len(m.refItems)+len(m.historyItems))
+	items = append(items, m.refItems...)
+	items = append(items, m.historyItems...)
+
+	m.currentRefList.SetItems(items)
+	m.incomingRefList.SetItems(items)
+}
+
 // refsLoadedMsg is a message that is sent when the git references have been loaded.
 type refsLoadedMsg struct {
 	items []list.Item
 }
 
+// workingRefsLoadedMsg is a message that is sent when the working copy pseudo-refs have been reloaded.
+type workingRefsLoadedMsg struct {
+	items []list.Item
+}
+
+// historyPageMsg is a message that is sent when a page of commit history has been loaded.
+type historyPageMsg struct {
+	generation int
+	iter       *CommitIterator
+	items      []list.Item
+}
+
+// historyErrMsg is a message that is sent when a page of commit history failed to load.
+type historyErrMsg struct {
+	generation int
+	iter       *CommitIterator
+	err        error
+}
+
 // errMsg is a message that is sent when an error occurs.
 type errMsg struct {
 	err error
@@ -154,13 +369,31 @@ func (e errMsg) Error() string {
 
 // diffGeneratedMsg is a message that is sent when the git diff has been generated.
 type diffGeneratedMsg struct {
-	diff string
+	fileDiffs []FileDiff
+	prompt    *PreparedPrompt
 }
 
 // llmResultMsg is a message that is sent when the LLM has generated a commit message and PR description.
 type llmResultMsg struct {
 	commitMessage string
+	commitBody    string
+	trailers      []Trailer
+	ticket        string
 	prDescription string
+	offlineReason string
+	violations    []LintViolation
+}
+
+// commitCreatedMsg is a message that is sent when the staged changes were committed with the generated message.
+type commitCreatedMsg struct {
+	commit *CommitInfo
+}
+
+// revisionResolvedMsg is a message that is sent when a typed revision expression has been resolved.
+type revisionResolvedMsg struct {
+	input  string
+	commit *CommitInfo
+	err    error
 }
 
 // tickMsg is sent periodically to update the keypress timer
@@ -176,16 +409,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 
-		// Adjust sizing to prevent cut-off - reserve more space for UI elements
-		listWidth := m.width/2 - 3
-		listHeight := m.height - 10 // Reserve more space for title, selections, status, and help
-
-		m.currentRefList.SetSize(listWidth, listHeight)
-		m.incomingRefList.SetSize(listWidth, listHeight)
+		m.resizeRefLists()
 		m.diffViewport.Width = m.width - 4
 		m.diffViewport.Height = m.height - 8 // Reserve more space for navigation
 		m.resultViewport.Width = m.width - 4
 		m.resultViewport.Height = m.height - 8
+		m.templateList.SetSize(m.width-4, m.height-4)
 
 	case tickMsg:
 		// Update keypress timer
@@ -197,24 +426,82 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tickCmd()
 
 	case refsLoadedMsg:
-		m.currentRefList.SetItems(msg.items)
-		m.incomingRefList.SetItems(msg.items)
+		m.refItems = msg.items
+		return m.startHistory(m.historyOptions)
+
+	case workingRefsLoadedMsg:
+		items := msg.items
+		for _, item := range m.refItems {
+			if _, ok := ParseWorkingDiffMode(item.(refItem).ref.Hash); !ok {
+				items = append(items, item)
+			}
+		}
+		m.refItems = items
+		m.setRefItems()
+		return m, nil
+
+	case historyPageMsg:
+		if msg.generation != m.historyGeneration {
+			// A newer search replaced the walk this page belongs to
+			msg.iter.Close()
+			return m, nil
+		}
+
+		m.history = msg.iter
+		m.historyItems = append(m.historyItems, msg.items...)
+		m.historyLoading = false
+		m.setRefItems()
+		return m, nil
+
+	case historyErrMsg:
+		if msg.generation != m.historyGeneration {
+			if msg.iter != nil {
+				msg.iter.Close()
+			}
+			return m, nil
+		}
+
+		// Keep the iterator, so that scrolling tries the page again
+		m.history = msg.iter
+		m.historyLoading = false
+		m.err = msg.err
+		return m, nil
 
 	case errMsg:
 		m.err = msg.err
 
+	case revisionResolvedMsg:
+		// Ignore results for input that has since been edited
+		if msg.input == m.revisionInput.Value() {
+			m.revisionCommit = msg.commit
+			m.revisionErr = msg.err
+		}
+		return m, nil
+
 	case diffGeneratedMsg:
-		m.diff = msg.diff
-		m.diffViewport.SetContent(msg.diff)
+		m.fileDiffs = msg.fileDiffs
+		m.showGenerated = false
+		m.prompt = msg.prompt
+		m.templatePicked = false
+		m.setDiffContent()
 		m.state = diffView
 
 	case llmResultMsg:
 		m.commitMessage = msg.commitMessage
+		m.commitBody = msg.commitBody
+		m.trailers = msg.trailers
+		m.ticket = msg.ticket
 		m.prDescription = msg.prDescription
-		content := fmt.Sprintf("COMMIT MESSAGE:\n%s\n\nPR DESCRIPTION:\n%s", msg.commitMessage, msg.prDescription)
+		m.offlineReason = msg.offlineReason
+		m.violations = msg.violations
+		m.committed = nil
+		content := fmt.Sprintf("COMMIT MESSAGE:\n%s\n\nPR DESCRIPTION:\n%s", m.fullCommitMessage(), msg.prDescription)
 		m.resultViewport.SetContent(content)
 		m.state = resultView
 
+	case commitCreatedMsg:
+		m.committed = msg.commit
+
 	case tea.KeyMsg:
 		// Record keypress for visual feedback
 		keyStr := msg.String()
@@ -229,9 +516,29 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		switch m.state {
 		case refSelectionView:
+			if m.revisionEditing {
+				return m.updateRevisionInput(msg)
+			}
+			if m.searchEditing {
+				return m.updateSearchInput(msg)
+			}
+
 			switch msg.String() {
 			case "q", "ctrl+c":
 				return m, tea.Quit
+			case ":":
+				if !m.activeListFiltering() {
+					return m.startRevisionInput()
+				}
+			case "ctrl+f":
+				if !m.activeListFiltering() {
+					return m.startSearchInput()
+				}
+			case "u":
+				if !m.activeListFiltering() {
+					m.repo.SetIncludeUntracked(!m.repo.IncludeUntracked())
+					return m, m.loadWorkingRefs()
+				}
 			case "tab":
 				if m.activeSide == currentSide {
 					m.activeSide = incomingSide
@@ -243,11 +550,13 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					if item, ok := m.currentRefList.SelectedItem().(refItem); ok {
 						m.selectedCurrent = item.ref.Hash
 						m.selectedCurrentName = item.ref.Name
+						m.selectedCurrentBranch = m.refBranch(item.ref)
 					}
 				} else {
 					if item, ok := m.incomingRefList.SelectedItem().(refItem); ok {
 						m.selectedIncoming = item.ref.Hash
 						m.selectedIncomingName = item.ref.Name
+						m.selectedIncomingBranch = m.refBranch(item.ref)
 					}
 				}
 
@@ -259,9 +568,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.activeSide == currentSide {
 					m.selectedCurrent = ""
 					m.selectedCurrentName = ""
+					m.selectedCurrentBranch = ""
 				} else {
 					m.selectedIncoming = ""
 					m.selectedIncomingName = ""
+					m.selectedIncomingBranch = ""
 				}
 			case "R":
 				// Reset all selections
@@ -269,6 +580,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.selectedIncoming = ""
 				m.selectedCurrentName = ""
 				m.selectedIncomingName = ""
+				m.selectedCurrentBranch = ""
+				m.selectedIncomingBranch = ""
 			}
 
 		case diffView:
@@ -278,7 +591,36 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "b":
 				m.state = refSelectionView
 			case "g":
+				if len(m.prompt.PRTemplates) > 1 && !m.templatePicked {
+					return m.startTemplatePicker()
+				}
 				return m, m.generateLLMResult()
+			case "t":
+				if len(m.prompt.PRTemplates) > 1 {
+					return m.startTemplatePicker()
+				}
+			case "G":
+				m.showGenerated = !m.showGenerated
+				m.setDiffContent()
+			}
+
+		case templatePickerView:
+			if m.templateList.FilterState() == list.Filtering {
+				break
+			}
+			switch msg.String() {
+			case "ctrl+c":
+				return m, tea.Quit
+			case "esc", "b":
+				m.state = diffView
+				return m, nil
+			case "enter":
+				if item, ok := m.templateList.SelectedItem().(templateItem); ok {
+					m.prompt.UsePRTemplate(item.template)
+					m.templatePicked = true
+					m.state = diffView
+					return m, m.generateLLMResult()
+				}
 			}
 
 		case resultView:
@@ -289,6 +631,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.state = diffView
 			case "c":
 				return m, m.copyCommitMessage()
+			case "C":
+				if m.committed == nil && m.comparesStaged() {
+					return m, m.commitStaged()
+				}
 			case "p":
 				return m, m.savePRDescription()
 			}
@@ -297,17 +643,26 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch m.state {
 	case refSelectionView:
-		if m.activeSide == currentSide {
+		if m.revisionEditing {
+			m.revisionInput, cmd = m.revisionInput.Update(msg)
+		} else if m.activeSide == currentSide {
 			m.currentRefList, cmd = m.currentRefList.Update(msg)
 		} else {
 			m.incomingRefList, cmd = m.incomingRefList.Update(msg)
 		}
 		cmds = append(cmds, cmd)
 
+		m, cmd = m.loadMoreHistory()
+		cmds = append(cmds, cmd)
+
 	case diffView:
 		m.diffViewport, cmd = m.diffViewport.Update(msg)
 		cmds = append(cmds, cmd)
 
+	case templatePickerView:
+		m.templateList, cmd = m.templateList.Update(msg)
+		cmds = append(cmds, cmd)
+
 	case resultView:
 		m.resultViewport, cmd = m.resultViewport.Update(msg)
 		cmds = append(cmds, cmd)
@@ -316,46 +671,340 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// startTemplatePicker offers the PR templates of the prompt, with the one in use selected.
+func (m model) startTemplatePicker() (tea.Model, tea.Cmd) {
+	items := make([]list.Item, len(m.prompt.PRTemplates))
+	selected := 0
+	for i, template := range m.prompt.PRTemplates {
+		items[i] = templateItem{template: template}
+		if template.Path == m.prompt.PRTemplate {
+			selected = i
+		}
+	}
+
+	cmd := m.templateList.SetItems(items)
+	m.templateList.Select(selected)
+	m.state = templatePickerView
+	return m, cmd
+}
+
+// activeListFiltering reports whether the list on the active side is capturing input for its filter.
+func (m model) activeListFiltering() bool {
+	if m.activeSide == currentSide {
+		return m.currentRefList.FilterState() == list.Filtering
+	}
+	return m.incomingRefList.FilterState() == list.Filtering
+}
+
+// startRevisionInput focuses the free-form revision input for the active side.
+func (m model) startRevisionInput() (tea.Model, tea.Cmd) {
+	m.revisionEditing = true
+	m.revisionCommit = nil
+	m.revisionErr = nil
+	m.revisionInput.SetValue("")
+	m.resizeRefLists()
+	return m, m.revisionInput.Focus()
+}
+
+// stopRevisionInput hides the revision input and restores the list size.
+func (m *model) stopRevisionInput() {
+	m.revisionEditing = false
+	m.revisionInput.Blur()
+	m.resizeRefLists()
+}
+
+// updateRevisionInput handles key presses while the revision input is focused. The input is
+// resolved on every change so the resolved commit or error is shown as the user types.
+func (m model) updateRevisionInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.stopRevisionInput()
+		return m, nil
+	case "enter":
+		if m.revisionCommit == nil {
+			return m, nil
+		}
+
+		name := fmt.Sprintf("%s (%s)", strings.TrimSpace(m.revisionInput.Value()), m.revisionCommit.ShortHash())
+		branch := m.repo.BranchName(strings.TrimSpace(m.revisionInput.Value()))
+		if m.activeSide == currentSide {
+			m.selectedCurrent = m.revisionCommit.Hash
+			m.selectedCurrentName = name
+			m.selectedCurrentBranch = branch
+		} else {
+			m.selectedIncoming = m.revisionCommit.Hash
+			m.selectedIncomingName = name
+			m.selectedIncomingBranch = branch
+		}
+		m.stopRevisionInput()
+
+		if m.selectedCurrent != "" && m.selectedIncoming != "" {
+			return m, m.generateDiff()
+		}
+		return m, nil
+	}
+
+	previous := m.revisionInput.Value()
+	var cmd tea.Cmd
+	m.revisionInput, cmd = m.revisionInput.Update(msg)
+
+	if m.revisionInput.Value() != previous {
+		return m, tea.Batch(cmd, m.resolveRevision(m.revisionInput.Value()))
+	}
+	return m, cmd
+}
+
+// startSearchInput focuses the history search input, prefilled with the active search.
+func (m model) startSearchInput() (tea.Model, tea.Cmd) {
+	m.searchEditing = true
+	m.searchErr = nil
+	m.searchInput.SetValue(m.historyOptions.String())
+	m.searchInput.CursorEnd()
+	m.resizeRefLists()
+	return m, m.searchInput.Focus()
+}
+
+// stopSearchInput hides the history search input and restores the list size.
+func (m *model) stopSearchInput() {
+	m.searchEditing = false
+	m.searchInput.Blur()
+	m.resizeRefLists()
+}
+
+// updateSearchInput handles key presses while the history search input is focused.
+// Enter restarts the history walk with the parsed query; an empty query clears the search.
+func (m model) updateSearchInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.stopSearchInput()
+		return m, nil
+	case "enter":
+		opts, err := ParseHistoryQuery(m.searchInput.Value(), time.Now())
+		if err != nil {
+			m.searchErr = err
+			return m, nil
+		}
+		m.stopSearchInput()
+		return m.startHistory(opts)
+	}
+
+	var cmd tea.Cmd
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	m.searchErr = nil
+	return m, cmd
+}
+
+// searchInputView renders the history search input and any query error.
+func (m model) searchInputView() string {
+	status := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Render("Enter to search commit history, empty to clear, Esc to cancel")
+	if m.searchErr != nil {
+		status = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Render("✗ " + m.searchErr.Error())
+	}
+
+	return truncate.String(m.searchInput.View(), uint(m.width)) + "\n" + truncate.String(status, uint(m.width))
+}
+
+// resolveRevision resolves a typed revision expression in the background.
+func (m model) resolveRevision(input string) tea.Cmd {
+	return func() tea.Msg {
+		if strings.TrimSpace(input) == "" {
+			return revisionResolvedMsg{input: input}
+		}
+
+		commit, err := m.repo.ResolveRevision(input)
+		if err != nil {
+			return revisionResolvedMsg{input: input, err: err}
+		}
+		return revisionResolvedMsg{input: input, commit: &commit}
+	}
+}
+
+// resizeRefLists sizes the ref lists to the window, leaving room for the revision input when it is shown.
+func (m *model) resizeRefLists() {
+	if m.width <= 0 || m.height <= 0 {
+		// No window size received yet
+		return
+	}
+
+	// Adjust sizing to prevent cut-off - reserve more space for UI elements
+	listWidth := m.width/2 - 3
+	listHeight := m.height - 10 // Reserve more space for title, selections, status, and help
+
+	if m.searchEditing {
+		listHeight -= searchInputHeight
+	}
+
+	currentHeight, incomingHeight := listHeight, listHeight
+	if m.revisionEditing {
+		if m.activeSide == currentSide {
+			currentHeight -= revisionInputHeight
+		} else {
+			incomingHeight -= revisionInputHeight
+		}
+	}
+
+	m.currentRefList.SetSize(listWidth, currentHeight)
+	m.incomingRefList.SetSize(listWidth, incomingHeight)
+}
+
+// revisionInputView renders the revision input together with the resolved commit or error.
+func (m model) revisionInputView() string {
+	var status string
+	switch {
+	case m.revisionErr != nil:
+		status = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Render("✗ " + m.revisionErr.Error())
+	case m.revisionCommit != nil:
+		status = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("46")).
+			Render(fmt.Sprintf("→ %s %s (%s, %s)", m.revisionCommit.ShortHash(), m.revisionCommit.Subject,
+				m.revisionCommit.Author, m.revisionCommit.When.Format("2006-01-02")))
+	default:
+		status = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")).
+			Render("Type a revision, Enter to select, Esc to cancel")
+	}
+
+	width := m.width/2 - 3
+	return truncate.String(m.revisionInput.View(), uint(width)) + "\n" + truncate.String(status, uint(width))
+}
+
 // generateDiff generates a git diff between the selected references.
 func (m model) generateDiff() tea.Cmd {
 	return func() tea.Msg {
-		var diff string
-		var err error
-
-		// Handle special cases for staged files
-		if m.selectedCurrent == "staged" {
-			diff, err = m.repo.GetStagedDiff()
-		} else if m.selectedIncoming == "staged" {
-			diff, err = m.repo.GetStagedDiff()
+		var fileDiffs []FileDiff
+		var commits []CommitInfo
+		var coAuthors []Trailer
+
+		opts, err := DiffOptionsFromConfig()
+		if err != nil {
+			return errMsg{err}
+		}
+
+		// Handle special cases for working copy changes
+		if mode, ok := ParseWorkingDiffMode(m.selectedCurrent); ok {
+			fileDiffs, err = m.repo.GetWorkingFileDiffs(mode, opts)
+		} else if mode, ok := ParseWorkingDiffMode(m.selectedIncoming); ok {
+			fileDiffs, err = m.repo.GetWorkingFileDiffs(mode, opts)
 		} else {
-			diff, err = m.repo.GetDiff(m.selectedCurrent, m.selectedIncoming)
+			fileDiffs, err = m.repo.GetFileDiffs(m.selectedCurrent, m.selectedIncoming, opts)
+			if err == nil {
+				// The commits only add context, so the diff is described without them on failure
+				rangeCommits, rangeErr := m.repo.GetCommitRange(m.selectedCurrent, m.selectedIncoming, 0)
+				if rangeErr != nil {
+					logger.Warn("Failed to collect commits", "error", rangeErr)
+				}
+				commits = PromptCommits(rangeCommits)
+				if coAuthors, rangeErr = m.repo.PromptCoAuthors(rangeCommits); rangeErr != nil {
+					logger.Warn("Failed to collect co-authors", "error", rangeErr)
+				}
+			}
+		}
+
+		if err != nil {
+			return errMsg{err}
+		}
+
+		// The incoming side holds the changes, unless only the other side is the working copy
+		branch := m.selectedIncomingBranch
+		if _, ok := ParseWorkingDiffMode(m.selectedCurrent); ok && branch == "" {
+			branch = m.selectedCurrentBranch
 		}
 
+		// Examples come from the history the compared changes build on
+		var base string
+		_, currentWorking := ParseWorkingDiffMode(m.selectedCurrent)
+		_, incomingWorking := ParseWorkingDiffMode(m.selectedIncoming)
+		if !currentWorking && !incomingWorking {
+			base = m.selectedCurrent
+		}
+
+		prompt, err := m.repo.PreparePrompt(fileDiffs, commits, branch, base)
 		if err != nil {
 			return errMsg{err}
 		}
-		return diffGeneratedMsg{diff}
+		prompt.AddTrailers(coAuthors...)
+
+		return diffGeneratedMsg{fileDiffs: fileDiffs, prompt: prompt}
+	}
+}
+
+// setDiffContent shows the diff, with generated files collapsed unless they were expanded.
+func (m *model) setDiffContent() {
+	if m.showGenerated {
+		m.diff = CombineFileDiffs(m.fileDiffs)
+	} else {
+		m.diff = CombineFileDiffs(CollapseGenerated(m.fileDiffs))
 	}
+	m.diffViewport.SetContent(m.diff)
 }
 
 // generateLLMResult generates a commit message and PR description from the git diff.
 func (m model) generateLLMResult() tea.Cmd {
 	return func() tea.Msg {
-		result, err := GenerateCommitAndPR(m.diff)
+		result, err := m.prompt.Generate()
 		if err != nil {
 			return errMsg{err}
 		}
 		return llmResultMsg{
 			commitMessage: result.CommitMessage,
+			commitBody:    result.Body,
+			trailers:      result.Trailers,
+			ticket:        result.Ticket,
 			prDescription: result.PRDescription,
+			offlineReason: result.OfflineReason,
+			violations:    result.Violations,
 		}
 	}
 }
 
+// refBranch returns the branch a selected ref names, see [GitRepo.BranchName]. Refs are
+// selected by hash, which loses the branch name.
+func (m model) refBranch(ref RefInfo) string {
+	switch ref.Type {
+	case RefTypeBranch, RefTypeRemote:
+		return m.repo.BranchName(ref.Name)
+	case RefTypeStaged, RefTypeUnstaged, RefTypeUncommitted:
+		return m.repo.BranchName(ref.Hash)
+	}
+	return ""
+}
+
+// fullCommitMessage returns the generated commit message with its body and trailers.
+func (m model) fullCommitMessage() string {
+	return FormatCommitMessage(m.commitMessage, m.commitBody, m.trailers)
+}
+
+// comparesStaged reports whether the selected refs compare the staged changes, which can
+// be committed with the generated message.
+func (m model) comparesStaged() bool {
+	return m.selectedCurrent == string(DiffStaged) || m.selectedIncoming == string(DiffStaged)
+}
+
+// commitStaged commits the staged changes with the generated commit message.
+func (m model) commitStaged() tea.Cmd {
+	return func() tea.Msg {
+		commit, err := m.repo.CommitStaged(m.fullCommitMessage())
+		if err != nil {
+			return errMsg{err}
+		}
+		return commitCreatedMsg{commit: commit}
+	}
+}
+
 // copyCommitMessage copies the generated commit message to the clipboard.
 func (m model) copyCommitMessage() tea.Cmd {
 	return func() tea.Msg {
-		err := clipboard.WriteAll(m.commitMessage)
+		err := clipboard.WriteAll(m.fullCommitMessage())
 		if err != nil {
 			return errMsg{fmt.Errorf("failed to copy to clipboard: %w", err)}
 		}
@@ -375,13 +1024,13 @@ func (m model) savePRDescription() tea.Cmd {
 		filename = ExpandPRTemplate(filename)
 
 		// Create front matter
-		frontMatter := fmt.Sprintf(`---
-title: "%s"
-base: %s
-head: %s
----
-
-`, m.commitMessage, m.selectedCurrent[:8], m.selectedIncoming[:8])
+		frontMatter := FrontMatter{
+			Title:    m.commitMessage,
+			Base:     shortHash(m.selectedCurrent),
+			Head:     shortHash(m.selectedIncoming),
+			Ticket:   m.ticket,
+			Trailers: m.trailers,
+		}.String()
 
 		content := frontMatter + m.prDescription
 
@@ -412,6 +1061,8 @@ func (m model) View() string {
 		return m.refSelectionView()
 	case diffView:
 		return m.diffView()
+	case templatePickerView:
+		return m.templatePickerView()
 	case resultView:
 		return m.resultView()
 	}
@@ -460,12 +1111,26 @@ func (m model) refSelectionView() string {
 	}
 	m.incomingRefList.Title = incomingTitle
 
-	currentView := currentStyle.Render(m.currentRefList.View())
-	incomingView := incomingStyle.Render(m.incomingRefList.View())
+	currentContent := m.currentRefList.View()
+	incomingContent := m.incomingRefList.View()
+	if m.revisionEditing {
+		if m.activeSide == currentSide {
+			currentContent += "\n\n" + m.revisionInputView()
+		} else {
+			incomingContent += "\n\n" + m.revisionInputView()
+		}
+	}
+
+	currentView := currentStyle.Render(currentContent)
+	incomingView := incomingStyle.Render(incomingContent)
 
 	content := lipgloss.JoinHorizontal(lipgloss.Top, currentView, "  ", incomingView)
 	b.WriteString(content)
 
+	if m.searchEditing {
+		b.WriteString("\n" + m.searchInputView())
+	}
+
 	// Show status and help
 	var statusMsg string
 	if m.selectedCurrentName != "" && m.selectedIncomingName != "" {
@@ -484,10 +1149,24 @@ func (m model) refSelectionView() string {
 		statusMsg = fmt.Sprintf("Select %s to continue", strings.Join(missing, " and "))
 	}
 
+	untracked := "hidden"
+	if m.repo != nil && m.repo.IncludeUntracked() {
+		untracked = "shown"
+	}
+	statusMsg += lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Render(" | Untracked files: " + untracked)
+
+	if !m.historyOptions.IsZero() {
+		statusMsg += lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")).
+			Render(" | History search: " + m.historyOptions.String())
+	}
+
 	b.WriteString("\n\n" + statusMsg)
 
 	// Add keypress feedback
-	helpLine := "Tab: Switch sides | Enter/Space: Select | r: Reset current | R: Reset all | q: Quit"
+	helpLine := "Tab: Switch sides | Enter/Space: Select | :: Type revision | ctrl+f: Search history | u: Toggle untracked | r: Reset current | R: Reset all | q: Quit"
 	if m.lastKeypress != "" && m.keypressTimer > 0 {
 		keypressStyle := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("226")).
@@ -514,7 +1193,60 @@ func (m model) diffView() string {
 	b.WriteString(title + "\n\n")
 	b.WriteString(m.diffViewport.View())
 
-	helpLine := "j/k: Scroll | g: Generate commit & PR | b: Back | q: Quit"
+	if m.prompt != nil && len(m.prompt.Context.OmittedFiles) > 0 {
+		omitted := "Not sent to the model: " + strings.Join(m.prompt.Context.OmittedFiles, ", ")
+		b.WriteString("\n" + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")).
+			Render(truncate.String(omitted, uint(max(m.width, 0)))))
+	}
+
+	if m.prompt != nil && m.prompt.Anonymizer != nil && m.prompt.Anonymizer.Len() > 0 {
+		anonymized := fmt.Sprintf("Anonymized before sending: %d paths, packages and identifiers", m.prompt.Anonymizer.Len())
+		b.WriteString("\n" + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")).
+			Render(truncate.String(anonymized, uint(max(m.width, 0)))))
+	}
+
+	if m.prompt != nil && len(m.prompt.Redactions) > 0 {
+		redacted := "Redacted before sending: " + SummarizeRedactions(m.prompt.Redactions)
+		b.WriteString("\n" + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Render(truncate.String(redacted, uint(max(m.width, 0)))))
+	}
+
+	if m.prompt != nil && m.prompt.TicketDetails != nil {
+		ticket := fmt.Sprintf("Ticket %s: %s", m.prompt.TicketDetails.ID, m.prompt.TicketDetails.Title)
+		b.WriteString("\n" + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")).
+			Render(truncate.String(ticket, uint(max(m.width, 0)))))
+	} else if m.prompt != nil && m.prompt.TicketError != nil {
+		ticket := "Ticket left out of the prompt: " + m.prompt.TicketError.Error()
+		b.WriteString("\n" + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Render(truncate.String(ticket, uint(max(m.width, 0)))))
+	}
+
+	if m.prompt != nil && m.prompt.PRTemplate != "" {
+		template := "PR template: " + m.prompt.PRTemplate
+		if len(m.prompt.PRTemplates) > 1 {
+			template += fmt.Sprintf(" (%d found)", len(m.prompt.PRTemplates))
+		}
+		b.WriteString("\n" + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")).
+			Render(truncate.String(template, uint(max(m.width, 0)))))
+	}
+
+	if m.prompt != nil && !m.prompt.Context.Examples.IsZero() {
+		examples := m.prompt.Context.Examples
+		b.WriteString("\n" + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")).
+			Render(fmt.Sprintf("Examples: %d commit messages, %d PR descriptions, %d of the style", len(examples.Commits), len(examples.PRs), len(examples.Style))))
+	}
+
+	helpLine := "j/k: Scroll | g: Generate commit & PR | G: Toggle generated files | b: Back | q: Quit"
+	if m.prompt != nil && len(m.prompt.PRTemplates) > 1 {
+		helpLine = "j/k: Scroll | g: Generate commit & PR | t: PR template | G: Toggle generated files | b: Back | q: Quit"
+	}
 	if m.lastKeypress != "" && m.keypressTimer > 0 {
 		keypressStyle := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("226")).
@@ -529,6 +1261,11 @@ func (m model) diffView() string {
 	return b.String()
 }
 
+// templatePickerView renders the PR template picker.
+func (m model) templatePickerView() string {
+	return m.templateList.View() + "\n\nenter: Use template and generate | /: Filter | esc: Back to diff"
+}
+
 // resultView renders the result view.
 func (m model) resultView() string {
 	var b strings.Builder
@@ -541,8 +1278,36 @@ func (m model) resultView() string {
 	b.WriteString(title + "\n\n")
 	b.WriteString(m.resultViewport.View())
 
+	if len(m.violations) > 0 {
+		rules := make([]string, len(m.violations))
+		for i, violation := range m.violations {
+			rules[i] = violation.Rule
+		}
+		violations := "Commit message breaks lint rules: " + strings.Join(rules, ", ")
+		b.WriteString("\n" + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Render(truncate.String(violations, uint(max(m.width, 0)))))
+	}
+
+	if m.offlineReason != "" {
+		offline := "Generated offline from the changed files: " + m.offlineReason
+		b.WriteString("\n" + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Render(truncate.String(offline, uint(max(m.width, 0)))))
+	}
+
+	if m.committed != nil {
+		committed := fmt.Sprintf("Committed %s %s", m.committed.ShortHash(), m.committed.Subject)
+		b.WriteString("\n" + lipgloss.NewStyle().
+			Foreground(lipgloss.Color("46")).
+			Render(truncate.String(committed, uint(max(m.width, 0)))))
+	}
+
 	// Add keypress feedback
 	helpLine := "c: Copy commit | p: Save PR | d: Back to diff | q: Quit"
+	if m.committed == nil && m.comparesStaged() {
+		helpLine = "c: Copy commit | C: Commit staged | p: Save PR | d: Back to diff | q: Quit"
+	}
 	if m.lastKeypress != "" && m.keypressTimer > 0 {
 		keypressStyle := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("226")).
@@ -24,14 +24,39 @@ var (
 	apiKey         string
 	prTemplate     string
 	model          string
+	includeCommits bool
+	maxCommits     int
+	showUntracked  bool
+	redact         bool
+	anonymize      bool
+	offline        bool
+	signoff        bool
+	commitStaged   bool
+	creditAuthors  bool
+	commitStyle    string
 	
 	// diff command flags
-	sideBySide       bool
-	unified          bool
-	staged           bool
-	unstaged         bool
-	syntaxHighlight  bool
-	showWhitespace   bool
+	sideBySide        bool
+	unified           bool
+	staged            bool
+	unstaged          bool
+	allUncommitted    bool
+	untracked         bool
+	diffContext       int
+	ignoreAllSpace    bool
+	ignoreSpaceChange bool
+	ignoreBlankLines  bool
+	diffAlgorithm     string
+	syntaxHighlight   bool
+	showWhitespace    bool
+
+	// lint command flags
+	lintFormat  string
+	lintRewrite bool
+
+	// prompt show command flags
+	promptCurrent  string
+	promptIncoming string
 )
 
 // main is the entry point of the application.
@@ -40,19 +65,42 @@ func main() {
 	log.SetLevel(log.InfoLevel)
 
 	var rootCmd = &cobra.Command{
-		Use:   "gitguy",
+		Use:   "gitguy [-- <pathspec>...]",
 		Short: "Generate commit messages and PR descriptions from Git diffs",
 		Long:  "Interactive TUI tool for generating commit messages and PR descriptions using OpenRouter AI",
+		Args:  pathspecArgs,
 		RunE:  run,
 	}
 
 	var diffCmd = &cobra.Command{
-		Use:   "diff",
+		Use:   "diff [-- <pathspec>...]",
 		Short: "Show git diff with syntax highlighting",
 		Long:  "Display git diff in side-by-side or unified format with syntax highlighting",
+		Args:  pathspecArgs,
 		RunE:  runDiff,
 	}
 
+	var lintCmd = &cobra.Command{
+		Use:   "lint [<range>]",
+		Short: "Check commit messages against the commit rules",
+		Long:  "Check the messages of a commit range, merge-base..HEAD of the default branch by default, against the rules used for generated commit messages",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runLint,
+	}
+
+	var promptCmd = &cobra.Command{
+		Use:   "prompt",
+		Short: "Inspect the prompt sent to the model",
+	}
+
+	var promptShowCmd = &cobra.Command{
+		Use:   "show [-- <pathspec>...]",
+		Short: "Print the rendered system and user prompts",
+		Long:  "Render the prompt templates for the changes between --ref-current and --ref-incoming, or for the staged changes, and print what would be sent to the model",
+		Args:  pathspecArgs,
+		RunE:  runPromptShow,
+	}
+
 	rootCmd.Flags().StringVar(&refCurrent, "ref-current", "", "Current Git ref (branch or commit SHA)")
 	rootCmd.Flags().StringVar(&refIncoming, "ref-incoming", "", "Incoming Git ref (branch or commit SHA)")
 	rootCmd.Flags().StringVar(&outPR, "out-pr", templateVar, "Output file for PR description")
@@ -60,12 +108,29 @@ func main() {
 	rootCmd.Flags().StringVar(&apiKey, "api-key", "", "OpenRouter API key")
 	rootCmd.Flags().StringVar(&prTemplate, "pr-template", "", "Path to PR template markdown file")
 	rootCmd.Flags().StringVar(&model, "model", app.DeepseekV3.String(), "LLM model to use (deepseek-v3, deepseek-r1, deepseek-r1-0528, kimi-k2)")
+	rootCmd.Flags().BoolVar(&includeCommits, "include-commits", true, "Send commit messages from the compared range to the model")
+	rootCmd.Flags().IntVar(&maxCommits, "max-commits", 20, "Maximum number of commit messages sent to the model")
+	rootCmd.Flags().BoolVar(&showUntracked, "untracked", true, "Include untracked files in unstaged and uncommitted changes (toggle with u in the TUI)")
+	rootCmd.Flags().BoolVar(&redact, "redact", true, "Replace secrets and personal data with placeholders before sending the diff to the model")
+	rootCmd.Flags().BoolVar(&anonymize, "anonymize", false, "Rename file paths, packages and configured identifiers before sending the diff to the model")
+	rootCmd.Flags().BoolVar(&offline, "offline", false, "Generate the commit message and PR description from the changed files, without the model")
+	rootCmd.Flags().BoolVarP(&signoff, "signoff", "s", false, "Add a Signed-off-by trailer for the git user to the commit message")
+	rootCmd.Flags().BoolVar(&creditAuthors, "co-authors", true, "Credit the other authors and co-authors of the compared commits with Co-authored-by trailers")
+	rootCmd.PersistentFlags().StringVar(&commitStyle, "style", "", "Commit message style ("+strings.Join(app.StyleNames(), ", ")+", default "+app.DefaultStyle+")")
+	rootCmd.Flags().BoolVar(&commitStaged, "commit", false, "Commit the staged changes with the generated message (non-interactive mode, instead of --ref-current and --ref-incoming)")
 
 	// diff command flags
 	diffCmd.Flags().BoolVar(&sideBySide, "side-by-side", true, "Display diff in side-by-side format")
 	diffCmd.Flags().BoolVar(&unified, "unified", false, "Display diff in unified format")
-	diffCmd.Flags().BoolVar(&staged, "staged", false, "Show staged changes")
-	diffCmd.Flags().BoolVar(&unstaged, "unstaged", false, "Show unstaged changes")
+	diffCmd.Flags().BoolVar(&staged, "staged", false, "Show staged changes (HEAD vs index)")
+	diffCmd.Flags().BoolVar(&unstaged, "unstaged", false, "Show unstaged changes (index vs working tree)")
+	diffCmd.Flags().BoolVar(&allUncommitted, "all", false, "Show all uncommitted changes (HEAD vs working tree)")
+	diffCmd.Flags().BoolVar(&untracked, "untracked", true, "Include untracked files as new files")
+	diffCmd.Flags().IntVar(&diffContext, "context", 3, "Number of context lines around each hunk")
+	diffCmd.Flags().BoolVarP(&ignoreAllSpace, "ignore-all-space", "w", false, "Ignore whitespace when comparing lines")
+	diffCmd.Flags().BoolVarP(&ignoreSpaceChange, "ignore-space-change", "b", false, "Ignore changes in the amount of whitespace")
+	diffCmd.Flags().BoolVar(&ignoreBlankLines, "ignore-blank-lines", false, "Ignore changes whose lines are all blank")
+	diffCmd.Flags().StringVar(&diffAlgorithm, "diff-algorithm", string(app.DiffMyers), "Diff algorithm (myers, patience, histogram)")
 	diffCmd.Flags().BoolVar(&syntaxHighlight, "syntax-highlighting", true, "Enable syntax highlighting")
 	diffCmd.Flags().BoolVar(&showWhitespace, "whitespace", false, "Show whitespace changes (default: false)")
 
@@ -76,8 +141,34 @@ func main() {
 	viper.BindPFlag("api-key", rootCmd.Flags().Lookup("api-key"))
 	viper.BindPFlag("pr-template", rootCmd.Flags().Lookup("pr-template"))
 	viper.BindPFlag("model", rootCmd.Flags().Lookup("model"))
-
+	viper.BindPFlag("include-commits", rootCmd.Flags().Lookup("include-commits"))
+	viper.BindPFlag("max-commits", rootCmd.Flags().Lookup("max-commits"))
+	viper.BindPFlag("untracked", rootCmd.Flags().Lookup("untracked"))
+	viper.BindPFlag("redact", rootCmd.Flags().Lookup("redact"))
+	viper.BindPFlag("anonymize", rootCmd.Flags().Lookup("anonymize"))
+	viper.BindPFlag("offline", rootCmd.Flags().Lookup("offline"))
+	viper.BindPFlag("signoff", rootCmd.Flags().Lookup("signoff"))
+	viper.BindPFlag("commit", rootCmd.Flags().Lookup("commit"))
+	viper.BindPFlag("co-authors", rootCmd.Flags().Lookup("co-authors"))
+	viper.BindPFlag("style", rootCmd.PersistentFlags().Lookup("style"))
+	viper.BindPFlag("diff-context", diffCmd.Flags().Lookup("context"))
+	viper.BindPFlag("ignore-all-space", diffCmd.Flags().Lookup("ignore-all-space"))
+	viper.BindPFlag("ignore-space-change", diffCmd.Flags().Lookup("ignore-space-change"))
+	viper.BindPFlag("ignore-blank-lines", diffCmd.Flags().Lookup("ignore-blank-lines"))
+	viper.BindPFlag("diff-algorithm", diffCmd.Flags().Lookup("diff-algorithm"))
+
+	// lint command flags
+	lintCmd.Flags().StringVar(&lintFormat, "format", app.LintFormatText, "Output format (text, json, github)")
+	lintCmd.Flags().BoolVar(&lintRewrite, "rewrite", false, "Ask the model to suggest messages for commits that break the rules")
+
+	// prompt show command flags
+	promptShowCmd.Flags().StringVar(&promptCurrent, "ref-current", "", "Current Git ref (branch or commit SHA)")
+	promptShowCmd.Flags().StringVar(&promptIncoming, "ref-incoming", "", "Incoming Git ref (branch or commit SHA)")
+
+	promptCmd.AddCommand(promptShowCmd)
 	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(lintCmd)
+	rootCmd.AddCommand(promptCmd)
 
 	viper.AutomaticEnv()
 
@@ -99,10 +190,14 @@ func run(cmd *cobra.Command, args []string) error {
 	if _, err := app.OpenRepo("."); err != nil {
 		return fmt.Errorf("not in a git repository: %w", err)
 	}
+	setPathspec(args)
 
 	if viper.GetBool("non-interactive") {
 		return runNonInteractive(ctx)
 	}
+	if viper.GetBool("commit") {
+		return fmt.Errorf("--commit only works with --non-interactive, press C in the TUI to commit the staged changes")
+	}
 
 	return runInteractive(ctx)
 }
@@ -117,41 +212,86 @@ func runNonInteractive(_ context.Context) error {
 	refIncoming := viper.GetString("ref-incoming")
 	outPR := viper.GetString("out-pr")
 
-	if refCurrent == "" || refIncoming == "" {
+	commit := viper.GetBool("commit")
+
+	if commit && (refCurrent != "" || refIncoming != "") {
+		return fmt.Errorf("--commit commits the staged changes and cannot be combined with --ref-current or --ref-incoming")
+	}
+	if !commit && (refCurrent == "" || refIncoming == "") {
 		return fmt.Errorf("both --ref-current and --ref-incoming are required in non-interactive mode")
 	}
 
 	repo, _ := app.OpenRepo(".")
 
-	diff, err := repo.GetDiff(refCurrent, refIncoming)
+	if commit {
+		// Fail before generating a message that could not be committed
+		if err := repo.CheckCommitPolicies(); err != nil {
+			return err
+		}
+		refCurrent, refIncoming = "HEAD", string(app.DiffStaged)
+	}
+	prompt, err := preparePrompt(repo, refCurrent, refIncoming)
 	if err != nil {
-		return fmt.Errorf("failed to generate diff: %w", err)
+		return err
 	}
-
-	if strings.TrimSpace(diff) == "" {
-		return fmt.Errorf("no differences found between %s and %s", refCurrent, refIncoming)
+	if omitted := prompt.Context.OmittedFiles; len(omitted) > 0 {
+		log.Info("Leaving files out of the prompt", "ignore-file", app.PromptIgnoreFile, "files", strings.Join(omitted, ", "))
+	}
+	if prompt.Anonymizer != nil {
+		log.Info("Anonymized names in the prompt", "count", prompt.Anonymizer.Len())
+	}
+	if len(prompt.Redactions) > 0 {
+		log.Warn("Redacted sensitive values from the prompt", "redacted", app.SummarizeRedactions(prompt.Redactions))
+	}
+	if examples := prompt.Context.Examples; !examples.IsZero() {
+		log.Info("Showing the model examples of the repository's conventions", "commits", len(examples.Commits), "prs", len(examples.PRs), "style", len(examples.Style))
+	}
+	if len(prompt.PRTemplates) > 1 {
+		log.Info("Found several PR templates, pick one with --pr-template", "using", prompt.PRTemplate, "found", len(prompt.PRTemplates))
+	}
+	if prompt.TicketDetails != nil {
+		log.Info("Sending the ticket along with the diff", "ticket", prompt.TicketDetails.ID, "title", prompt.TicketDetails.Title)
+	} else if prompt.TicketError != nil {
+		log.Warn("Leaving the ticket out of the prompt", "error", prompt.TicketError)
 	}
 
-	result, err := app.GenerateCommitAndPR(diff)
+	result, err := prompt.Generate()
 	if err != nil {
 		return fmt.Errorf("failed to generate commit and PR: %w", err)
 	}
+	if result.OfflineReason != "" {
+		log.Warn("Generated the commit message offline from the changed files", "reason", result.OfflineReason)
+	}
+	if result.RepairAttempts > 0 {
+		log.Info("Asked the model to repair the commit message", "attempts", result.RepairAttempts)
+	}
+	if len(result.Violations) > 0 {
+		log.Warn("The commit message breaks lint rules", "violations", strings.TrimSpace(app.FormatViolations(result.Violations)))
+	}
 
 	// Print commit message to stdout
-	fmt.Println(result.CommitMessage)
+	fmt.Println(result.FullCommitMessage())
+
+	if commit {
+		created, err := repo.CommitStaged(result.FullCommitMessage())
+		if err != nil {
+			return err
+		}
+		log.Info("Committed the staged changes", "commit", created.ShortHash())
+	}
 
 	// Expand template if it is pr_{{id}}.md or PR_{{ID}}.md
 	if strings.ToUpper(outPR) == templateVar {
 		outPR = app.ExpandPRTemplate(outPR)
 	}
 
-	frontMatter := fmt.Sprintf(`---
-title: "%s"
-base: %s
-head: %s
----
-
-`, result.CommitMessage, refCurrent, refIncoming)
+	frontMatter := app.FrontMatter{
+		Title:    result.CommitMessage,
+		Base:     refCurrent,
+		Head:     refIncoming,
+		Ticket:   result.Ticket,
+		Trailers: result.Trailers,
+	}.String()
 
 	content := frontMatter + result.PRDescription
 
@@ -172,6 +312,59 @@ head: %s
 	return nil
 }
 
+// preparePrompt builds the prompt for the changes between two refs, with the commits and
+// co-authors of their range, or for the staged changes when refIncoming is "staged".
+func preparePrompt(repo *app.GitRepo, refCurrent, refIncoming string) (*app.PreparedPrompt, error) {
+	opts, err := app.DiffOptionsFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var fileDiffs []app.FileDiff
+	var commits []app.CommitInfo
+	var coAuthors []app.Trailer
+	// Examples are sampled from the history the changes build on, HEAD for staged changes
+	var base string
+	if refIncoming == string(app.DiffStaged) {
+		fileDiffs, err = repo.GetWorkingFileDiffs(app.DiffStaged, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate diff: %w", err)
+		}
+		if len(fileDiffs) == 0 {
+			return nil, fmt.Errorf("no staged changes")
+		}
+	} else {
+		fileDiffs, err = repo.GetFileDiffs(refCurrent, refIncoming, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate diff: %w", err)
+		}
+
+		if len(fileDiffs) == 0 {
+			return nil, fmt.Errorf("no differences found between %s and %s", refCurrent, refIncoming)
+		}
+		base = refCurrent
+
+		// The commits only add context, so the diff is described without them on failure
+		rangeCommits, err := repo.GetCommitRange(refCurrent, refIncoming, 0)
+		if err != nil {
+			log.Warn("Failed to collect commits", "error", err)
+		}
+		commits = app.PromptCommits(rangeCommits)
+
+		coAuthors, err = repo.PromptCoAuthors(rangeCommits)
+		if err != nil {
+			log.Warn("Failed to collect co-authors", "error", err)
+		}
+	}
+
+	prompt, err := repo.PreparePrompt(fileDiffs, commits, repo.BranchName(refIncoming), base)
+	if err != nil {
+		return nil, err
+	}
+	prompt.AddTrailers(coAuthors...)
+	return prompt, nil
+}
+
 // runInteractive starts the interactive TUI for the application.
 func runInteractive(_ context.Context) error {
 	log.Info("Starting interactive TUI")
@@ -187,34 +380,44 @@ func runDiff(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("not in a git repository: %w", err)
 	}
+	setPathspec(args)
+	if !cmd.Flags().Changed("untracked") {
+		// Fall back to the untracked setting from the config file
+		untracked = viper.GetBool("untracked")
+	}
+	repo.SetIncludeUntracked(untracked)
+
+	opts, err := app.DiffOptionsFromConfig()
+	if err != nil {
+		return err
+	}
 
 	// Determine what diff to show based on flags
 	var fileDiffs []app.FileDiff
 	
-	if staged {
-		fileDiffs, err = repo.GetStagedFileDiffs()
-		if err != nil {
-			return fmt.Errorf("failed to get staged diff: %w", err)
-		}
-		if len(fileDiffs) == 0 {
-			return fmt.Errorf("no staged changes found")
+	if staged || unstaged || allUncommitted {
+		mode := app.DiffUncommitted
+		if staged {
+			mode = app.DiffStaged
+		} else if unstaged {
+			mode = app.DiffUnstaged
 		}
-	} else if unstaged {
-		fileDiffs, err = repo.GetUnstagedFileDiffs()
+
+		fileDiffs, err = repo.GetWorkingFileDiffs(mode, opts)
 		if err != nil {
-			return fmt.Errorf("failed to get unstaged diff: %w", err)
+			return fmt.Errorf("failed to get %s diff: %w", mode, err)
 		}
 		if len(fileDiffs) == 0 {
-			return fmt.Errorf("no unstaged changes found")
+			return fmt.Errorf("no %s changes found", mode)
 		}
 	} else {
 		// Default: try unstaged first, then staged
-		fileDiffs, err = repo.GetUnstagedFileDiffs()
+		fileDiffs, err = repo.GetUnstagedFileDiffs(opts)
 		if err == nil && len(fileDiffs) > 0 {
 			// Found unstaged changes
 		} else {
 			// Try staged changes
-			fileDiffs, err = repo.GetStagedFileDiffs()
+			fileDiffs, err = repo.GetStagedFileDiffs(opts)
 			if err != nil {
 				return fmt.Errorf("failed to get diff: %w", err)
 			}
@@ -224,13 +427,114 @@ func runDiff(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Combine all file diffs and get primary filename for syntax highlighting
-	diffContent := app.CombineFileDiffs(fileDiffs)
-	primaryFilename := app.GetPrimaryFilename(fileDiffs)
-
-	// Create and run the diff viewer
-	diffViewer := app.NewDiffViewer(diffContent, primaryFilename, sideBySide, syntaxHighlight, showWhitespace)
+	// Create and run the diff viewer, with generated files collapsed
+	diffViewer := app.NewDiffViewerFromFileDiffs(fileDiffs, sideBySide, syntaxHighlight, showWhitespace)
 	p := tea.NewProgram(diffViewer, tea.WithAltScreen())
 	_, err = p.Run()
 	return err
 }
+
+// runLint checks the messages of a commit range, printing violations in the requested
+// format, and fails if any commit breaks the rules.
+func runLint(cmd *cobra.Command, args []string) error {
+	repo, err := app.OpenRepo(".")
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	var rangeSpec string
+	if len(args) > 0 {
+		rangeSpec = args[0]
+	}
+	base, head, err := repo.ParseCommitRange(rangeSpec)
+	if err != nil {
+		return err
+	}
+
+	rules, err := repo.LintRulesFromConfig()
+	if err != nil {
+		return err
+	}
+
+	report, err := repo.LintRange(base, head, rules)
+	if err != nil {
+		return fmt.Errorf("failed to lint %s..%s: %w", base, head, err)
+	}
+
+	if lintRewrite && len(report.Failed) > 0 {
+		opts, err := app.DiffOptionsFromConfig()
+		if err != nil {
+			return err
+		}
+		if err := repo.SuggestRewrites(report, opts); err != nil {
+			return err
+		}
+	}
+
+	output, err := app.FormatLintReport(report, lintFormat)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(cmd.OutOrStdout(), output)
+
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("%d of %d commits in %s..%s break the commit rules", len(report.Failed), report.Checked, base, head)
+	}
+	return nil
+}
+
+// pathspecArgs accepts positional arguments only after "--", where they are pathspecs,
+// so that a mistyped subcommand is reported instead of filtering out every file.
+func pathspecArgs(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 && cmd.ArgsLenAtDash() != 0 {
+		return fmt.Errorf("unexpected argument %q, pathspecs go after \"--\"", args[0])
+	}
+	return nil
+}
+
+// setPathspec stores pathspec arguments, such as those after "--", so that every diff
+// built from the config is limited to them.
+func setPathspec(args []string) {
+	if len(args) > 0 {
+		viper.Set("pathspec", args)
+	}
+}
+
+// runPromptShow prints the system and user prompts rendered for the changes between two
+// refs, or for the staged changes, as they would be sent to the model.
+func runPromptShow(cmd *cobra.Command, args []string) error {
+	repo, err := app.OpenRepo(".")
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+	setPathspec(args)
+
+	refCurrent, refIncoming := promptCurrent, promptIncoming
+	switch {
+	case refCurrent == "" && refIncoming == "":
+		refCurrent, refIncoming = "HEAD", string(app.DiffStaged)
+	case refCurrent == "" || refIncoming == "":
+		return fmt.Errorf("pass both --ref-current and --ref-incoming, or neither for the staged changes")
+	}
+
+	prompt, err := preparePrompt(repo, refCurrent, refIncoming)
+	if err != nil {
+		return err
+	}
+	system, user, err := prompt.Render()
+	if err != nil {
+		return err
+	}
+
+	source := func(path string) string {
+		if path == "" {
+			return "built-in"
+		}
+		return path
+	}
+	templates := prompt.Context.Templates
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "# System prompt (%s)\n\n%s\n\n", source(templates.SystemPath), strings.TrimSpace(system))
+	fmt.Fprintf(out, "# User prompt (%s)\n\n%s\n", source(templates.UserPath), strings.TrimSpace(user))
+	return nil
+}